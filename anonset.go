@@ -0,0 +1,191 @@
+package nft
+
+import (
+	"strings"
+
+	"github.com/mdlayher/netlink"
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+// anonSetName and anonMapName are the names of the anonymous sets and maps
+// of a rule. The kernel replaces %d with a free index.
+const (
+	anonSetName = "__set%d"
+	anonMapName = "__map%d"
+)
+
+// anonSet is an anonymous set created along with the rule looking it up.
+// match is the match of the rule using the set, or nil for the set of port
+// protocols. A set with a data type is a map, data holding the value of each
+// key.
+type anonSet struct {
+	match    any
+	keyType  uint32
+	keyLen   uint32
+	keys     [][]byte
+	dataType uint32
+	dataLen  uint32
+	data     [][]byte
+}
+
+// name returns the name the anonymous set is created with.
+func (s anonSet) name() string {
+	if s.dataType != 0 {
+		return anonMapName
+	}
+	return anonSetName
+}
+
+// anonSets returns the anonymous sets of a rule. Their IDs follow the rule
+// ID in the returned order.
+func (r *Rule) anonSets() []anonSet {
+	sets := r.portAnonSets()
+	sets = append(sets, r.icmpAnonSets()...)
+	sets = append(sets, r.timeAnonSets()...)
+	sets = append(sets, r.natAnonSets()...)
+	return sets
+}
+
+// anonSetID returns the ID of the anonymous set of match, or of the port
+// protocols if match is nil.
+func (r *Rule) anonSetID(match any) uint32 {
+	for i, set := range r.anonSets() {
+		if set.match == match {
+			return r.ID + 1 + uint32(i)
+		}
+	}
+	return 0
+}
+
+// anonSetMsgs returns the messages creating the anonymous sets of a rule.
+func anonSetMsgs(r *Rule) []nftnl.Msg {
+	header := func(msgType uint16) nftnl.Header {
+		return nftnl.Header{
+			SubsysID: unix.NFNL_SUBSYS_NFTABLES,
+			MsgType:  msgType,
+			Flags:    netlink.Request | netlink.Acknowledge | netlink.Create,
+		}
+	}
+
+	var msgs []nftnl.Msg
+	for i, set := range r.anonSets() {
+		id := r.ID + 1 + uint32(i)
+		flags := uint32(unix.NFT_SET_ANONYMOUS | unix.NFT_SET_CONSTANT)
+		if set.dataType != 0 {
+			flags |= unix.NFT_SET_MAP
+		}
+		elems := make([]nftnl.SetElemAttrs, len(set.keys))
+		for j, key := range set.keys {
+			elems[j] = nftnl.SetElemAttrs{Key: &nftnl.DataAttrs{Value: key}}
+			if set.dataType != 0 {
+				elems[j].Data = &nftnl.DataAttrs{Value: set.data[j]}
+			}
+		}
+		msgs = append(msgs,
+			nftnl.Msg{
+				Header:   header(unix.NFT_MSG_NEWSET),
+				NfGenMsg: nftnl.NfGenMsg{Family: r.Family},
+				Attrs: &nftnl.SetAttrs{
+					Table:    r.Table,
+					Name:     set.name(),
+					ID:       id,
+					Flags:    flags,
+					KeyType:  set.keyType,
+					KeyLen:   set.keyLen,
+					DataType: set.dataType,
+					DataLen:  set.dataLen,
+				},
+			},
+			nftnl.Msg{
+				Header:   header(unix.NFT_MSG_NEWSETELEM),
+				NfGenMsg: nftnl.NfGenMsg{Family: r.Family},
+				Attrs: &nftnl.SetElemListAttrs{
+					Table:    r.Table,
+					Set:      set.name(),
+					SetID:    id,
+					Elements: elems,
+				},
+			},
+		)
+	}
+	return msgs
+}
+
+// anonSetLookup looks the value in register 1 up in the anonymous set id.
+func anonSetLookup(id uint32, negate bool) *nftnl.LookupAttrs {
+	return &nftnl.LookupAttrs{
+		Set:   anonSetName,
+		SetID: id,
+		SReg:  1,
+		Flags: lookupFlags(negate),
+	}
+}
+
+// isAnonSet reports whether name is the name of an anonymous set or map.
+func isAnonSet(name string) bool {
+	return strings.HasPrefix(name, "__set") || strings.HasPrefix(name, "__map")
+}
+
+// getAnonSetElems returns the elements of the anonymous set looked up right
+// after an expression of a dumped rule that load reports as loading the key,
+// or nil if the rule has no such lookup.
+func (c *Conn) getAnonSetElems(r *Rule, attrs *nftnl.RuleAttrs, load func(nftnl.ExprDataAttrs) bool) ([]nftnl.SetElemAttrs, error) {
+	exprs := attrs.Expressions
+	for i := 0; i+1 < len(exprs); i++ {
+		if !load(exprs[i].Data) {
+			continue
+		}
+		if lookup, ok := exprs[i+1].Data.(*nftnl.LookupAttrs); ok && isAnonSet(lookup.Set) {
+			return c.getSetElems(r.Family, r.Table, lookup.Set)
+		}
+	}
+	return nil, nil
+}
+
+// getSetKeys returns the keys of the elements of a set.
+func (c *Conn) getSetKeys(family uint8, table string, set string) ([][]byte, error) {
+	elems, err := c.getSetElems(family, table, set)
+	if err != nil {
+		return nil, err
+	}
+	var keys [][]byte
+	for _, elem := range elems {
+		if elem.Key != nil {
+			keys = append(keys, elem.Key.Value)
+		}
+	}
+	return keys, nil
+}
+
+// getSetElems returns the elements of a set.
+func (c *Conn) getSetElems(family uint8, table string, set string) ([]nftnl.SetElemAttrs, error) {
+	res, err := c.nftnlConn.Send(nftnl.Msg{
+		Header: nftnl.Header{
+			SubsysID: unix.NFNL_SUBSYS_NFTABLES,
+			MsgType:  unix.NFT_MSG_GETSETELEM,
+			Flags:    netlink.Request | netlink.Dump,
+		},
+		NfGenMsg: nftnl.NfGenMsg{
+			Family: family,
+		},
+		Attrs: &nftnl.SetElemListAttrs{
+			Table: table,
+			Set:   set,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lists, err := extractAttrs[*nftnl.SetElemListAttrs](res)
+	if err != nil {
+		return nil, err
+	}
+
+	var elems []nftnl.SetElemAttrs
+	for _, list := range lists {
+		elems = append(elems, list.Elements...)
+	}
+	return elems, nil
+}
@@ -0,0 +1,161 @@
+package nft
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+// ARPOp is the operation of an ARP packet.
+type ARPOp uint16
+
+const (
+	ARPOpRequest ARPOp = 1
+	ARPOpReply   ARPOp = 2
+	// ARPOpRequestReverse and ARPOpReplyReverse are RARP operations.
+	ARPOpRequestReverse ARPOp = 3
+	ARPOpReplyReverse   ARPOp = 4
+)
+
+// ARPMatch matches ARP packets for IPv4 over Ethernet. It is supported in
+// the arp family, and in the bridge and netdev families where the ARP
+// protocol is implied.
+type ARPMatch struct {
+	// Op is compared unless 0.
+	Op       ARPOp
+	SenderHW net.HardwareAddr
+	SenderIP *netip.Addr
+	TargetHW net.HardwareAddr
+	TargetIP *netip.Addr
+}
+
+func (m *ARPMatch) validate(r *Rule) error {
+	switch r.Family {
+	case unix.NFPROTO_ARP, unix.NFPROTO_BRIDGE, unix.NFPROTO_NETDEV:
+	default:
+		return fmt.Errorf("arp is only supported in the arp, bridge and netdev families")
+	}
+	if m.Op == 0 && m.SenderHW == nil && m.SenderIP == nil && m.TargetHW == nil && m.TargetIP == nil {
+		return fmt.Errorf("arp match requires an operation or an address")
+	}
+	for _, addr := range []net.HardwareAddr{m.SenderHW, m.TargetHW} {
+		if addr != nil && len(addr) != 6 {
+			return fmt.Errorf("arp hardware address %s is not a MAC-48 address", addr)
+		}
+	}
+	for _, addr := range []*netip.Addr{m.SenderIP, m.TargetIP} {
+		if addr != nil && !addr.Is4() {
+			return fmt.Errorf("arp address %s is not an IPv4 address", addr)
+		}
+	}
+	return nil
+}
+
+// arpFieldExpr compares value with the field at offset of the ARP header.
+func arpFieldExpr(offset uint32, value []byte) []nftnl.ExprAttrs {
+	return appendExpr(nil,
+		&nftnl.PayloadAttrs{
+			DReg:   1,
+			Base:   unix.NFT_PAYLOAD_NETWORK_HEADER,
+			Offset: offset,
+			Len:    uint32(len(value)),
+		},
+		&nftnl.CmpAttrs{
+			SReg: 1,
+			Op:   unix.NFT_CMP_EQ,
+			Data: &nftnl.DataAttrs{
+				Value: value,
+			},
+		},
+	)
+}
+
+func (r *Rule) marshalARPExprs() []nftnl.ExprAttrs {
+	m := r.ARP
+	var exprs []nftnl.ExprAttrs
+	if r.Family != unix.NFPROTO_ARP {
+		exprs = appendExpr(exprs,
+			&nftnl.MetaAttrs{
+				DReg: 1,
+				Key:  unix.NFT_META_PROTOCOL,
+			},
+			&nftnl.CmpAttrs{
+				SReg: 1,
+				Op:   unix.NFT_CMP_EQ,
+				Data: &nftnl.DataAttrs{
+					Value: binary.BigEndian.AppendUint16(nil, unix.ETH_P_ARP),
+				},
+			},
+		)
+	}
+	if m.Op != 0 {
+		exprs = append(exprs, arpFieldExpr(6, binary.BigEndian.AppendUint16(nil, uint16(m.Op)))...)
+	}
+	if m.SenderHW != nil {
+		exprs = append(exprs, arpFieldExpr(8, m.SenderHW)...)
+	}
+	if m.SenderIP != nil {
+		exprs = append(exprs, arpFieldExpr(14, m.SenderIP.AsSlice())...)
+	}
+	if m.TargetHW != nil {
+		exprs = append(exprs, arpFieldExpr(18, m.TargetHW)...)
+	}
+	if m.TargetIP != nil {
+		exprs = append(exprs, arpFieldExpr(24, m.TargetIP.AsSlice())...)
+	}
+	return exprs
+}
+
+func (r *Rule) unmarshalARPExprs(attrs *nftnl.RuleAttrs) {
+	exprs := attrs.Expressions
+	arp := r.Family == unix.NFPROTO_ARP
+	m := &ARPMatch{}
+	var found bool
+	for i := 0; i+1 < len(exprs); i++ {
+		cmp, ok := exprs[i+1].Data.(*nftnl.CmpAttrs)
+		if !ok || cmp.Op != unix.NFT_CMP_EQ || cmp.Data == nil {
+			continue
+		}
+		value := cmp.Data.Value
+
+		switch e := exprs[i].Data.(type) {
+		case *nftnl.MetaAttrs:
+			// The ARP protocol is implied outside of the arp family.
+			if e.Key == unix.NFT_META_PROTOCOL && len(value) == 2 &&
+				binary.BigEndian.Uint16(value) == unix.ETH_P_ARP {
+				arp = true
+				i++
+			}
+			continue
+		case *nftnl.PayloadAttrs:
+			if e.DReg == 0 || e.Base != unix.NFT_PAYLOAD_NETWORK_HEADER || uint32(len(value)) != e.Len {
+				continue
+			}
+			switch {
+			case e.Offset == 6 && e.Len == 2:
+				m.Op = ARPOp(binary.BigEndian.Uint16(value))
+			case e.Offset == 8 && e.Len == 6:
+				m.SenderHW = net.HardwareAddr(value)
+			case e.Offset == 14 && e.Len == 4:
+				addr, _ := netip.AddrFromSlice(value)
+				m.SenderIP = &addr
+			case e.Offset == 18 && e.Len == 6:
+				m.TargetHW = net.HardwareAddr(value)
+			case e.Offset == 24 && e.Len == 4:
+				addr, _ := netip.AddrFromSlice(value)
+				m.TargetIP = &addr
+			default:
+				continue
+			}
+			found = true
+			i++
+		}
+	}
+	if arp && found {
+		r.ARP = m
+	}
+}
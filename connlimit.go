@@ -0,0 +1,30 @@
+package nft
+
+import (
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
+)
+
+// Connlimit matches packets by the number of connections tracked for the
+// rule, or for the set element when it is attached to a SetUpdate. By
+// default it matches while there are at most Count connections; Over
+// inverts it to match once there are more than Count.
+type Connlimit struct {
+	Count uint32
+	Over  bool
+}
+
+func (c *Connlimit) marshal() *nftnl.ConnlimitAttrs {
+	attrs := &nftnl.ConnlimitAttrs{Count: c.Count}
+	if c.Over {
+		attrs.Flags = unixext.NFT_CONNLIMIT_F_INV
+	}
+	return attrs
+}
+
+func connlimitFromAttrs(attr *nftnl.ConnlimitAttrs) *Connlimit {
+	return &Connlimit{
+		Count: attr.Count,
+		Over:  attr.Flags&unixext.NFT_CONNLIMIT_F_INV != 0,
+	}
+}
@@ -0,0 +1,240 @@
+package nft
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+// ctHelperMaxNameLen mirrors NF_CT_HELPER_NAME_LEN, minus the terminating
+// NUL.
+const ctHelperMaxNameLen = 15
+
+// CtHelper assigns a conntrack helper, such as "ftp", "sip" or "tftp", to
+// new connections. The helper module must be available in the kernel.
+type CtHelper struct {
+	Name string
+	// L3Proto restricts the helper to NFPROTO_IPV4 or NFPROTO_IPV6. It
+	// defaults to the family of the table.
+	L3Proto uint8
+	L4Proto uint8
+}
+
+func (h *CtHelper) validate() error {
+	if h.Name == "" {
+		return fmt.Errorf("ct helper name must be specified")
+	}
+	if len(h.Name) > ctHelperMaxNameLen {
+		return fmt.Errorf("ct helper name must be at most %d characters", ctHelperMaxNameLen)
+	}
+	if h.L4Proto != unix.IPPROTO_TCP && h.L4Proto != unix.IPPROTO_UDP {
+		return fmt.Errorf("ct helper L4 protocol must be tcp or udp")
+	}
+	return nil
+}
+
+func (h *CtHelper) marshal() *nftnl.CtHelperAttrs {
+	return &nftnl.CtHelperAttrs{
+		Name:    h.Name,
+		L3Proto: uint16(h.L3Proto),
+		L4Proto: h.L4Proto,
+	}
+}
+
+func ctHelperFromAttrs(family uint8, attr *nftnl.CtHelperAttrs) *CtHelper {
+	return &CtHelper{
+		Name:    attr.Name,
+		L3Proto: ctObjectL3Proto(family, attr.L3Proto),
+		L4Proto: attr.L4Proto,
+	}
+}
+
+// ctObjectL3Proto returns the L3 protocol of a conntrack object, or 0 when
+// it is the family of the table the object was created with by default.
+func ctObjectL3Proto(family uint8, l3proto uint16) uint8 {
+	if l3proto == uint16(family) {
+		return 0
+	}
+	return uint8(l3proto)
+}
+
+// CtTimeoutState is a connection state of an L4 protocol with its own
+// timeout.
+type CtTimeoutState string
+
+const (
+	CtTimeoutSynSent     CtTimeoutState = "syn_sent"
+	CtTimeoutSynRecv     CtTimeoutState = "syn_recv"
+	CtTimeoutEstablished CtTimeoutState = "established"
+	CtTimeoutFinWait     CtTimeoutState = "fin_wait"
+	CtTimeoutCloseWait   CtTimeoutState = "close_wait"
+	CtTimeoutLastAck     CtTimeoutState = "last_ack"
+	CtTimeoutTimeWait    CtTimeoutState = "time_wait"
+	CtTimeoutClose       CtTimeoutState = "close"
+	CtTimeoutSynSent2    CtTimeoutState = "syn_sent2"
+	CtTimeoutRetrans     CtTimeoutState = "retrans"
+	CtTimeoutUnack       CtTimeoutState = "unack"
+
+	CtTimeoutUnreplied CtTimeoutState = "unreplied"
+	CtTimeoutReplied   CtTimeoutState = "replied"
+
+	// CtTimeoutGeneric is the single timeout of ICMP and ICMPv6.
+	CtTimeoutGeneric CtTimeoutState = "timeout"
+)
+
+var ctTimeoutTCPStates = map[CtTimeoutState]uint16{
+	CtTimeoutSynSent:     unixext.CTA_TIMEOUT_TCP_SYN_SENT,
+	CtTimeoutSynRecv:     unixext.CTA_TIMEOUT_TCP_SYN_RECV,
+	CtTimeoutEstablished: unixext.CTA_TIMEOUT_TCP_ESTABLISHED,
+	CtTimeoutFinWait:     unixext.CTA_TIMEOUT_TCP_FIN_WAIT,
+	CtTimeoutCloseWait:   unixext.CTA_TIMEOUT_TCP_CLOSE_WAIT,
+	CtTimeoutLastAck:     unixext.CTA_TIMEOUT_TCP_LAST_ACK,
+	CtTimeoutTimeWait:    unixext.CTA_TIMEOUT_TCP_TIME_WAIT,
+	CtTimeoutClose:       unixext.CTA_TIMEOUT_TCP_CLOSE,
+	CtTimeoutSynSent2:    unixext.CTA_TIMEOUT_TCP_SYN_SENT2,
+	CtTimeoutRetrans:     unixext.CTA_TIMEOUT_TCP_RETRANS,
+	CtTimeoutUnack:       unixext.CTA_TIMEOUT_TCP_UNACK,
+}
+
+var ctTimeoutUDPStates = map[CtTimeoutState]uint16{
+	CtTimeoutUnreplied: unixext.CTA_TIMEOUT_UDP_UNREPLIED,
+	CtTimeoutReplied:   unixext.CTA_TIMEOUT_UDP_REPLIED,
+}
+
+var ctTimeoutICMPStates = map[CtTimeoutState]uint16{
+	CtTimeoutGeneric: unixext.CTA_TIMEOUT_ICMP_TIMEOUT,
+}
+
+var ctTimeoutICMPv6States = map[CtTimeoutState]uint16{
+	CtTimeoutGeneric: unixext.CTA_TIMEOUT_ICMPV6_TIMEOUT,
+}
+
+// ctTimeoutStates returns the timeout attributes of the states of l4proto,
+// or nil if its states are not supported.
+func ctTimeoutStates(l4proto uint8) map[CtTimeoutState]uint16 {
+	switch l4proto {
+	case unix.IPPROTO_TCP:
+		return ctTimeoutTCPStates
+	case unix.IPPROTO_UDP, unix.IPPROTO_UDPLITE:
+		return ctTimeoutUDPStates
+	case unix.IPPROTO_ICMP:
+		return ctTimeoutICMPStates
+	case unix.IPPROTO_ICMPV6:
+		return ctTimeoutICMPv6States
+	}
+	return nil
+}
+
+// CtTimeout is a timeout policy overriding the default conntrack timeouts
+// of the connections it is assigned to. States that are not set keep their
+// default, and the kernel reports every state of the protocol on dump. The
+// kernel keeps timeouts with a precision of a second.
+type CtTimeout struct {
+	// L3Proto defaults to the family of the table.
+	L3Proto uint8
+	// L4Proto is one of tcp, udp, udplite, icmp or icmpv6, whose states
+	// are known.
+	L4Proto  uint8
+	Timeouts map[CtTimeoutState]time.Duration
+}
+
+func (c *CtTimeout) validate() error {
+	if c.L4Proto == 0 {
+		return fmt.Errorf("ct timeout L4 protocol must be specified")
+	}
+	states := ctTimeoutStates(c.L4Proto)
+	if states == nil {
+		return fmt.Errorf("ct timeout L4 protocol must be tcp, udp, udplite, icmp or icmpv6")
+	}
+	for state := range c.Timeouts {
+		if _, ok := states[state]; !ok {
+			return fmt.Errorf("ct timeout state %q is not valid for L4 protocol %d", state, c.L4Proto)
+		}
+	}
+	return nil
+}
+
+func (c *CtTimeout) marshal() *nftnl.CtTimeoutAttrs {
+	attrs := &nftnl.CtTimeoutAttrs{
+		L3Proto:  uint16(c.L3Proto),
+		L4Proto:  c.L4Proto,
+		Timeouts: make(map[uint16]uint32),
+	}
+	states := ctTimeoutStates(c.L4Proto)
+	for state, timeout := range c.Timeouts {
+		attrs.Timeouts[states[state]] = uint32(timeout / time.Second)
+	}
+	return attrs
+}
+
+func ctTimeoutFromAttrs(family uint8, attr *nftnl.CtTimeoutAttrs) *CtTimeout {
+	c := &CtTimeout{
+		L3Proto:  ctObjectL3Proto(family, attr.L3Proto),
+		L4Proto:  attr.L4Proto,
+		Timeouts: make(map[CtTimeoutState]time.Duration),
+	}
+	for state, typ := range ctTimeoutStates(attr.L4Proto) {
+		if timeout, ok := attr.Timeouts[typ]; ok {
+			c.Timeouts[state] = time.Duration(timeout) * time.Second
+		}
+	}
+	return c
+}
+
+// CtExpect creates an expectation for a related connection to DPort when
+// it is assigned to a connection, as a conntrack helper would.
+type CtExpect struct {
+	// L3Proto defaults to the family of the table. It must be set to
+	// NFPROTO_IPV4 or NFPROTO_IPV6 in inet tables, and to the family of the
+	// table otherwise.
+	L3Proto uint8
+	L4Proto uint8
+	DPort   uint16
+	// Timeout after which the expectation expires. The kernel keeps it with
+	// a precision of a millisecond.
+	Timeout time.Duration
+	// Size is the maximum number of expectations of the connection.
+	Size uint8
+}
+
+func (c *CtExpect) validate(family uint8) error {
+	if family == unix.NFPROTO_INET && c.L3Proto != unix.NFPROTO_IPV4 && c.L3Proto != unix.NFPROTO_IPV6 {
+		return fmt.Errorf("ct expectation L3 protocol must be ipv4 or ipv6 in the inet family")
+	}
+	if family != unix.NFPROTO_INET && c.L3Proto != 0 && c.L3Proto != family {
+		return fmt.Errorf("ct expectation L3 protocol must match the family of the table")
+	}
+	if c.L4Proto == 0 {
+		return fmt.Errorf("ct expectation L4 protocol must be specified")
+	}
+	if c.DPort == 0 {
+		return fmt.Errorf("ct expectation destination port must be specified")
+	}
+	if c.Size == 0 {
+		return fmt.Errorf("ct expectation size must be specified")
+	}
+	return nil
+}
+
+func (c *CtExpect) marshal() *nftnl.CtExpectAttrs {
+	return &nftnl.CtExpectAttrs{
+		L3Proto: uint16(c.L3Proto),
+		L4Proto: c.L4Proto,
+		DPort:   c.DPort,
+		Timeout: uint32(c.Timeout.Milliseconds()),
+		Size:    c.Size,
+	}
+}
+
+func ctExpectFromAttrs(family uint8, attr *nftnl.CtExpectAttrs) *CtExpect {
+	return &CtExpect{
+		L3Proto: ctObjectL3Proto(family, attr.L3Proto),
+		L4Proto: attr.L4Proto,
+		DPort:   attr.DPort,
+		Timeout: time.Duration(attr.Timeout) * time.Millisecond,
+		Size:    attr.Size,
+	}
+}
@@ -0,0 +1,112 @@
+package nft
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+// Dup sends a copy of the packet to the gateway Addr, through the interface
+// with index Ifindex when it is set. In the netdev family the copy is sent
+// out of Ifindex unchanged and Addr must not be set.
+type Dup struct {
+	Addr    *netip.Addr
+	Ifindex uint32
+}
+
+func (d *Dup) validate(r *Rule) error {
+	switch r.Family {
+	case unix.NFPROTO_IPV4, unix.NFPROTO_IPV6:
+		if d.Addr == nil || !d.Addr.IsValid() {
+			return fmt.Errorf("dup requires an address")
+		}
+		if d.Addr.Is4() != (r.Family == unix.NFPROTO_IPV4) {
+			return fmt.Errorf("dup address family does not match the rule")
+		}
+	case unix.NFPROTO_NETDEV:
+		if d.Addr != nil {
+			return fmt.Errorf("dup in the netdev family does not support an address")
+		}
+		if d.Ifindex == 0 {
+			return fmt.Errorf("dup in the netdev family requires an interface")
+		}
+	default:
+		return fmt.Errorf("dup is only supported in the ipv4, ipv6 and netdev families")
+	}
+	return nil
+}
+
+func dupExpr(d *Dup) []nftnl.ExprAttrs {
+	attrs := &nftnl.DupAttrs{}
+
+	var exprs []nftnl.ExprAttrs
+	if d.Addr != nil {
+		attrs.SRegAddr = 1
+		exprs = appendExpr(exprs,
+			&nftnl.ImmediateAttrs{
+				DReg: attrs.SRegAddr,
+				Data: &nftnl.DataAttrs{
+					Value: d.Addr.AsSlice(),
+				},
+			},
+		)
+	}
+	if d.Ifindex != 0 {
+		attrs.SRegDev = 2
+		exprs = appendExpr(exprs, ifindexImmediate(attrs.SRegDev, d.Ifindex))
+	}
+
+	return appendExpr(exprs, attrs)
+}
+
+// dupFromExprs decodes the dup statement at exprs[i] from the immediates
+// loading its registers.
+func dupFromExprs(exprs []nftnl.ExprAttrs, i int) *Dup {
+	attr := exprs[i].Data.(*nftnl.DupAttrs)
+	d := &Dup{}
+	for reg, value := range immediatesBefore(exprs, i) {
+		switch reg {
+		case attr.SRegAddr:
+			if addr, ok := netip.AddrFromSlice(value); ok {
+				d.Addr = &addr
+			}
+		case attr.SRegDev:
+			if len(value) >= 4 {
+				d.Ifindex = binary.NativeEndian.Uint32(value)
+			}
+		}
+	}
+	return d
+}
+
+func ifindexImmediate(dreg uint32, ifindex uint32) *nftnl.ImmediateAttrs {
+	value := make([]byte, 4)
+	binary.NativeEndian.PutUint32(value, ifindex)
+	return &nftnl.ImmediateAttrs{
+		DReg: dreg,
+		Data: &nftnl.DataAttrs{
+			Value: value,
+		},
+	}
+}
+
+// immediatesBefore returns the values loaded into registers by the
+// immediates directly preceding exprs[i].
+func immediatesBefore(exprs []nftnl.ExprAttrs, i int) map[uint32][]byte {
+	values := make(map[uint32][]byte)
+	for j := i - 1; j >= 0; j-- {
+		imm, ok := exprs[j].Data.(*nftnl.ImmediateAttrs)
+		if !ok {
+			break
+		}
+		data, ok := imm.Data.(*nftnl.DataAttrs)
+		if !ok {
+			break
+		}
+		values[imm.DReg] = data.Value
+	}
+	return values
+}
@@ -0,0 +1,154 @@
+package nft
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+type SetUpdateOp uint8
+
+const (
+	// SetUpdateOpAdd adds the element if it is missing. The timeout of an
+	// existing element is left untouched.
+	SetUpdateOpAdd SetUpdateOp = 0x1
+	// SetUpdateOpUpdate adds the element if it is missing and refreshes its
+	// timeout otherwise.
+	SetUpdateOpUpdate SetUpdateOp = 0x2
+)
+
+// SetKey is the field of the packet used as the key of a set or map.
+type SetKey uint8
+
+const (
+	SetKeySrcAddr SetKey = 0x1
+	SetKeyDstAddr SetKey = 0x2
+)
+
+// SetUpdate adds an element keyed on a field of the packet to a set from the
+// packet path. The set must have been created with the NFT_SET_EVAL flag.
+//
+// Connlimit and Last are attached to the element and evaluated for every
+// packet that reaches it, so a Connlimit counts the connections of each key
+// separately. The rule stops matching when the Connlimit does not match.
+// Sets holding a Connlimit cannot have a timeout.
+type SetUpdate struct {
+	Op    SetUpdateOp
+	Set   string
+	SetID uint32
+	Key   SetKey
+	// Timeout of the element, the set's default timeout is used when unset.
+	Timeout   time.Duration
+	Connlimit *Connlimit
+	Last      *Last
+}
+
+func (u *SetUpdate) validate(r *Rule) error {
+	if u.Set == "" {
+		return fmt.Errorf("set update requires a set name")
+	}
+	switch u.Op {
+	case SetUpdateOpAdd, SetUpdateOpUpdate:
+	default:
+		return fmt.Errorf("unknown set update op %d", u.Op)
+	}
+	return u.Key.validate(r)
+}
+
+func (k SetKey) validate(r *Rule) error {
+	switch k {
+	case SetKeySrcAddr, SetKeyDstAddr:
+		if r.l3proto() == 0 {
+			return fmt.Errorf("set key on an address requires the L3 protocol to be specified")
+		}
+	default:
+		return fmt.Errorf("unknown set key %d", k)
+	}
+	return nil
+}
+
+// load returns the expression loading the key into dreg.
+func (k SetKey) load(l3proto uint8, dreg uint32) nftnl.ExprDataAttrs {
+	field := HashFieldSrcAddr
+	if k == SetKeyDstAddr {
+		field = HashFieldDstAddr
+	}
+	load, _ := hashFieldLoad(field, l3proto, dreg)
+	return load
+}
+
+// setKeyFromExpr returns the key loaded by attr and its register.
+func setKeyFromExpr(attr nftnl.ExprDataAttrs) (SetKey, uint32) {
+	field, reg := hashFieldFromExpr(attr)
+	switch field {
+	case HashFieldSrcAddr:
+		return SetKeySrcAddr, reg
+	case HashFieldDstAddr:
+		return SetKeyDstAddr, reg
+	}
+	return 0, 0
+}
+
+func setUpdateExpr(u *SetUpdate, l3proto uint8) []nftnl.ExprAttrs {
+	load := u.Key.load(l3proto, 1)
+	attrs := &nftnl.DynsetAttrs{
+		SetName: u.Set,
+		SetID:   u.SetID,
+		Op:      unix.NFT_DYNSET_OP_ADD,
+		SRegKey: 1,
+		Timeout: uint64(u.Timeout.Milliseconds()),
+	}
+	if u.Op == SetUpdateOpUpdate {
+		attrs.Op = unix.NFT_DYNSET_OP_UPDATE
+	}
+	if u.Connlimit != nil {
+		attrs.Expressions = append(attrs.Expressions, u.Connlimit.marshal())
+	}
+	if u.Last != nil {
+		attrs.Expressions = append(attrs.Expressions, u.Last.marshal())
+	}
+	if len(attrs.Expressions) > 1 {
+		attrs.Flags = unixext.NFT_DYNSET_F_EXPR
+	}
+
+	return appendExpr(nil, load, attrs)
+}
+
+// setUpdateFromExprs decodes the dynset at exprs[i] and the load of its key
+// preceding it.
+func setUpdateFromExprs(exprs []nftnl.ExprAttrs, i int) *SetUpdate {
+	attr := exprs[i].Data.(*nftnl.DynsetAttrs)
+	if i == 0 {
+		return nil
+	}
+	key, reg := setKeyFromExpr(exprs[i-1].Data)
+	if key == 0 || reg != attr.SRegKey {
+		return nil
+	}
+
+	u := &SetUpdate{
+		Op:      SetUpdateOpAdd,
+		Set:     attr.SetName,
+		Key:     key,
+		Timeout: time.Duration(attr.Timeout) * time.Millisecond,
+	}
+	switch attr.Op {
+	case unix.NFT_DYNSET_OP_ADD:
+	case unix.NFT_DYNSET_OP_UPDATE:
+		u.Op = SetUpdateOpUpdate
+	default:
+		return nil
+	}
+	for _, expr := range attr.Expressions {
+		switch e := expr.(type) {
+		case *nftnl.ConnlimitAttrs:
+			u.Connlimit = connlimitFromAttrs(e)
+		case *nftnl.LastAttrs:
+			u.Last = lastFromAttrs(e)
+		}
+	}
+	return u
+}
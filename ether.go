@@ -0,0 +1,305 @@
+package nft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+// EtherMatch matches the Ethernet header of the packet. Outside of the
+// bridge family the rule also requires an Ethernet input interface, which
+// is not decoded into Rule.IIfaceMatch.
+type EtherMatch struct {
+	Src net.HardwareAddr
+	Dst net.HardwareAddr
+	// Type is the ethertype following the VLAN tags matched by the rule,
+	// such as unix.ETH_P_IP. It is compared unless 0.
+	Type uint16
+}
+
+// VLANMatch matches an 802.1Q tag (Rule.VLAN) or an outer 802.1ad tag
+// (Rule.ServiceVLAN), which precedes the 802.1Q tag if both are matched.
+// An empty match only requires the tag to be present.
+type VLANMatch struct {
+	ID *uint16
+	// PCP is the priority code point.
+	PCP *uint8
+	// DEI is the drop eligible indicator.
+	DEI *bool
+}
+
+// PacketType is the class of the link layer destination of a packet.
+type PacketType uint8
+
+const (
+	// PacketTypeHost is a unicast packet addressed to the host.
+	PacketTypeHost      PacketType = unix.PACKET_HOST
+	PacketTypeBroadcast PacketType = unix.PACKET_BROADCAST
+	PacketTypeMulticast PacketType = unix.PACKET_MULTICAST
+	// PacketTypeOtherHost is a unicast packet addressed to another host,
+	// such as a bridged one.
+	PacketTypeOtherHost PacketType = unix.PACKET_OTHERHOST
+)
+
+func (m *EtherMatch) validate() error {
+	if m.Src == nil && m.Dst == nil && m.Type == 0 {
+		return fmt.Errorf("ether match requires an address or a type")
+	}
+	for _, addr := range []net.HardwareAddr{m.Src, m.Dst} {
+		if addr != nil && len(addr) != 6 {
+			return fmt.Errorf("ether address %s is not a MAC-48 address", addr)
+		}
+	}
+	if m.Type == unix.ETH_P_8021Q || m.Type == unix.ETH_P_8021AD {
+		return fmt.Errorf("VLAN tags must be matched with VLAN and ServiceVLAN")
+	}
+	return nil
+}
+
+func (m *VLANMatch) validate() error {
+	if m.ID != nil && *m.ID > 0xfff {
+		return fmt.Errorf("VLAN ID %d exceeds 4095", *m.ID)
+	}
+	if m.PCP != nil && *m.PCP > 7 {
+		return fmt.Errorf("VLAN PCP %d exceeds 7", *m.PCP)
+	}
+	return nil
+}
+
+// hasLinkMatch reports whether the rule matches on the Ethernet header.
+func (r *Rule) hasLinkMatch() bool {
+	return r.Ether != nil || r.VLAN != nil || r.ServiceVLAN != nil
+}
+
+func (r *Rule) validateEther() error {
+	if r.Ether != nil {
+		if err := r.Ether.validate(); err != nil {
+			return err
+		}
+	}
+	for _, m := range []*VLANMatch{r.VLAN, r.ServiceVLAN} {
+		if m == nil {
+			continue
+		}
+		if err := m.validate(); err != nil {
+			return err
+		}
+	}
+	if r.hasLinkMatch() && r.Family != unix.NFPROTO_BRIDGE &&
+		r.IIfaceMatch != nil && r.IIfaceMatch.Type != 0 && r.IIfaceMatch.Type != unix.ARPHRD_ETHER {
+		return fmt.Errorf("ether matching requires an Ethernet input interface")
+	}
+	return nil
+}
+
+// llMatchExpr compares length bytes at offset of the link layer header,
+// masked with mask if it is not nil, with value.
+func llMatchExpr(offset, length uint32, mask, value []byte) []nftnl.ExprAttrs {
+	exprs := appendExpr(nil,
+		&nftnl.PayloadAttrs{
+			DReg:   1,
+			Base:   unix.NFT_PAYLOAD_LL_HEADER,
+			Offset: offset,
+			Len:    length,
+		},
+	)
+	if mask != nil {
+		exprs = appendExpr(exprs,
+			&nftnl.BitwiseAttrs{
+				SReg: 1,
+				DReg: 1,
+				Len:  length,
+				Mask: &nftnl.DataAttrs{
+					Value: mask,
+				},
+				Xor: &nftnl.DataAttrs{
+					Value: make([]byte, length),
+				},
+			},
+		)
+	}
+	return appendExpr(exprs,
+		&nftnl.CmpAttrs{
+			SReg: 1,
+			Op:   unix.NFT_CMP_EQ,
+			Data: &nftnl.DataAttrs{
+				Value: value,
+			},
+		},
+	)
+}
+
+func etherTypeExpr(offset uint32, typ uint16) []nftnl.ExprAttrs {
+	return llMatchExpr(offset, 2, nil, binary.BigEndian.AppendUint16(nil, typ))
+}
+
+// vlanExpr matches the fields of the tag control information at offset.
+func vlanExpr(m *VLANMatch, offset uint32) []nftnl.ExprAttrs {
+	var exprs []nftnl.ExprAttrs
+	if m.ID != nil {
+		exprs = append(exprs, llMatchExpr(offset, 2, []byte{0x0f, 0xff}, binary.BigEndian.AppendUint16(nil, *m.ID))...)
+	}
+	if m.PCP != nil {
+		exprs = append(exprs, llMatchExpr(offset, 1, []byte{0xe0}, []byte{*m.PCP << 5})...)
+	}
+	if m.DEI != nil {
+		value := []byte{0}
+		if *m.DEI {
+			value[0] = 0x10
+		}
+		exprs = append(exprs, llMatchExpr(offset, 1, []byte{0x10}, value)...)
+	}
+	return exprs
+}
+
+func (r *Rule) marshalEtherExprs() []nftnl.ExprAttrs {
+	var exprs []nftnl.ExprAttrs
+	if r.hasLinkMatch() && r.Family != unix.NFPROTO_BRIDGE && (r.IIfaceMatch == nil || r.IIfaceMatch.Type == 0) {
+		exprs = append(exprs, ifaceMatchExpr(&IfaceMatch{Type: unix.ARPHRD_ETHER}, iifMetaKeys)...)
+	}
+
+	if r.Ether != nil && r.Ether.Dst != nil {
+		exprs = append(exprs, llMatchExpr(0, 6, nil, r.Ether.Dst)...)
+	}
+	if r.Ether != nil && r.Ether.Src != nil {
+		exprs = append(exprs, llMatchExpr(6, 6, nil, r.Ether.Src)...)
+	}
+
+	offset := uint32(12)
+	if r.ServiceVLAN != nil {
+		exprs = append(exprs, etherTypeExpr(offset, unix.ETH_P_8021AD)...)
+		exprs = append(exprs, vlanExpr(r.ServiceVLAN, offset+2)...)
+		offset += 4
+	}
+	if r.VLAN != nil {
+		exprs = append(exprs, etherTypeExpr(offset, unix.ETH_P_8021Q)...)
+		exprs = append(exprs, vlanExpr(r.VLAN, offset+2)...)
+		offset += 4
+	}
+	if r.Ether != nil && r.Ether.Type != 0 {
+		exprs = append(exprs, etherTypeExpr(offset, r.Ether.Type)...)
+	}
+
+	if r.PktType != nil {
+		exprs = appendExpr(exprs,
+			&nftnl.MetaAttrs{
+				DReg: 1,
+				Key:  unix.NFT_META_PKTTYPE,
+			},
+			&nftnl.CmpAttrs{
+				SReg: 1,
+				Op:   unix.NFT_CMP_EQ,
+				Data: &nftnl.DataAttrs{
+					Value: []byte{uint8(*r.PktType)},
+				},
+			},
+		)
+	}
+	return exprs
+}
+
+// llCmp is a comparison of the link layer header.
+type llCmp struct {
+	offset uint32
+	mask   []byte
+	value  []byte
+}
+
+func (r *Rule) unmarshalEtherExprs(attrs *nftnl.RuleAttrs) {
+	exprs := attrs.Expressions
+	var cmps []llCmp
+	for i := 0; i+1 < len(exprs); i++ {
+		if meta, ok := exprs[i].Data.(*nftnl.MetaAttrs); ok && meta.Key == unix.NFT_META_PKTTYPE {
+			cmp, ok := exprs[i+1].Data.(*nftnl.CmpAttrs)
+			if ok && cmp.Op == unix.NFT_CMP_EQ && cmp.Data != nil && len(cmp.Data.Value) == 1 {
+				typ := PacketType(cmp.Data.Value[0])
+				r.PktType = &typ
+				i++
+			}
+			continue
+		}
+
+		load, ok := exprs[i].Data.(*nftnl.PayloadAttrs)
+		if !ok || load.DReg == 0 || load.Base != unix.NFT_PAYLOAD_LL_HEADER {
+			continue
+		}
+		c := llCmp{offset: load.Offset}
+		next := i + 1
+		if bitwise, ok := exprs[next].Data.(*nftnl.BitwiseAttrs); ok && bitwise.Mask != nil && next+1 < len(exprs) {
+			c.mask = bitwise.Mask.Value
+			next++
+		}
+		cmp, ok := exprs[next].Data.(*nftnl.CmpAttrs)
+		if !ok || cmp.Op != unix.NFT_CMP_EQ || cmp.Data == nil || uint32(len(cmp.Data.Value)) != load.Len {
+			continue
+		}
+		c.value = cmp.Data.Value
+		cmps = append(cmps, c)
+		i = next
+	}
+	if len(cmps) == 0 {
+		return
+	}
+
+	find := func(offset uint32, length int, mask []byte) []byte {
+		for _, c := range cmps {
+			if c.offset == offset && len(c.value) == length && bytes.Equal(c.mask, mask) {
+				return c.value
+			}
+		}
+		return nil
+	}
+	etherType := func(offset uint32) uint16 {
+		if v := find(offset, 2, nil); v != nil {
+			return binary.BigEndian.Uint16(v)
+		}
+		return 0
+	}
+	vlan := func(offset uint32) *VLANMatch {
+		m := &VLANMatch{}
+		if v := find(offset, 2, []byte{0x0f, 0xff}); v != nil {
+			id := binary.BigEndian.Uint16(v)
+			m.ID = &id
+		}
+		if v := find(offset, 1, []byte{0xe0}); v != nil {
+			pcp := v[0] >> 5
+			m.PCP = &pcp
+		}
+		if v := find(offset, 1, []byte{0x10}); v != nil {
+			dei := v[0] != 0
+			m.DEI = &dei
+		}
+		return m
+	}
+
+	ether := &EtherMatch{}
+	if v := find(0, 6, nil); v != nil {
+		ether.Dst = net.HardwareAddr(v)
+	}
+	if v := find(6, 6, nil); v != nil {
+		ether.Src = net.HardwareAddr(v)
+	}
+	offset := uint32(12)
+	if etherType(offset) == unix.ETH_P_8021AD {
+		r.ServiceVLAN = vlan(offset + 2)
+		offset += 4
+	}
+	if etherType(offset) == unix.ETH_P_8021Q {
+		r.VLAN = vlan(offset + 2)
+		offset += 4
+	}
+	ether.Type = etherType(offset)
+	if ether.Src != nil || ether.Dst != nil || ether.Type != 0 {
+		r.Ether = ether
+	}
+
+	// The Ethernet interface is implied outside of the bridge family.
+	if r.hasLinkMatch() && r.Family != unix.NFPROTO_BRIDGE &&
+		r.IIfaceMatch != nil && *r.IIfaceMatch == (IfaceMatch{Type: unix.ARPHRD_ETHER}) {
+		r.IIfaceMatch = nil
+	}
+}
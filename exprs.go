@@ -18,10 +18,10 @@ func (r *Rule) marshalExprs() []nftnl.ExprAttrs {
 				Key:  unix.NFT_META_IIFNAME,
 			},
 			&nftnl.CmpAttrs{
-				Op:   unix.NFT_CMP_EQ,
+				Op:   cmpOp(r.NegateIIface),
 				SReg: 1,
 				Data: &nftnl.DataAttrs{
-					Value: []byte(r.IIface + "\x00"),
+					Value: ifaceNameValue(r.IIface),
 				},
 			},
 		)
@@ -34,15 +34,29 @@ func (r *Rule) marshalExprs() []nftnl.ExprAttrs {
 				Key:  unix.NFT_META_OIFNAME,
 			},
 			&nftnl.CmpAttrs{
-				Op:   unix.NFT_CMP_EQ,
+				Op:   cmpOp(r.NegateOIface),
 				SReg: 1,
 				Data: &nftnl.DataAttrs{
-					Value: []byte(r.OIface + "\x00"),
+					Value: ifaceNameValue(r.OIface),
 				},
 			},
 		)
 	}
 
+	if r.IIfaceMatch != nil {
+		exprs = append(exprs, ifaceMatchExpr(r.IIfaceMatch, iifMetaKeys)...)
+	}
+
+	if r.OIfaceMatch != nil {
+		exprs = append(exprs, ifaceMatchExpr(r.OIfaceMatch, oifMetaKeys)...)
+	}
+
+	exprs = append(exprs, r.marshalEtherExprs()...)
+
+	if r.ARP != nil {
+		exprs = append(exprs, r.marshalARPExprs()...)
+	}
+
 	if r.L3Proto != 0 {
 		exprs = appendExpr(exprs,
 			&nftnl.MetaAttrs{
@@ -50,7 +64,7 @@ func (r *Rule) marshalExprs() []nftnl.ExprAttrs {
 				Key:  unix.NFT_META_NFPROTO,
 			},
 			&nftnl.CmpAttrs{
-				Op:   unix.NFT_CMP_EQ,
+				Op:   cmpOp(r.NegateL3Proto),
 				SReg: 1,
 				Data: &nftnl.DataAttrs{
 					Value: []byte{r.L3Proto},
@@ -60,103 +74,144 @@ func (r *Rule) marshalExprs() []nftnl.ExprAttrs {
 	}
 
 	if r.SrcIPv4 != nil {
-		if r.SrcIPv4.Prefix != nil {
-			exprs = append(exprs, prefixExpr(r.SrcIPv4.Prefix, true)...)
-		} else if r.SrcIPv4.Addr != nil {
-			exprs = append(exprs, addrExpr(r.SrcIPv4.Addr, true)...)
-		}
+		exprs = append(exprs, ipMatchExpr(r.SrcIPv4, true, net.IPv4len)...)
 	}
 
 	if r.DstIPv4 != nil {
-		if r.DstIPv4.Prefix != nil {
-			exprs = append(exprs, prefixExpr(r.DstIPv4.Prefix, false)...)
-		} else if r.DstIPv4.Addr != nil {
-			exprs = append(exprs, addrExpr(r.DstIPv4.Addr, false)...)
-		}
+		exprs = append(exprs, ipMatchExpr(r.DstIPv4, false, net.IPv4len)...)
 	}
 
 	if r.SrcIPv6 != nil {
-		if r.SrcIPv6.Prefix != nil {
-			exprs = append(exprs, prefixExpr(r.SrcIPv6.Prefix, true)...)
-		} else if r.SrcIPv6.Addr != nil {
-			exprs = append(exprs, addrExpr(r.SrcIPv6.Addr, true)...)
-		}
+		exprs = append(exprs, ipMatchExpr(r.SrcIPv6, true, net.IPv6len)...)
 	}
 
 	if r.DstIPv6 != nil {
-		if r.DstIPv6.Prefix != nil {
-			exprs = append(exprs, prefixExpr(r.DstIPv6.Prefix, false)...)
-		} else if r.DstIPv6.Addr != nil {
-			exprs = append(exprs, addrExpr(r.DstIPv6.Addr, false)...)
-		}
+		exprs = append(exprs, ipMatchExpr(r.DstIPv6, false, net.IPv6len)...)
 	}
 
-	if r.L4Proto != 0 {
+	l4proto := r.l4proto()
+	if r.NegateL4Proto {
+		l4proto = r.L4Proto
+	}
+	if l4proto != 0 {
 		exprs = appendExpr(exprs,
 			&nftnl.MetaAttrs{
 				DReg: 1,
 				Key:  unix.NFT_META_L4PROTO,
 			},
 			&nftnl.CmpAttrs{
-				Op:   unix.NFT_CMP_EQ,
+				Op:   cmpOp(r.NegateL4Proto),
 				SReg: 1,
 				Data: &nftnl.DataAttrs{
-					Value: []byte{r.L4Proto},
+					Value: []byte{l4proto},
 				},
 			},
 		)
 	}
 
+	if r.l4proto() == 0 && (r.SrcPort != nil || r.DstPort != nil) {
+		exprs = append(exprs, portProtoExpr(r.anonSetID(nil))...)
+	}
+
 	if r.SrcPort != nil {
-		if r.SrcPort.Port != 0 {
-			exprs = append(exprs, portExpr(r.SrcPort.Port)...)
-		}
+		exprs = append(exprs, portExpr(r.SrcPort, portLoad(true), r.anonSetID(r.SrcPort))...)
 	}
 
 	if r.DstPort != nil {
-		if r.DstPort.Port != 0 {
-			exprs = append(exprs, portExpr(r.DstPort.Port)...)
-		}
+		exprs = append(exprs, portExpr(r.DstPort, portLoad(false), r.anonSetID(r.DstPort))...)
+	}
+
+	exprs = append(exprs, r.marshalICMPExprs()...)
+
+	if r.TCPFlags != nil {
+		exprs = append(exprs, tcpFlagsExpr(r.TCPFlags)...)
+	}
+
+	if r.Mark != nil {
+		exprs = append(exprs, markMatchExpr(metaFieldLoad(MetaFieldMark), r.Mark)...)
+	}
+
+	if r.SkUID != nil {
+		exprs = append(exprs, ownerExpr(r.SkUID, unix.NFT_META_SKUID)...)
+	}
+
+	if r.SkGID != nil {
+		exprs = append(exprs, ownerExpr(r.SkGID, unix.NFT_META_SKGID)...)
+	}
+
+	if r.Time != nil {
+		exprs = append(exprs, r.marshalTimeExprs()...)
+	}
+
+	if r.Fib != nil {
+		exprs = append(exprs, fibExpr(r.Fib)...)
+	}
+
+	if r.Rt != nil {
+		exprs = append(exprs, rtExpr(r.Rt)...)
+	}
+
+	if r.Socket != nil {
+		exprs = append(exprs, socketExpr(r.Socket)...)
+	}
+
+	for _, m := range r.Xfrm {
+		exprs = append(exprs, xfrmExpr(m)...)
+	}
+
+	if r.Osf != nil {
+		exprs = append(exprs, osfExpr(r.Osf)...)
+	}
+
+	if r.Inner != nil {
+		exprs = append(exprs, innerExpr(r.Inner)...)
+	}
+
+	if r.Tunnel != nil {
+		exprs = append(exprs, tunnelExpr(r.Tunnel)...)
+	}
+
+	for _, m := range r.XtMatches {
+		exprs = append(exprs, xtMatchExpr(m)...)
+	}
+
+	for _, m := range r.Exthdrs {
+		exprs = append(exprs, exthdrMatchExpr(m)...)
 	}
 
 	if r.Ct != nil {
 		if r.Ct.SrcIPv4 != nil {
-			if r.Ct.SrcIPv4.Prefix != nil {
-				exprs = append(exprs, ctPrefixExpr(r.Ct.SrcIPv4.Prefix, true)...)
-			}
+			exprs = append(exprs, ctIPMatchExpr(r.Ct.SrcIPv4, true, net.IPv4len)...)
 		}
 
 		if r.Ct.DstIPv4 != nil {
-			if r.Ct.DstIPv4.Prefix != nil {
-				exprs = append(exprs, ctPrefixExpr(r.Ct.DstIPv4.Prefix, false)...)
-			}
+			exprs = append(exprs, ctIPMatchExpr(r.Ct.DstIPv4, false, net.IPv4len)...)
 		}
 
 		if r.Ct.SrcIPv6 != nil {
-			if r.Ct.SrcIPv6.Prefix != nil {
-				exprs = append(exprs, ctPrefixExpr(r.Ct.SrcIPv6.Prefix, true)...)
-			}
+			exprs = append(exprs, ctIPMatchExpr(r.Ct.SrcIPv6, true, net.IPv6len)...)
 		}
 
 		if r.Ct.DstIPv6 != nil {
-			if r.Ct.DstIPv6.Prefix != nil {
-				exprs = append(exprs, ctPrefixExpr(r.Ct.DstIPv6.Prefix, false)...)
-			}
+			exprs = append(exprs, ctIPMatchExpr(r.Ct.DstIPv6, false, net.IPv6len)...)
 		}
 
 		if r.Ct.SrcPort != nil {
-			if r.Ct.SrcPort.Port != 0 {
-				exprs = append(exprs, ctPortExpr(r.Ct.SrcPort.Port)...)
-			}
+			exprs = append(exprs, portExpr(r.Ct.SrcPort, ctPortLoad(true), r.anonSetID(r.Ct.SrcPort))...)
 		}
 		if r.Ct.DstPort != nil {
-			if r.Ct.DstPort.Port != 0 {
-				exprs = append(exprs, ctPortExpr(r.Ct.DstPort.Port)...)
-			}
+			exprs = append(exprs, portExpr(r.Ct.DstPort, ctPortLoad(false), r.anonSetID(r.Ct.DstPort))...)
 		}
 		if len(r.Ct.States) > 0 {
 			exprs = append(exprs, ctStateExpr(r.Ct.States)...)
 		}
+		if r.Ct.Mark != nil {
+			exprs = append(exprs, markMatchExpr(metaFieldLoad(MetaFieldCtMark), r.Ct.Mark)...)
+		}
+	}
+
+	if r.Connlimit != nil {
+		exprs = appendExpr(exprs, r.Connlimit.marshal())
 	}
 
 	if r.Counter != nil {
@@ -168,6 +223,10 @@ func (r *Rule) marshalExprs() []nftnl.ExprAttrs {
 		)
 	}
 
+	if r.Last != nil {
+		exprs = appendExpr(exprs, r.Last.marshal())
+	}
+
 	if r.Quota != nil {
 		exprs = appendExpr(exprs,
 			&nftnl.QuotaAttrs{
@@ -176,7 +235,67 @@ func (r *Rule) marshalExprs() []nftnl.ExprAttrs {
 		)
 	}
 
+	if r.Limit != nil {
+		exprs = appendExpr(exprs, r.Limit.marshal())
+	}
+
+	for _, ref := range r.ObjectRefs {
+		exprs = append(exprs, objectRefExpr(ref, r.l3proto())...)
+	}
+
+	for _, u := range r.SetUpdates {
+		exprs = append(exprs, setUpdateExpr(u, r.l3proto())...)
+	}
+
+	for _, set := range r.MetaSets {
+		exprs = append(exprs, metaSetExpr(set)...)
+	}
+
+	for _, set := range r.PayloadSets {
+		exprs = append(exprs, payloadSetExpr(set, r.l3proto(), r.l4proto())...)
+	}
+
+	for _, set := range r.ExthdrSets {
+		exprs = append(exprs, exthdrSetExpr(set)...)
+	}
+
+	if r.Tproxy != nil {
+		exprs = append(exprs, tproxyExpr(r.Tproxy, r.l3proto())...)
+	}
+
+	if r.Dup != nil {
+		exprs = append(exprs, dupExpr(r.Dup)...)
+	}
+
+	if r.Synproxy != nil {
+		exprs = appendExpr(exprs, r.Synproxy.marshal())
+	}
+
+	if r.Notrack {
+		exprs = appendExpr(exprs, &nftnl.NotrackAttrs{})
+	}
+
+	if r.XtTarget != nil {
+		exprs = append(exprs, xtTargetExpr(r.XtTarget)...)
+	}
+
 	if r.Action != nil {
+		if r.Action.Log != nil {
+			exprs = append(exprs, logExpr(r.Action.Log)...)
+		}
+		if r.Action.Reject != nil {
+			exprs = append(exprs, rejectExpr(r.Action.Reject, r.Family, r.l3proto())...)
+		}
+		if r.Action.Queue != nil {
+			exprs = append(exprs, queueExpr(r.Action.Queue, r.l3proto())...)
+		}
+		if r.Action.Nat != nil {
+			// The anonymous map of the rule is created with the rule ID.
+			exprs = append(exprs, natExpr(r.Action.Nat, r.l3proto(), r.anonSetID(r.Action.Nat.Map))...)
+		}
+		if r.Action.Fwd != nil {
+			exprs = append(exprs, fwdExpr(r.Action.Fwd)...)
+		}
 		if r.Action.Verdict != nil {
 			exprs = appendExpr(exprs,
 				&nftnl.ImmediateAttrs{
@@ -204,66 +323,208 @@ func appendExpr(slice []nftnl.ExprAttrs, data ...nftnl.ExprDataAttrs) []nftnl.Ex
 	return append(slice, exprs...)
 }
 
+func (r *Rule) unmarshalMetaExprs(attrs *nftnl.RuleAttrs) {
+	for i := 0; i+1 < len(attrs.Expressions); i++ {
+		meta, ok := attrs.Expressions[i].Data.(*nftnl.MetaAttrs)
+		if !ok || meta.DReg == 0 {
+			continue
+		}
+
+		cmp, ok := attrs.Expressions[i+1].Data.(*nftnl.CmpAttrs)
+		if !ok || cmp.Data == nil || len(cmp.Data.Value) == 0 {
+			continue
+		}
+		if cmp.Op != unix.NFT_CMP_EQ && cmp.Op != unix.NFT_CMP_NEQ {
+			continue
+		}
+
+		value := cmp.Data.Value
+		negate := cmp.Op == unix.NFT_CMP_NEQ
+		switch meta.Key {
+		case unix.NFT_META_IIFNAME:
+			r.IIface = ifaceNameFromValue(value)
+			r.NegateIIface = negate
+		case unix.NFT_META_OIFNAME:
+			r.OIface = ifaceNameFromValue(value)
+			r.NegateOIface = negate
+		case unix.NFT_META_NFPROTO:
+			r.L3Proto = value[0]
+			r.NegateL3Proto = negate
+		case unix.NFT_META_L4PROTO:
+			r.L4Proto = value[0]
+			r.NegateL4Proto = negate
+		default:
+			continue
+		}
+
+		i++
+	}
+}
+
+func (r *Rule) unmarshalStatementExprs(attrs *nftnl.RuleAttrs) {
+	for i, expr := range attrs.Expressions {
+		switch e := expr.Data.(type) {
+		case *nftnl.TproxyAttrs:
+			r.Tproxy = tproxyFromExprs(attrs.Expressions, i)
+		case *nftnl.DupAttrs:
+			r.Dup = dupFromExprs(attrs.Expressions, i)
+		case *nftnl.SynproxyAttrs:
+			r.Synproxy = synproxyFromAttrs(e)
+		case *nftnl.NotrackAttrs:
+			r.Notrack = true
+		case *nftnl.ConnlimitAttrs:
+			r.Connlimit = connlimitFromAttrs(e)
+		case *nftnl.LastAttrs:
+			r.Last = lastFromAttrs(e)
+		case *nftnl.LimitAttrs:
+			r.Limit = limitFromAttrs(e)
+		case *nftnl.DynsetAttrs:
+			if u := setUpdateFromExprs(attrs.Expressions, i); u != nil {
+				r.SetUpdates = append(r.SetUpdates, u)
+			}
+		case *nftnl.ObjrefAttrs:
+			if ref := objectRefFromExprs(attrs.Expressions, i); ref != nil {
+				r.ObjectRefs = append(r.ObjectRefs, ref)
+			}
+		}
+	}
+}
+
+func (r *Rule) unmarshalActionExprs(attrs *nftnl.RuleAttrs) {
+	for i, expr := range attrs.Expressions {
+		switch e := expr.Data.(type) {
+		case *nftnl.ImmediateAttrs:
+			verdict, ok := e.Data.(*nftnl.VerdictAttrs)
+			if !ok {
+				continue
+			}
+			if r.Action == nil {
+				r.Action = &Action{}
+			}
+			r.Action.Verdict = &Verdict{
+				Code:    VerdictCode(verdict.Code),
+				Chain:   verdict.Chain,
+				ChainID: verdict.ChainID,
+			}
+		case *nftnl.RejectAttrs:
+			if r.Action == nil {
+				r.Action = &Action{}
+			}
+			r.Action.Reject = rejectFromExpr(e, r.Family, r.l3proto())
+		case *nftnl.LogAttrs:
+			if r.Action == nil {
+				r.Action = &Action{}
+			}
+			r.Action.Log = logFromExpr(e)
+		case *nftnl.QueueAttrs:
+			if r.Action == nil {
+				r.Action = &Action{}
+			}
+			r.Action.Queue = queueFromExprs(attrs.Expressions, i)
+		case *nftnl.NatAttrs:
+			if r.Action == nil {
+				r.Action = &Action{}
+			}
+			r.Action.Nat = natFromExprs(attrs.Expressions, i)
+		case *nftnl.FwdAttrs:
+			if r.Action == nil {
+				r.Action = &Action{}
+			}
+			r.Action.Fwd = fwdFromExprs(attrs.Expressions, i)
+		}
+	}
+}
+
 func (r *Rule) unmarshalPrefixExprs(attrs *nftnl.RuleAttrs) {
 	for i := 0; i < len(attrs.Expressions); i++ {
 		expr := attrs.Expressions[i]
 
-		var match *IPMatch
+		// The match is only allocated once the whole prefix match is found,
+		// as the same loads also feed other expressions.
+		var match func() *IPMatch
+		var addrLen uint32
 
 		switch e := expr.Data.(type) {
 		case *nftnl.PayloadAttrs:
-			if e.Base != unix.NFT_PAYLOAD_NETWORK_HEADER {
+			if e.Base != unix.NFT_PAYLOAD_NETWORK_HEADER || e.DReg == 0 {
 				continue
 			}
+			addrLen = e.Len
 			switch {
 			case e.Offset == 12 && e.Len == 4:
-				if r.SrcIPv4 == nil {
-					r.SrcIPv4 = &IPMatch{}
+				match = func() *IPMatch {
+					if r.SrcIPv4 == nil {
+						r.SrcIPv4 = &IPMatch{}
+					}
+					return r.SrcIPv4
 				}
-				match = r.SrcIPv4
 			case e.Offset == 16 && e.Len == 4:
-				if r.DstIPv4 == nil {
-					r.DstIPv4 = &IPMatch{}
+				match = func() *IPMatch {
+					if r.DstIPv4 == nil {
+						r.DstIPv4 = &IPMatch{}
+					}
+					return r.DstIPv4
 				}
-				match = r.DstIPv4
 			case e.Offset == 8 && e.Len == 16:
-				if r.SrcIPv6 == nil {
-					r.SrcIPv6 = &IPMatch{}
+				match = func() *IPMatch {
+					if r.SrcIPv6 == nil {
+						r.SrcIPv6 = &IPMatch{}
+					}
+					return r.SrcIPv6
 				}
-				match = r.SrcIPv6
 			case e.Offset == 24 && e.Len == 16:
-				if r.DstIPv6 == nil {
-					r.DstIPv6 = &IPMatch{}
+				match = func() *IPMatch {
+					if r.DstIPv6 == nil {
+						r.DstIPv6 = &IPMatch{}
+					}
+					return r.DstIPv6
 				}
-				match = r.DstIPv6
 			default:
 				continue
 			}
 		case *nftnl.CtAttrs:
-			if r.Ct == nil {
-				r.Ct = &CtMatch{}
+			if e.DReg == 0 {
+				continue
+			}
+			addrLen = net.IPv4len
+			if e.Key == unix.NFT_CT_SRC_IP6 || e.Key == unix.NFT_CT_DST_IP6 {
+				addrLen = net.IPv6len
+			}
+			ct := func() *CtMatch {
+				if r.Ct == nil {
+					r.Ct = &CtMatch{}
+				}
+				return r.Ct
 			}
 			switch e.Key {
 			case unix.NFT_CT_SRC_IP:
-				if r.Ct.SrcIPv4 == nil {
-					r.Ct.SrcIPv4 = &IPMatch{}
+				match = func() *IPMatch {
+					if ct().SrcIPv4 == nil {
+						r.Ct.SrcIPv4 = &IPMatch{}
+					}
+					return r.Ct.SrcIPv4
 				}
-				match = r.Ct.SrcIPv4
 			case unix.NFT_CT_DST_IP:
-				if r.Ct.DstIPv4 == nil {
-					r.Ct.DstIPv4 = &IPMatch{}
+				match = func() *IPMatch {
+					if ct().DstIPv4 == nil {
+						r.Ct.DstIPv4 = &IPMatch{}
+					}
+					return r.Ct.DstIPv4
 				}
-				match = r.Ct.DstIPv4
 			case unix.NFT_CT_SRC_IP6:
-				if r.Ct.SrcIPv6 == nil {
-					r.Ct.SrcIPv6 = &IPMatch{}
+				match = func() *IPMatch {
+					if ct().SrcIPv6 == nil {
+						r.Ct.SrcIPv6 = &IPMatch{}
+					}
+					return r.Ct.SrcIPv6
 				}
-				match = r.Ct.SrcIPv6
 			case unix.NFT_CT_DST_IP6:
-				if r.Ct.DstIPv6 == nil {
-					r.Ct.DstIPv6 = &IPMatch{}
+				match = func() *IPMatch {
+					if ct().DstIPv6 == nil {
+						r.Ct.DstIPv6 = &IPMatch{}
+					}
+					return r.Ct.DstIPv6
 				}
-				match = r.Ct.DstIPv6
 			default:
 				continue
 			}
@@ -271,8 +532,35 @@ func (r *Rule) unmarshalPrefixExprs(attrs *nftnl.RuleAttrs) {
 			continue
 		}
 
-		if match == nil {
-			continue
+		// Addresses and sets are compared right after the load.
+		if i+1 < len(attrs.Expressions) {
+			switch next := attrs.Expressions[i+1].Data.(type) {
+			case *nftnl.CmpAttrs:
+				if next.Op != unix.NFT_CMP_EQ && next.Op != unix.NFT_CMP_NEQ {
+					continue
+				}
+				if next.Data == nil || uint32(len(next.Data.Value)) != addrLen {
+					continue
+				}
+				addr, ok := netip.AddrFromSlice(next.Data.Value)
+				if !ok {
+					continue
+				}
+				m := match()
+				m.Addr = &addr
+				m.Negate = next.Op == unix.NFT_CMP_NEQ
+				i++
+				continue
+			case *nftnl.LookupAttrs:
+				if next.DReg != 0 {
+					continue
+				}
+				m := match()
+				m.Set = next.Set
+				m.Negate = next.Flags&unix.NFT_LOOKUP_F_INV != 0
+				i++
+				continue
+			}
 		}
 
 		if i+2 >= len(attrs.Expressions) {
@@ -308,7 +596,9 @@ func (r *Rule) unmarshalPrefixExprs(attrs *nftnl.RuleAttrs) {
 
 		prefix := netip.PrefixFrom(addr, prefixLen)
 
-		match.Prefix = &prefix
+		m := match()
+		m.Prefix = &prefix
+		m.Negate = cmp.Op == unix.NFT_CMP_NEQ
 
 		i += 2
 	}
@@ -0,0 +1,284 @@
+package nft
+
+import (
+	"fmt"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+type ExthdrOp uint8
+
+const (
+	// ExthdrOpIPv6 looks up an IPv6 extension header by its next header
+	// number.
+	ExthdrOpIPv6 ExthdrOp = 0x1
+	// ExthdrOpTCPOption looks up a TCP option by its kind.
+	ExthdrOpTCPOption ExthdrOp = 0x2
+)
+
+// IPv6 extension header types.
+const (
+	ExthdrTypeHopByHop uint8 = unix.IPPROTO_HOPOPTS
+	ExthdrTypeRouting  uint8 = unix.IPPROTO_ROUTING
+	ExthdrTypeFragment uint8 = unix.IPPROTO_FRAGMENT
+	ExthdrTypeDstOpts  uint8 = unix.IPPROTO_DSTOPTS
+	ExthdrTypeMobility uint8 = unix.IPPROTO_MH
+)
+
+// TCP option kinds.
+const (
+	TCPOptionEOL           uint8 = 0
+	TCPOptionNOP           uint8 = 1
+	TCPOptionMSS           uint8 = 2
+	TCPOptionWindowScale   uint8 = 3
+	TCPOptionSACKPermitted uint8 = 4
+	TCPOptionSACK          uint8 = 5
+	TCPOptionTimestamp     uint8 = 8
+)
+
+// ExthdrMatch matches an IPv6 extension header or a TCP option of the
+// packet. Value is compared in network byte order with the bytes at Offset
+// from the start of the header or option, for example offset 2 of the MSS
+// option holds the segment size. Without a Value, the match checks whether
+// the header or option is present, or missing if Missing is set.
+type ExthdrMatch struct {
+	Op      ExthdrOp
+	Type    uint8
+	Offset  uint32
+	Value   []byte
+	Missing bool
+}
+
+// ExthdrSet rewrites a field of a TCP option. The kernel only ever lowers
+// the MSS option, so setting it clamps the segment size. FromRtMSS takes the
+// value from the MSS of the route instead of Value, which clamps the MSS to
+// the path MTU.
+type ExthdrSet struct {
+	Op        ExthdrOp
+	Type      uint8
+	Offset    uint32
+	Value     []byte
+	FromRtMSS bool
+}
+
+func validateExthdrOp(op ExthdrOp, r *Rule) error {
+	switch op {
+	case ExthdrOpIPv6:
+		if r.l3proto() != unix.NFPROTO_IPV6 {
+			return fmt.Errorf("IPv6 extension headers require the L3 protocol to be IPv6")
+		}
+	case ExthdrOpTCPOption:
+		if r.l4proto() != unix.IPPROTO_TCP {
+			return fmt.Errorf("TCP options require the L4 protocol to be tcp")
+		}
+	default:
+		return fmt.Errorf("unknown exthdr op %d", op)
+	}
+	return nil
+}
+
+func (m *ExthdrMatch) validate(r *Rule) error {
+	if err := validateExthdrOp(m.Op, r); err != nil {
+		return err
+	}
+	if m.Missing && m.Value != nil {
+		return fmt.Errorf("exthdr missing cannot be combined with a value")
+	}
+	if len(m.Value) > 16 {
+		return fmt.Errorf("exthdr value must be at most 16 bytes")
+	}
+	if m.Offset > 255 {
+		return fmt.Errorf("exthdr offset must be at most 255")
+	}
+	return nil
+}
+
+func (s *ExthdrSet) validate(r *Rule) error {
+	if s.Op != ExthdrOpTCPOption {
+		return fmt.Errorf("only TCP options can be rewritten")
+	}
+	if err := validateExthdrOp(s.Op, r); err != nil {
+		return err
+	}
+	if s.FromRtMSS {
+		if s.Value != nil {
+			return fmt.Errorf("exthdr set cannot take both a value and the route MSS")
+		}
+		if s.Type != TCPOptionMSS {
+			return fmt.Errorf("the route MSS can only be written to the MSS option")
+		}
+	} else if len(s.Value) != 2 && len(s.Value) != 4 {
+		return fmt.Errorf("exthdr set value must be 2 or 4 bytes")
+	}
+	if s.Offset > 255 {
+		return fmt.Errorf("exthdr offset must be at most 255")
+	}
+	return nil
+}
+
+func exthdrOp(op ExthdrOp) uint32 {
+	if op == ExthdrOpTCPOption {
+		return unix.NFT_EXTHDR_OP_TCPOPT
+	}
+	return unix.NFT_EXTHDR_OP_IPV6
+}
+
+func exthdrMatchExpr(m *ExthdrMatch) []nftnl.ExprAttrs {
+	attrs := &nftnl.ExthdrAttrs{
+		DReg:   1,
+		Type:   m.Type,
+		Offset: m.Offset,
+		Len:    uint32(len(m.Value)),
+		Op:     exthdrOp(m.Op),
+	}
+	value := m.Value
+	if value == nil {
+		// The register holds whether the header was found.
+		attrs.Flags = unix.NFT_EXTHDR_F_PRESENT
+		attrs.Offset = 0
+		attrs.Len = 1
+		value = []byte{1}
+		if m.Missing {
+			value[0] = 0
+		}
+	}
+
+	return appendExpr(nil,
+		attrs,
+		&nftnl.CmpAttrs{
+			SReg: 1,
+			Op:   unix.NFT_CMP_EQ,
+			Data: &nftnl.DataAttrs{
+				Value: value,
+			},
+		},
+	)
+}
+
+func exthdrSetExpr(s *ExthdrSet) []nftnl.ExprAttrs {
+	attrs := &nftnl.ExthdrAttrs{
+		SReg:   1,
+		Type:   s.Type,
+		Offset: s.Offset,
+		Len:    uint32(len(s.Value)),
+		Op:     exthdrOp(s.Op),
+	}
+
+	if !s.FromRtMSS {
+		return appendExpr(nil,
+			&nftnl.ImmediateAttrs{
+				DReg: 1,
+				Data: &nftnl.DataAttrs{
+					Value: s.Value,
+				},
+			},
+			attrs,
+		)
+	}
+
+	// The route MSS is loaded in host byte order.
+	attrs.Len = 2
+	return appendExpr(nil,
+		&nftnl.RtAttrs{
+			DReg: 1,
+			Key:  unix.NFT_RT_TCPMSS,
+		},
+		&nftnl.ByteorderAttrs{
+			SReg: 1,
+			DReg: 1,
+			Op:   unix.NFT_BYTEORDER_HTON,
+			Len:  2,
+			Size: 2,
+		},
+		attrs,
+	)
+}
+
+func exthdrOpFromAttrs(attr *nftnl.ExthdrAttrs) ExthdrOp {
+	switch attr.Op {
+	case unix.NFT_EXTHDR_OP_IPV6:
+		return ExthdrOpIPv6
+	case unix.NFT_EXTHDR_OP_TCPOPT:
+		return ExthdrOpTCPOption
+	}
+	return 0
+}
+
+// unmarshalExthdrExprs decodes extension header matches and TCP option
+// writes.
+func (r *Rule) unmarshalExthdrExprs(attrs *nftnl.RuleAttrs) {
+	exprs := attrs.Expressions
+	for i := 0; i < len(exprs); i++ {
+		exthdr, ok := exprs[i].Data.(*nftnl.ExthdrAttrs)
+		if !ok {
+			continue
+		}
+		op := exthdrOpFromAttrs(exthdr)
+		if op == 0 {
+			continue
+		}
+
+		if exthdr.SReg != 0 {
+			if s := exthdrSetFromExprs(exprs[:i], exthdr); s != nil {
+				s.Op = op
+				r.ExthdrSets = append(r.ExthdrSets, s)
+			}
+			continue
+		}
+
+		if i+1 >= len(exprs) {
+			return
+		}
+		cmp, ok := exprs[i+1].Data.(*nftnl.CmpAttrs)
+		if !ok || cmp.Op != unix.NFT_CMP_EQ || cmp.Data == nil || len(cmp.Data.Value) == 0 {
+			continue
+		}
+
+		m := &ExthdrMatch{
+			Op:     op,
+			Type:   exthdr.Type,
+			Offset: exthdr.Offset,
+		}
+		if exthdr.Flags&unix.NFT_EXTHDR_F_PRESENT != 0 {
+			m.Offset = 0
+			m.Missing = cmp.Data.Value[0] == 0
+		} else {
+			m.Value = cmp.Data.Value
+		}
+		r.Exthdrs = append(r.Exthdrs, m)
+		i++
+	}
+}
+
+// exthdrSetFromExprs decodes the value of an exthdr write from the
+// expressions preceding it.
+func exthdrSetFromExprs(prev []nftnl.ExprAttrs, write *nftnl.ExthdrAttrs) *ExthdrSet {
+	if len(prev) == 0 {
+		return nil
+	}
+	s := &ExthdrSet{
+		Type:   write.Type,
+		Offset: write.Offset,
+	}
+	switch e := prev[len(prev)-1].Data.(type) {
+	case *nftnl.ImmediateAttrs:
+		data, ok := e.Data.(*nftnl.DataAttrs)
+		if !ok || e.DReg != write.SReg {
+			return nil
+		}
+		s.Value = data.Value
+	case *nftnl.ByteorderAttrs:
+		if len(prev) < 2 {
+			return nil
+		}
+		rt, ok := prev[len(prev)-2].Data.(*nftnl.RtAttrs)
+		if !ok || rt.Key != unix.NFT_RT_TCPMSS {
+			return nil
+		}
+		s.FromRtMSS = true
+	default:
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,178 @@
+package nft
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+type FibResult uint8
+
+const (
+	FibResultOif      FibResult = 0x1
+	FibResultOifName  FibResult = 0x2
+	FibResultAddrType FibResult = 0x3
+)
+
+// FibFlags select the keys of the routing lookup: exactly one of
+// FibFlagSAddr or FibFlagDAddr, optionally combined with the mark and the
+// input or output interface.
+type FibFlags uint32
+
+const (
+	FibFlagSAddr FibFlags = unix.NFTA_FIB_F_SADDR
+	FibFlagDAddr FibFlags = unix.NFTA_FIB_F_DADDR
+	FibFlagMark  FibFlags = unix.NFTA_FIB_F_MARK
+	FibFlagIIF   FibFlags = unix.NFTA_FIB_F_IIF
+	FibFlagOIF   FibFlags = unix.NFTA_FIB_F_OIF
+)
+
+type FibAddrType uint32
+
+const (
+	FibAddrTypeUnspec      FibAddrType = unix.RTN_UNSPEC
+	FibAddrTypeUnicast     FibAddrType = unix.RTN_UNICAST
+	FibAddrTypeLocal       FibAddrType = unix.RTN_LOCAL
+	FibAddrTypeBroadcast   FibAddrType = unix.RTN_BROADCAST
+	FibAddrTypeAnycast     FibAddrType = unix.RTN_ANYCAST
+	FibAddrTypeMulticast   FibAddrType = unix.RTN_MULTICAST
+	FibAddrTypeBlackhole   FibAddrType = unix.RTN_BLACKHOLE
+	FibAddrTypeUnreachable FibAddrType = unix.RTN_UNREACHABLE
+	FibAddrTypeProhibit    FibAddrType = unix.RTN_PROHIBIT
+)
+
+// FibMatch looks the packet up in the routing table and compares the
+// result. For example, reverse path filtering is a lookup of the source
+// address and input interface that matches when the output interface is
+// missing.
+type FibMatch struct {
+	Flags  FibFlags
+	Result FibResult
+	// Missing matches when the lookup finds no interface. It can only be
+	// used with FibResultOif and FibResultOifName.
+	Missing bool
+	// Oif, OifName or AddrType is compared with the result, depending on
+	// Result.
+	Oif      uint32
+	OifName  string
+	AddrType FibAddrType
+}
+
+func (f *FibMatch) validate(r *Rule) error {
+	switch r.Family {
+	case unix.NFPROTO_IPV4, unix.NFPROTO_IPV6, unix.NFPROTO_INET, unix.NFPROTO_NETDEV:
+	default:
+		return fmt.Errorf("fib is only supported in the ipv4, ipv6, inet and netdev families")
+	}
+	if f.Flags&^(FibFlagSAddr|FibFlagDAddr|FibFlagMark|FibFlagIIF|FibFlagOIF) != 0 {
+		return fmt.Errorf("invalid fib flags %#x", f.Flags)
+	}
+	addr := f.Flags & (FibFlagSAddr | FibFlagDAddr)
+	if addr != FibFlagSAddr && addr != FibFlagDAddr {
+		return fmt.Errorf("fib requires exactly one of saddr or daddr")
+	}
+	if f.Flags&FibFlagIIF != 0 && f.Flags&FibFlagOIF != 0 {
+		return fmt.Errorf("fib iif and oif cannot be combined")
+	}
+	switch f.Result {
+	case FibResultOif, FibResultOifName:
+		if f.Flags&FibFlagOIF != 0 {
+			return fmt.Errorf("fib oif cannot be used as a key when looking up the output interface")
+		}
+		if f.Missing && (f.Oif != 0 || f.OifName != "") {
+			return fmt.Errorf("fib missing cannot be combined with an interface")
+		}
+		if len(f.OifName) > unix.IFNAMSIZ-1 {
+			return fmt.Errorf("fib interface name must be at most %d characters", unix.IFNAMSIZ-1)
+		}
+	case FibResultAddrType:
+		if f.Missing {
+			return fmt.Errorf("fib missing can only be used when looking up the output interface")
+		}
+	default:
+		return fmt.Errorf("unknown fib result %d", f.Result)
+	}
+	return nil
+}
+
+func fibExpr(f *FibMatch) []nftnl.ExprAttrs {
+	attrs := &nftnl.FibAttrs{
+		DReg:  1,
+		Flags: uint32(f.Flags),
+	}
+
+	var value []byte
+	switch f.Result {
+	case FibResultOif:
+		attrs.Result = unix.NFT_FIB_RESULT_OIF
+		value = make([]byte, 4)
+		binary.NativeEndian.PutUint32(value, f.Oif)
+	case FibResultOifName:
+		attrs.Result = unix.NFT_FIB_RESULT_OIFNAME
+		value = []byte(f.OifName + "\x00")
+	case FibResultAddrType:
+		attrs.Result = unix.NFT_FIB_RESULT_ADDRTYPE
+		value = make([]byte, 4)
+		binary.NativeEndian.PutUint32(value, uint32(f.AddrType))
+	}
+	if f.Missing {
+		// The register holds whether an interface was found.
+		attrs.Flags |= unix.NFTA_FIB_F_PRESENT
+		value = []byte{0}
+	}
+
+	return appendExpr(nil,
+		attrs,
+		&nftnl.CmpAttrs{
+			SReg: 1,
+			Op:   unix.NFT_CMP_EQ,
+			Data: &nftnl.DataAttrs{
+				Value: value,
+			},
+		},
+	)
+}
+
+func (r *Rule) unmarshalFibExprs(attrs *nftnl.RuleAttrs) {
+	for i := 0; i+1 < len(attrs.Expressions); i++ {
+		fib, ok := attrs.Expressions[i].Data.(*nftnl.FibAttrs)
+		if !ok {
+			continue
+		}
+		cmp, ok := attrs.Expressions[i+1].Data.(*nftnl.CmpAttrs)
+		if !ok || cmp.Op != unix.NFT_CMP_EQ || cmp.Data == nil || len(cmp.Data.Value) == 0 {
+			continue
+		}
+
+		f := &FibMatch{Flags: FibFlags(fib.Flags &^ unix.NFTA_FIB_F_PRESENT)}
+		value := cmp.Data.Value
+		switch fib.Result {
+		case unix.NFT_FIB_RESULT_OIF:
+			f.Result = FibResultOif
+		case unix.NFT_FIB_RESULT_OIFNAME:
+			f.Result = FibResultOifName
+		case unix.NFT_FIB_RESULT_ADDRTYPE:
+			f.Result = FibResultAddrType
+		default:
+			continue
+		}
+
+		switch {
+		case fib.Flags&unix.NFTA_FIB_F_PRESENT != 0:
+			f.Missing = value[0] == 0
+		case f.Result == FibResultOifName:
+			f.OifName = strings.TrimRight(string(value), "\x00")
+		case len(value) >= 4:
+			if f.Result == FibResultOif {
+				f.Oif = binary.NativeEndian.Uint32(value)
+			} else {
+				f.AddrType = FibAddrType(binary.NativeEndian.Uint32(value))
+			}
+		}
+		r.Fib = f
+		i++
+	}
+}
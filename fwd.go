@@ -0,0 +1,76 @@
+package nft
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+// Fwd sends the packet out of the interface with index Ifindex, bypassing
+// the rest of the stack. When Addr is set the packet is sent to the
+// neighbour with that address, and packets of the other IP version are left
+// untouched. Fwd is only supported in the netdev family, and the kernel only
+// allows it in the ingress and egress hooks.
+type Fwd struct {
+	Ifindex uint32
+	Addr    *netip.Addr
+}
+
+func (f *Fwd) validate(r *Rule) error {
+	if r.Family != unix.NFPROTO_NETDEV {
+		return fmt.Errorf("fwd is only supported in the netdev family")
+	}
+	if f.Ifindex == 0 {
+		return fmt.Errorf("fwd requires an interface")
+	}
+	if f.Addr != nil && !f.Addr.IsValid() {
+		return fmt.Errorf("invalid fwd address")
+	}
+	return nil
+}
+
+func fwdExpr(f *Fwd) []nftnl.ExprAttrs {
+	attrs := &nftnl.FwdAttrs{SRegDev: 1}
+	exprs := appendExpr(nil, ifindexImmediate(attrs.SRegDev, f.Ifindex))
+
+	if f.Addr != nil {
+		attrs.SRegAddr = 2
+		attrs.NFProto = unix.NFPROTO_IPV6
+		if f.Addr.Is4() {
+			attrs.NFProto = unix.NFPROTO_IPV4
+		}
+		exprs = appendExpr(exprs,
+			&nftnl.ImmediateAttrs{
+				DReg: attrs.SRegAddr,
+				Data: &nftnl.DataAttrs{
+					Value: f.Addr.AsSlice(),
+				},
+			},
+		)
+	}
+
+	return appendExpr(exprs, attrs)
+}
+
+// fwdFromExprs decodes the fwd statement at exprs[i] from the immediates
+// loading its registers.
+func fwdFromExprs(exprs []nftnl.ExprAttrs, i int) *Fwd {
+	attr := exprs[i].Data.(*nftnl.FwdAttrs)
+	f := &Fwd{}
+	for reg, value := range immediatesBefore(exprs, i) {
+		switch reg {
+		case attr.SRegDev:
+			if len(value) >= 4 {
+				f.Ifindex = binary.NativeEndian.Uint32(value)
+			}
+		case attr.SRegAddr:
+			if addr, ok := netip.AddrFromSlice(value); ok {
+				f.Addr = &addr
+			}
+		}
+	}
+	return f
+}
@@ -0,0 +1,211 @@
+package nft
+
+import (
+	"fmt"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+type HashType uint8
+
+const (
+	// HashTypeJenkins hashes the concatenation of Fields.
+	HashTypeJenkins HashType = 0x1
+	// HashTypeSymmetric hashes the addresses and ports of the flow, giving
+	// the same result for both directions.
+	HashTypeSymmetric HashType = 0x2
+)
+
+type HashField uint8
+
+const (
+	HashFieldSrcAddr HashField = 0x1
+	HashFieldDstAddr HashField = 0x2
+	HashFieldL4Proto HashField = 0x3
+	HashFieldSrcPort HashField = 0x4
+	HashFieldDstPort HashField = 0x5
+	HashFieldMark    HashField = 0x6
+)
+
+// Hash hashes packet fields into a number in [Offset, Offset+Modulus). A
+// zero Seed makes the kernel pick a random one.
+type Hash struct {
+	Type    HashType
+	Fields  []HashField
+	Modulus uint32
+	Seed    uint32
+	Offset  uint32
+}
+
+func (h *Hash) validate(r *Rule) error {
+	if h.Modulus == 0 {
+		return fmt.Errorf("hash modulus must be greater than 0")
+	}
+	if uint64(h.Offset)+uint64(h.Modulus) > 1<<32 {
+		return fmt.Errorf("hash offset and modulus exceed 32 bits")
+	}
+	switch h.Type {
+	case HashTypeJenkins:
+		if len(h.Fields) == 0 {
+			return fmt.Errorf("jenkins hash requires at least one field")
+		}
+	case HashTypeSymmetric:
+		if len(h.Fields) > 0 || h.Seed != 0 {
+			return fmt.Errorf("symmetric hash does not take fields or a seed")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown hash type %d", h.Type)
+	}
+
+	var size uint32
+	for _, f := range h.Fields {
+		switch f {
+		case HashFieldSrcAddr, HashFieldDstAddr:
+			if r.l3proto() != unix.NFPROTO_IPV4 && r.l3proto() != unix.NFPROTO_IPV6 {
+				return fmt.Errorf("hashing addresses requires the L3 protocol to be specified")
+			}
+		case HashFieldSrcPort, HashFieldDstPort:
+			if r.l4proto() == 0 {
+				return fmt.Errorf("hashing ports requires the L4 protocol to be specified")
+			}
+		case HashFieldL4Proto, HashFieldMark:
+		default:
+			return fmt.Errorf("unknown hash field %d", f)
+		}
+		_, n := hashFieldLoad(f, r.l3proto(), 0)
+		size += (n + 3) &^ 3
+	}
+	if size > 64 {
+		return fmt.Errorf("hash fields exceed 64 bytes")
+	}
+	return nil
+}
+
+// hashFieldLoad returns the expression loading field into dreg and the
+// length of the field.
+func hashFieldLoad(field HashField, l3proto uint8, dreg uint32) (nftnl.ExprDataAttrs, uint32) {
+	switch field {
+	case HashFieldSrcAddr, HashFieldDstAddr:
+		offset, n := uint32(12), uint32(4)
+		if l3proto == unix.NFPROTO_IPV6 {
+			offset, n = 8, 16
+		}
+		if field == HashFieldDstAddr {
+			offset += n
+		}
+		return &nftnl.PayloadAttrs{
+			DReg:   dreg,
+			Base:   unix.NFT_PAYLOAD_NETWORK_HEADER,
+			Offset: offset,
+			Len:    n,
+		}, n
+	case HashFieldSrcPort, HashFieldDstPort:
+		offset := uint32(0)
+		if field == HashFieldDstPort {
+			offset = 2
+		}
+		return &nftnl.PayloadAttrs{
+			DReg:   dreg,
+			Base:   unix.NFT_PAYLOAD_TRANSPORT_HEADER,
+			Offset: offset,
+			Len:    2,
+		}, 2
+	case HashFieldL4Proto:
+		return &nftnl.MetaAttrs{DReg: dreg, Key: unix.NFT_META_L4PROTO}, 1
+	case HashFieldMark:
+		return &nftnl.MetaAttrs{DReg: dreg, Key: unix.NFT_META_MARK}, 4
+	}
+	return nil, 0
+}
+
+func hashFieldFromExpr(attr nftnl.ExprDataAttrs) (HashField, uint32) {
+	switch e := attr.(type) {
+	case *nftnl.PayloadAttrs:
+		switch {
+		case e.Base == unix.NFT_PAYLOAD_NETWORK_HEADER && e.Len == 4 && e.Offset == 12,
+			e.Base == unix.NFT_PAYLOAD_NETWORK_HEADER && e.Len == 16 && e.Offset == 8:
+			return HashFieldSrcAddr, e.DReg
+		case e.Base == unix.NFT_PAYLOAD_NETWORK_HEADER && e.Len == 4 && e.Offset == 16,
+			e.Base == unix.NFT_PAYLOAD_NETWORK_HEADER && e.Len == 16 && e.Offset == 24:
+			return HashFieldDstAddr, e.DReg
+		case e.Base == unix.NFT_PAYLOAD_TRANSPORT_HEADER && e.Len == 2 && e.Offset == 0:
+			return HashFieldSrcPort, e.DReg
+		case e.Base == unix.NFT_PAYLOAD_TRANSPORT_HEADER && e.Len == 2 && e.Offset == 2:
+			return HashFieldDstPort, e.DReg
+		}
+	case *nftnl.MetaAttrs:
+		switch e.Key {
+		case unix.NFT_META_L4PROTO:
+			return HashFieldL4Proto, e.DReg
+		case unix.NFT_META_MARK:
+			return HashFieldMark, e.DReg
+		}
+	}
+	return 0, 0
+}
+
+// marshal returns the expressions computing the hash into dreg. The fields
+// are concatenated in 32-bit registers, each padded to 4 bytes.
+func (h *Hash) marshal(l3proto uint8, dreg uint32) []nftnl.ExprAttrs {
+	attrs := &nftnl.HashAttrs{
+		DReg:    dreg,
+		Modulus: h.Modulus,
+		Seed:    h.Seed,
+		Offset:  h.Offset,
+		Type:    unix.NFT_HASH_JENKINS,
+	}
+	if h.Type == HashTypeSymmetric {
+		attrs.Type = unix.NFT_HASH_SYM
+		return appendExpr(nil, attrs)
+	}
+
+	var exprs []nftnl.ExprAttrs
+	reg := uint32(unix.NFT_REG32_00)
+	for _, f := range h.Fields {
+		load, n := hashFieldLoad(f, l3proto, reg)
+		exprs = appendExpr(exprs, load)
+		reg += (n + 3) / 4
+	}
+	attrs.SReg = unix.NFT_REG32_00
+	attrs.Len = (reg - unix.NFT_REG32_00) * 4
+	return appendExpr(exprs, attrs)
+}
+
+// hashFromExprs decodes the hash at exprs[i] and the field loads preceding
+// it.
+func hashFromExprs(exprs []nftnl.ExprAttrs, i int) *Hash {
+	attr := exprs[i].Data.(*nftnl.HashAttrs)
+	h := &Hash{
+		Type:    HashTypeJenkins,
+		Modulus: attr.Modulus,
+		Seed:    attr.Seed,
+		Offset:  attr.Offset,
+	}
+	if attr.Type == unix.NFT_HASH_SYM {
+		h.Type = HashTypeSymmetric
+		return h
+	}
+
+	start := reg32(attr.SReg)
+	end := start + attr.Len/4
+	for j := i - 1; j >= 0; j-- {
+		field, reg := hashFieldFromExpr(exprs[j].Data)
+		if field == 0 || reg32(reg) < start || reg32(reg) >= end {
+			break
+		}
+		h.Fields = append([]HashField{field}, h.Fields...)
+	}
+	return h
+}
+
+// reg32 returns the 32-bit register a register starts at. The kernel
+// reports 32-bit registers aligned to a 128-bit register by the number of
+// the 128-bit register.
+func reg32(reg uint32) uint32 {
+	if reg >= unix.NFT_REG_1 && reg <= unix.NFT_REG_4 {
+		return unix.NFT_REG32_00 + (reg-unix.NFT_REG_1)*4
+	}
+	return reg
+}
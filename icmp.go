@@ -0,0 +1,265 @@
+package nft
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+// ICMPType is the type of an ICMP message.
+// https://www.iana.org/assignments/icmp-parameters/icmp-parameters.xhtml#icmp-parameters-types
+type ICMPType uint8
+
+const (
+	ICMPTypeEchoReply           ICMPType = 0
+	ICMPTypeDestUnreachable     ICMPType = 3
+	ICMPTypeSourceQuench        ICMPType = 4
+	ICMPTypeRedirect            ICMPType = 5
+	ICMPTypeEchoRequest         ICMPType = 8
+	ICMPTypeRouterAdvertisement ICMPType = 9
+	ICMPTypeRouterSolicitation  ICMPType = 10
+	ICMPTypeTimeExceeded        ICMPType = 11
+	ICMPTypeParameterProblem    ICMPType = 12
+	ICMPTypeTimestampRequest    ICMPType = 13
+	ICMPTypeTimestampReply      ICMPType = 14
+	ICMPTypeInfoRequest         ICMPType = 15
+	ICMPTypeInfoReply           ICMPType = 16
+	ICMPTypeAddressMaskRequest  ICMPType = 17
+	ICMPTypeAddressMaskReply    ICMPType = 18
+)
+
+// ICMPv6Type is the type of an ICMPv6 message.
+// https://www.iana.org/assignments/icmpv6-parameters/icmpv6-parameters.xhtml#icmpv6-parameters-2
+type ICMPv6Type uint8
+
+const (
+	ICMPv6TypeDestUnreachable    ICMPv6Type = 1
+	ICMPv6TypePacketTooBig       ICMPv6Type = 2
+	ICMPv6TypeTimeExceeded       ICMPv6Type = 3
+	ICMPv6TypeParameterProblem   ICMPv6Type = 4
+	ICMPv6TypeEchoRequest        ICMPv6Type = 128
+	ICMPv6TypeEchoReply          ICMPv6Type = 129
+	ICMPv6TypeMLDListenerQuery   ICMPv6Type = 130
+	ICMPv6TypeMLDListenerReport  ICMPv6Type = 131
+	ICMPv6TypeMLDListenerDone    ICMPv6Type = 132
+	ICMPv6TypeNDRouterSolicit    ICMPv6Type = 133
+	ICMPv6TypeNDRouterAdvert     ICMPv6Type = 134
+	ICMPv6TypeNDNeighborSolicit  ICMPv6Type = 135
+	ICMPv6TypeNDNeighborAdvert   ICMPv6Type = 136
+	ICMPv6TypeNDRedirect         ICMPv6Type = 137
+	ICMPv6TypeRouterRenumbering  ICMPv6Type = 138
+	ICMPv6TypeMLD2ListenerReport ICMPv6Type = 143
+)
+
+// ICMPMatch matches the type and code of ICMP packets. The ICMP protocol
+// is implied, so the L4Proto of the rule may be left unset and decodes to
+// 0.
+type ICMPMatch struct {
+	// Type is matched unless Types is set.
+	Type ICMPType
+	// Types matches any of the types through an anonymous set.
+	Types []ICMPType
+	// Code, if set, is compared with the code of the message, such as
+	// ICMPCodePortUnreachable.
+	Code *uint8
+}
+
+// ICMPv6Match matches the type and code of ICMPv6 packets. The ICMPv6
+// protocol is implied, so the L4Proto of the rule may be left unset and
+// decodes to 0.
+type ICMPv6Match struct {
+	// Type is matched unless Types is set.
+	Type ICMPv6Type
+	// Types matches any of the types through an anonymous set.
+	Types []ICMPv6Type
+	// Code, if set, is compared with the code of the message, such as
+	// ICMPv6CodePortUnreachable.
+	Code *uint8
+}
+
+func (r *Rule) validateICMP() error {
+	var proto uint8
+	switch {
+	case r.ICMP != nil && r.ICMPv6 != nil:
+		return fmt.Errorf("icmp and icmpv6 cannot be matched in the same rule")
+	case r.ICMP != nil:
+		proto = unix.IPPROTO_ICMP
+	case r.ICMPv6 != nil:
+		proto = unix.IPPROTO_ICMPV6
+	default:
+		return nil
+	}
+	if r.L4Proto != 0 && r.L4Proto != proto {
+		return fmt.Errorf("icmp matching conflicts with L4 protocol %d", r.L4Proto)
+	}
+	if r.SrcPort != nil || r.DstPort != nil {
+		return fmt.Errorf("icmp cannot be combined with port matching")
+	}
+	switch l3proto := r.l3proto(); {
+	case r.ICMP != nil && l3proto == unix.NFPROTO_IPV6:
+		return fmt.Errorf("icmp requires an ipv4 rule")
+	case r.ICMPv6 != nil && l3proto == unix.NFPROTO_IPV4:
+		return fmt.Errorf("icmpv6 requires an ipv6 rule")
+	}
+	if r.Family == unix.NFPROTO_ARP {
+		return fmt.Errorf("icmp matching is not supported in the arp family")
+	}
+	return nil
+}
+
+// icmpAnonSets returns the anonymous sets of the ICMP types of a rule.
+func (r *Rule) icmpAnonSets() []anonSet {
+	switch {
+	case r.ICMP != nil && len(r.ICMP.Types) > 0:
+		set := anonSet{match: r.ICMP, keyType: nftTypeICMPType, keyLen: 1}
+		for _, t := range r.ICMP.Types {
+			set.keys = append(set.keys, []byte{uint8(t)})
+		}
+		return []anonSet{set}
+	case r.ICMPv6 != nil && len(r.ICMPv6.Types) > 0:
+		set := anonSet{match: r.ICMPv6, keyType: nftTypeICMP6Type, keyLen: 1}
+		for _, t := range r.ICMPv6.Types {
+			set.keys = append(set.keys, []byte{uint8(t)})
+		}
+		return []anonSet{set}
+	}
+	return nil
+}
+
+// icmpExpr matches the type and code of an ICMP or ICMPv6 header. setID is
+// the ID of the anonymous set of the types, or 0 to compare typ.
+func icmpExpr(typ uint8, setID uint32, code *uint8) []nftnl.ExprAttrs {
+	exprs := appendExpr(nil,
+		&nftnl.PayloadAttrs{
+			DReg:   1,
+			Base:   unix.NFT_PAYLOAD_TRANSPORT_HEADER,
+			Offset: 0,
+			Len:    1,
+		},
+	)
+	if setID != 0 {
+		exprs = appendExpr(exprs, anonSetLookup(setID, false))
+	} else {
+		exprs = appendExpr(exprs,
+			&nftnl.CmpAttrs{
+				SReg: 1,
+				Op:   unix.NFT_CMP_EQ,
+				Data: &nftnl.DataAttrs{
+					Value: []byte{typ},
+				},
+			},
+		)
+	}
+	if code != nil {
+		exprs = appendExpr(exprs,
+			&nftnl.PayloadAttrs{
+				DReg:   1,
+				Base:   unix.NFT_PAYLOAD_TRANSPORT_HEADER,
+				Offset: 1,
+				Len:    1,
+			},
+			&nftnl.CmpAttrs{
+				SReg: 1,
+				Op:   unix.NFT_CMP_EQ,
+				Data: &nftnl.DataAttrs{
+					Value: []byte{*code},
+				},
+			},
+		)
+	}
+	return exprs
+}
+
+func (r *Rule) marshalICMPExprs() []nftnl.ExprAttrs {
+	switch {
+	case r.ICMP != nil:
+		return icmpExpr(uint8(r.ICMP.Type), r.anonSetID(r.ICMP), r.ICMP.Code)
+	case r.ICMPv6 != nil:
+		return icmpExpr(uint8(r.ICMPv6.Type), r.anonSetID(r.ICMPv6), r.ICMPv6.Code)
+	}
+	return nil
+}
+
+func (r *Rule) unmarshalICMPExprs(attrs *nftnl.RuleAttrs) {
+	if r.NegateL4Proto || (r.L4Proto != unix.IPPROTO_ICMP && r.L4Proto != unix.IPPROTO_ICMPV6) {
+		return
+	}
+
+	var typ uint8
+	var code *uint8
+	var found bool
+	exprs := attrs.Expressions
+	for i := 0; i+1 < len(exprs); i++ {
+		load, ok := exprs[i].Data.(*nftnl.PayloadAttrs)
+		if !ok || load.DReg == 0 || load.Base != unix.NFT_PAYLOAD_TRANSPORT_HEADER || load.Len != 1 {
+			continue
+		}
+		switch e := exprs[i+1].Data.(type) {
+		case *nftnl.CmpAttrs:
+			if e.Op != unix.NFT_CMP_EQ || e.Data == nil || len(e.Data.Value) != 1 {
+				continue
+			}
+			switch load.Offset {
+			case 0:
+				typ = e.Data.Value[0]
+			case 1:
+				c := e.Data.Value[0]
+				code = &c
+			default:
+				continue
+			}
+		case *nftnl.LookupAttrs:
+			// The types are filled in by getICMPSetElems.
+			if load.Offset != 0 || e.DReg != 0 || e.Flags != 0 || !isAnonSet(e.Set) {
+				continue
+			}
+		default:
+			continue
+		}
+		found = true
+		i++
+	}
+	if !found {
+		return
+	}
+
+	if r.L4Proto == unix.IPPROTO_ICMP {
+		r.ICMP = &ICMPMatch{Type: ICMPType(typ), Code: code}
+	} else {
+		r.ICMPv6 = &ICMPv6Match{Type: ICMPv6Type(typ), Code: code}
+	}
+	r.L4Proto = 0
+}
+
+// getICMPSetElems fills in the types of the anonymous set looked up by the
+// ICMP match of a dumped rule, in ascending order.
+func (c *Conn) getICMPSetElems(r *Rule, attrs *nftnl.RuleAttrs) error {
+	if r.ICMP == nil && r.ICMPv6 == nil {
+		return nil
+	}
+	elems, err := c.getAnonSetElems(r, attrs, func(data nftnl.ExprDataAttrs) bool {
+		load, ok := data.(*nftnl.PayloadAttrs)
+		return ok && load.Base == unix.NFT_PAYLOAD_TRANSPORT_HEADER && load.Offset == 0 && load.Len == 1
+	})
+	if err != nil {
+		return err
+	}
+	var types []uint8
+	for _, elem := range elems {
+		if elem.Key != nil && len(elem.Key.Value) >= 1 {
+			types = append(types, elem.Key.Value[0])
+		}
+	}
+	slices.Sort(types)
+
+	for _, t := range types {
+		if r.ICMP != nil {
+			r.ICMP.Types = append(r.ICMP.Types, ICMPType(t))
+		} else {
+			r.ICMPv6.Types = append(r.ICMPv6.Types, ICMPv6Type(t))
+		}
+	}
+	return nil
+}
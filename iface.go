@@ -0,0 +1,199 @@
+package nft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+// IfaceMatch matches properties of the input (Rule.IIfaceMatch) or output
+// (Rule.OIfaceMatch) interface other than its name. All the fields that are
+// set must match.
+type IfaceMatch struct {
+	// Index is the interface index, which unlike the name stays the same
+	// when the interface is renamed. It is compared unless 0.
+	Index uint32
+	// Group is the interface group, where 0 is the default group.
+	Group *uint32
+	// Type is the ARPHRD hardware type of the interface, such as
+	// unix.ARPHRD_ETHER. It is compared unless 0.
+	Type uint16
+	// Kind is the link kind the interface was created with, such as
+	// "wireguard", "veth" or "vxlan".
+	Kind string
+}
+
+func (m *IfaceMatch) validate() error {
+	if m.Index == 0 && m.Group == nil && m.Type == 0 && m.Kind == "" {
+		return fmt.Errorf("interface match requires an index, a group, a type or a kind")
+	}
+	if len(m.Kind) >= unix.IFNAMSIZ {
+		return fmt.Errorf("interface kind %q exceeds %d characters", m.Kind, unix.IFNAMSIZ-1)
+	}
+	return nil
+}
+
+// validateIfaceName checks an IIface or OIface name. A trailing '*'
+// matches all the interfaces starting with the rest of the name.
+func validateIfaceName(name string) error {
+	prefix, wildcard := strings.CutSuffix(name, "*")
+	switch {
+	case wildcard && prefix == "":
+		return fmt.Errorf("interface wildcard %q requires a prefix", name)
+	case strings.Contains(prefix, "*"):
+		return fmt.Errorf("interface name %q may only end with a wildcard", name)
+	case len(prefix) >= unix.IFNAMSIZ:
+		return fmt.Errorf("interface name %q exceeds %d characters", name, unix.IFNAMSIZ-1)
+	}
+	return nil
+}
+
+func (r *Rule) validateIfaces() error {
+	for _, name := range []string{r.IIface, r.OIface} {
+		if name == "" {
+			continue
+		}
+		if err := validateIfaceName(name); err != nil {
+			return err
+		}
+	}
+	for _, m := range []*IfaceMatch{r.IIfaceMatch, r.OIfaceMatch} {
+		if m == nil {
+			continue
+		}
+		if err := m.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ifaceNameValue returns the value an interface name is compared with.
+// Exact names include the terminating NUL, wildcards only the prefix.
+func ifaceNameValue(name string) []byte {
+	if prefix, ok := strings.CutSuffix(name, "*"); ok {
+		return []byte(prefix)
+	}
+	return []byte(name + "\x00")
+}
+
+func ifaceNameFromValue(value []byte) string {
+	if i := bytes.IndexByte(value, 0); i >= 0 {
+		return string(value[:i])
+	}
+	return string(value) + "*"
+}
+
+// ifaceMetaKeys are the meta keys of the input and output interfaces.
+type ifaceMetaKeys struct {
+	index, group, typ, kind uint32
+}
+
+var (
+	iifMetaKeys = ifaceMetaKeys{
+		index: unix.NFT_META_IIF,
+		group: unix.NFT_META_IIFGROUP,
+		typ:   unix.NFT_META_IIFTYPE,
+		kind:  unixext.NFT_META_IIFKIND,
+	}
+	oifMetaKeys = ifaceMetaKeys{
+		index: unix.NFT_META_OIF,
+		group: unix.NFT_META_OIFGROUP,
+		typ:   unix.NFT_META_OIFTYPE,
+		kind:  unixext.NFT_META_OIFKIND,
+	}
+)
+
+func ifaceMatchExpr(m *IfaceMatch, keys ifaceMetaKeys) []nftnl.ExprAttrs {
+	var exprs []nftnl.ExprAttrs
+	add := func(key uint32, value []byte) {
+		exprs = appendExpr(exprs,
+			&nftnl.MetaAttrs{
+				DReg: 1,
+				Key:  key,
+			},
+			&nftnl.CmpAttrs{
+				SReg: 1,
+				Op:   unix.NFT_CMP_EQ,
+				Data: &nftnl.DataAttrs{
+					Value: value,
+				},
+			},
+		)
+	}
+	if m.Index != 0 {
+		value := make([]byte, 4)
+		binary.NativeEndian.PutUint32(value, m.Index)
+		add(keys.index, value)
+	}
+	if m.Group != nil {
+		value := make([]byte, 4)
+		binary.NativeEndian.PutUint32(value, *m.Group)
+		add(keys.group, value)
+	}
+	if m.Type != 0 {
+		value := make([]byte, 2)
+		binary.NativeEndian.PutUint16(value, m.Type)
+		add(keys.typ, value)
+	}
+	if m.Kind != "" {
+		add(keys.kind, []byte(m.Kind+"\x00"))
+	}
+	return exprs
+}
+
+func (r *Rule) unmarshalIfaceExprs(attrs *nftnl.RuleAttrs) {
+	match := func(in bool) *IfaceMatch {
+		if in {
+			if r.IIfaceMatch == nil {
+				r.IIfaceMatch = &IfaceMatch{}
+			}
+			return r.IIfaceMatch
+		}
+		if r.OIfaceMatch == nil {
+			r.OIfaceMatch = &IfaceMatch{}
+		}
+		return r.OIfaceMatch
+	}
+
+	for i := 0; i+1 < len(attrs.Expressions); i++ {
+		meta, ok := attrs.Expressions[i].Data.(*nftnl.MetaAttrs)
+		if !ok || meta.DReg == 0 {
+			continue
+		}
+		cmp, ok := attrs.Expressions[i+1].Data.(*nftnl.CmpAttrs)
+		if !ok || cmp.Op != unix.NFT_CMP_EQ || cmp.Data == nil || len(cmp.Data.Value) == 0 {
+			continue
+		}
+
+		value := cmp.Data.Value
+		switch meta.Key {
+		case unix.NFT_META_IIF, unix.NFT_META_OIF:
+			if len(value) != 4 {
+				continue
+			}
+			match(meta.Key == unix.NFT_META_IIF).Index = binary.NativeEndian.Uint32(value)
+		case unix.NFT_META_IIFGROUP, unix.NFT_META_OIFGROUP:
+			if len(value) != 4 {
+				continue
+			}
+			group := binary.NativeEndian.Uint32(value)
+			match(meta.Key == unix.NFT_META_IIFGROUP).Group = &group
+		case unix.NFT_META_IIFTYPE, unix.NFT_META_OIFTYPE:
+			if len(value) != 2 {
+				continue
+			}
+			match(meta.Key == unix.NFT_META_IIFTYPE).Type = binary.NativeEndian.Uint16(value)
+		case unixext.NFT_META_IIFKIND, unixext.NFT_META_OIFKIND:
+			match(meta.Key == unixext.NFT_META_IIFKIND).Kind = strings.TrimRight(string(value), "\x00")
+		default:
+			continue
+		}
+		i++
+	}
+}
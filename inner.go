@@ -0,0 +1,299 @@
+package nft
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+type InnerTunnel uint8
+
+const (
+	InnerTunnelVxlan  InnerTunnel = 0x1
+	InnerTunnelGeneve InnerTunnel = 0x2
+	InnerTunnelGre    InnerTunnel = 0x3
+	// InnerTunnelGreUDP is GRE encapsulated in UDP, as described in RFC
+	// 8086. Only GRE headers without options are supported.
+	InnerTunnelGreUDP InnerTunnel = 0x4
+)
+
+// InnerMatch matches the headers of the packet encapsulated in a tunnel.
+// The rule's L4Proto must select the outer protocol of the tunnel, UDP or
+// GRE. UDP tunnels are recognised on any port, so the rule should also
+// match the outer destination port of the tunnel.
+type InnerMatch struct {
+	Tunnel InnerTunnel
+	// VNI matches the VXLAN or Geneve network identifier.
+	VNI *uint32
+	// L3Proto is NFPROTO_IPV4 or NFPROTO_IPV6 and must be set to match on
+	// inner addresses.
+	L3Proto uint8
+	L4Proto uint8
+	SrcIP   *IPMatch
+	DstIP   *IPMatch
+}
+
+func (m *InnerMatch) validate(r *Rule) error {
+	switch m.Tunnel {
+	case InnerTunnelVxlan, InnerTunnelGeneve, InnerTunnelGreUDP:
+		if r.l4proto() != unix.IPPROTO_UDP {
+			return fmt.Errorf("inner match on a UDP tunnel requires L4 protocol UDP")
+		}
+	case InnerTunnelGre:
+		if r.l4proto() != unix.IPPROTO_GRE {
+			return fmt.Errorf("inner match on a GRE tunnel requires L4 protocol GRE")
+		}
+	default:
+		return fmt.Errorf("unknown inner tunnel %d", m.Tunnel)
+	}
+	if m.VNI != nil {
+		if m.Tunnel != InnerTunnelVxlan && m.Tunnel != InnerTunnelGeneve {
+			return fmt.Errorf("inner VNI is only supported with VXLAN and Geneve")
+		}
+		if *m.VNI > 0xffffff {
+			return fmt.Errorf("invalid VNI %d", *m.VNI)
+		}
+	}
+	switch m.L3Proto {
+	case 0, unix.NFPROTO_IPV4, unix.NFPROTO_IPV6:
+	default:
+		return fmt.Errorf("inner L3 protocol must be ipv4 or ipv6")
+	}
+	for _, ip := range []*IPMatch{m.SrcIP, m.DstIP} {
+		if ip == nil {
+			continue
+		}
+		var addr netip.Addr
+		switch {
+		case ip.Prefix != nil:
+			addr = ip.Prefix.Addr()
+		case ip.Addr != nil:
+			addr = *ip.Addr
+		default:
+			return fmt.Errorf("inner address match requires an address or prefix")
+		}
+		if m.L3Proto == 0 {
+			return fmt.Errorf("inner L3 protocol must be specified when matching on inner addresses")
+		}
+		if addr.Is4() != (m.L3Proto == unix.NFPROTO_IPV4) {
+			return fmt.Errorf("inner address %s does not match the inner L3 protocol", addr)
+		}
+	}
+	if m.VNI == nil && m.L3Proto == 0 && m.L4Proto == 0 {
+		return fmt.Errorf("inner match requires a VNI, L3 or L4 protocol")
+	}
+	return nil
+}
+
+// inner wraps a load so that it is evaluated against the encapsulated
+// headers.
+func (m *InnerMatch) inner(load nftnl.ExprDataAttrs) *nftnl.InnerAttrs {
+	attrs := &nftnl.InnerAttrs{Expr: load}
+	switch m.Tunnel {
+	case InnerTunnelVxlan:
+		attrs.Type = unixext.NFT_INNER_VXLAN
+		attrs.Flags = unixext.NFT_INNER_HDRSIZE | unixext.NFT_INNER_LL | unixext.NFT_INNER_NH | unixext.NFT_INNER_TH
+		attrs.HdrSize = 8
+	case InnerTunnelGeneve:
+		attrs.Type = unixext.NFT_INNER_GENEVE
+		attrs.Flags = unixext.NFT_INNER_HDRSIZE | unixext.NFT_INNER_LL | unixext.NFT_INNER_NH | unixext.NFT_INNER_TH
+		attrs.HdrSize = 8
+	case InnerTunnelGre:
+		// The kernel skips the GRE header, including its options, on its
+		// own.
+		attrs.Flags = unixext.NFT_INNER_NH | unixext.NFT_INNER_TH
+		attrs.HdrSize = 4
+	case InnerTunnelGreUDP:
+		attrs.Flags = unixext.NFT_INNER_HDRSIZE | unixext.NFT_INNER_NH | unixext.NFT_INNER_TH
+		attrs.HdrSize = 4
+	}
+	return attrs
+}
+
+func innerTunnelFromAttrs(attrs *nftnl.InnerAttrs) InnerTunnel {
+	switch attrs.Type {
+	case unixext.NFT_INNER_VXLAN:
+		return InnerTunnelVxlan
+	case unixext.NFT_INNER_GENEVE:
+		return InnerTunnelGeneve
+	case unixext.NFT_INNER_UNSPEC:
+		if attrs.Flags&unixext.NFT_INNER_HDRSIZE != 0 {
+			return InnerTunnelGreUDP
+		}
+		return InnerTunnelGre
+	}
+	return 0
+}
+
+// innerExprs moves the leading load of exprs inside an inner expression.
+func (m *InnerMatch) innerExprs(exprs []nftnl.ExprAttrs) []nftnl.ExprAttrs {
+	attrs := m.inner(exprs[0].Data)
+	exprs[0] = nftnl.ExprAttrs{Name: attrs.ExprName(), Data: attrs}
+	return exprs
+}
+
+func innerExpr(m *InnerMatch) []nftnl.ExprAttrs {
+	var exprs []nftnl.ExprAttrs
+
+	if m.VNI != nil {
+		exprs = append(exprs, m.innerExprs(appendExpr(nil,
+			&nftnl.PayloadAttrs{
+				DReg:   1,
+				Base:   unix.NFT_PAYLOAD_TUN_HEADER,
+				Offset: 4,
+				Len:    3,
+			},
+			&nftnl.CmpAttrs{
+				SReg: 1,
+				Op:   unix.NFT_CMP_EQ,
+				Data: &nftnl.DataAttrs{
+					Value: []byte{byte(*m.VNI >> 16), byte(*m.VNI >> 8), byte(*m.VNI)},
+				},
+			},
+		))...)
+	}
+
+	if m.L3Proto != 0 {
+		ethType := uint16(unix.ETH_P_IP)
+		if m.L3Proto == unix.NFPROTO_IPV6 {
+			ethType = unix.ETH_P_IPV6
+		}
+		value := make([]byte, 2)
+		binary.BigEndian.PutUint16(value, ethType)
+		exprs = append(exprs, m.innerExprs(appendExpr(nil,
+			&nftnl.MetaAttrs{
+				DReg: 1,
+				Key:  unix.NFT_META_PROTOCOL,
+			},
+			&nftnl.CmpAttrs{
+				SReg: 1,
+				Op:   unix.NFT_CMP_EQ,
+				Data: &nftnl.DataAttrs{
+					Value: value,
+				},
+			},
+		))...)
+	}
+
+	if m.L4Proto != 0 {
+		exprs = append(exprs, m.innerExprs(appendExpr(nil,
+			&nftnl.MetaAttrs{
+				DReg: 1,
+				Key:  unix.NFT_META_L4PROTO,
+			},
+			&nftnl.CmpAttrs{
+				SReg: 1,
+				Op:   unix.NFT_CMP_EQ,
+				Data: &nftnl.DataAttrs{
+					Value: []byte{m.L4Proto},
+				},
+			},
+		))...)
+	}
+
+	for _, ip := range []struct {
+		match *IPMatch
+		src   bool
+	}{{m.SrcIP, true}, {m.DstIP, false}} {
+		switch {
+		case ip.match == nil:
+		case ip.match.Prefix != nil:
+			exprs = append(exprs, m.innerExprs(prefixExpr(ip.match.Prefix, ip.src, ip.match.Negate))...)
+		case ip.match.Addr != nil:
+			exprs = append(exprs, m.innerExprs(addrExpr(ip.match.Addr, ip.src, ip.match.Negate))...)
+		}
+	}
+
+	return exprs
+}
+
+func (r *Rule) unmarshalInnerExprs(attrs *nftnl.RuleAttrs) {
+	exprs := attrs.Expressions
+	for i := 0; i+1 < len(exprs); i++ {
+		inner, ok := exprs[i].Data.(*nftnl.InnerAttrs)
+		if !ok || inner.Expr == nil {
+			continue
+		}
+		tunnel := innerTunnelFromAttrs(inner)
+		if tunnel == 0 {
+			continue
+		}
+
+		// A prefix is masked between the load and the comparison.
+		var bitwise *nftnl.BitwiseAttrs
+		next := i + 1
+		if b, ok := exprs[next].Data.(*nftnl.BitwiseAttrs); ok && next+1 < len(exprs) {
+			bitwise = b
+			next++
+		}
+		cmp, ok := exprs[next].Data.(*nftnl.CmpAttrs)
+		if !ok || cmp.Data == nil || len(cmp.Data.Value) == 0 {
+			continue
+		}
+		// Only the addresses may be negated.
+		negate := cmp.Op == unix.NFT_CMP_NEQ
+		if cmp.Op != unix.NFT_CMP_EQ && !negate {
+			continue
+		}
+		value := cmp.Data.Value
+
+		if r.Inner == nil {
+			r.Inner = &InnerMatch{Tunnel: tunnel}
+		}
+		m := r.Inner
+
+		switch load := inner.Expr.(type) {
+		case *nftnl.PayloadAttrs:
+			switch {
+			case load.Base == unix.NFT_PAYLOAD_TUN_HEADER && load.Offset == 4 && load.Len == 3 && len(value) == 3:
+				vni := uint32(value[0])<<16 | uint32(value[1])<<8 | uint32(value[2])
+				m.VNI = &vni
+			case load.Base == unix.NFT_PAYLOAD_NETWORK_HEADER:
+				ip := &IPMatch{Negate: negate}
+				switch {
+				case load.Offset == 12 && load.Len == 4, load.Offset == 8 && load.Len == 16:
+					m.SrcIP = ip
+				case load.Offset == 16 && load.Len == 4, load.Offset == 24 && load.Len == 16:
+					m.DstIP = ip
+				default:
+					continue
+				}
+				addr, ok := netip.AddrFromSlice(value)
+				if !ok {
+					continue
+				}
+				if bitwise != nil && bitwise.Mask != nil {
+					bits, _ := net.IPMask(bitwise.Mask.Value).Size()
+					prefix := netip.PrefixFrom(addr, bits)
+					ip.Prefix = &prefix
+				} else {
+					ip.Addr = &addr
+				}
+			default:
+				continue
+			}
+		case *nftnl.MetaAttrs:
+			switch {
+			case load.Key == unix.NFT_META_PROTOCOL && len(value) == 2:
+				switch binary.BigEndian.Uint16(value) {
+				case unix.ETH_P_IP:
+					m.L3Proto = unix.NFPROTO_IPV4
+				case unix.ETH_P_IPV6:
+					m.L3Proto = unix.NFPROTO_IPV6
+				}
+			case load.Key == unix.NFT_META_L4PROTO:
+				m.L4Proto = value[0]
+			default:
+				continue
+			}
+		default:
+			continue
+		}
+		i = next
+	}
+}
@@ -0,0 +1,33 @@
+package nft
+
+import (
+	"time"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+)
+
+// Last records when the rule, or the set element when it is attached to a
+// SetUpdate, last matched a packet.
+type Last struct {
+	// Time is when a packet last matched, or the zero time if none has.
+	// The kernel reports it with millisecond precision. Setting it when
+	// creating a rule restores a previously dumped state.
+	Time time.Time
+}
+
+func (l *Last) marshal() *nftnl.LastAttrs {
+	attrs := &nftnl.LastAttrs{}
+	if !l.Time.IsZero() {
+		attrs.Set = 1
+		attrs.Msecs = uint64(max(time.Since(l.Time).Milliseconds(), 0))
+	}
+	return attrs
+}
+
+func lastFromAttrs(attr *nftnl.LastAttrs) *Last {
+	l := &Last{}
+	if attr.Set != 0 {
+		l.Time = time.Now().Add(-time.Duration(attr.Msecs) * time.Millisecond)
+	}
+	return l
+}
@@ -0,0 +1,91 @@
+package nft
+
+import (
+	"fmt"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+type LimitType uint8
+
+const (
+	LimitTypePackets LimitType = 0x1
+	LimitTypeBytes   LimitType = 0x2
+)
+
+// LimitUnit is the period a limit's rate applies to, in seconds.
+type LimitUnit uint64
+
+const (
+	LimitUnitSecond LimitUnit = 1
+	LimitUnitMinute LimitUnit = 60
+	LimitUnitHour   LimitUnit = 60 * 60
+	LimitUnitDay    LimitUnit = 24 * 60 * 60
+	LimitUnitWeek   LimitUnit = 7 * 24 * 60 * 60
+)
+
+// The kernel uses a burst of 5 packets when none is given for packet limits.
+const limitPktBurstDefault = 5
+
+// Limit matches packets until Rate packets or bytes per Unit have been seen.
+type Limit struct {
+	// Type is what the rate counts. If unset, packets are counted.
+	Type LimitType
+	Rate uint64
+	Unit LimitUnit
+	// Burst is the number of packets or bytes allowed on top of the rate. If
+	// unset, packet limits allow a burst of 5 packets and byte limits none.
+	Burst uint32
+	// Over inverts the limit so that it only matches once the rate has been
+	// exceeded.
+	Over bool
+}
+
+func (l *Limit) validate() error {
+	if l.Type > LimitTypeBytes {
+		return fmt.Errorf("invalid limit type %d", l.Type)
+	}
+	if l.Rate == 0 {
+		return fmt.Errorf("limit rate must be specified")
+	}
+	if l.Unit == 0 {
+		return fmt.Errorf("limit unit must be specified")
+	}
+	return nil
+}
+
+func (l *Limit) marshal() *nftnl.LimitAttrs {
+	attrs := &nftnl.LimitAttrs{
+		Rate:  l.Rate,
+		Unit:  uint64(l.Unit),
+		Burst: l.Burst,
+		Type:  unix.NFT_LIMIT_PKTS,
+	}
+	if l.Type == LimitTypeBytes {
+		attrs.Type = unix.NFT_LIMIT_PKT_BYTES
+	}
+	if l.Over {
+		attrs.Flags |= unix.NFT_LIMIT_F_INV
+	}
+	return attrs
+}
+
+func limitFromAttrs(attr *nftnl.LimitAttrs) *Limit {
+	l := &Limit{
+		Rate:  attr.Rate,
+		Unit:  LimitUnit(attr.Unit),
+		Burst: attr.Burst,
+		Over:  attr.Flags&unix.NFT_LIMIT_F_INV != 0,
+	}
+	switch attr.Type {
+	case unix.NFT_LIMIT_PKTS:
+		// The kernel always reports the burst, report its default as unset.
+		if l.Burst == limitPktBurstDefault {
+			l.Burst = 0
+		}
+	case unix.NFT_LIMIT_PKT_BYTES:
+		l.Type = LimitTypeBytes
+	}
+	return l
+}
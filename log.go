@@ -0,0 +1,98 @@
+package nft
+
+import (
+	"fmt"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
+)
+
+type LogLevel uint8
+
+const (
+	LogLevelEmerg LogLevel = iota + 1
+	LogLevelAlert
+	LogLevelCrit
+	LogLevelErr
+	LogLevelWarning
+	LogLevelNotice
+	LogLevelInfo
+	LogLevelDebug
+	LogLevelAudit
+)
+
+type LogFlags uint32
+
+const (
+	LogFlagTCPSeq    LogFlags = unixext.NF_LOG_TCPSEQ
+	LogFlagTCPOpt    LogFlags = unixext.NF_LOG_TCPOPT
+	LogFlagIPOpt     LogFlags = unixext.NF_LOG_IPOPT
+	LogFlagSkUID     LogFlags = unixext.NF_LOG_UID
+	LogFlagMACDecode LogFlags = unixext.NF_LOG_MACDECODE
+	LogFlagAll       LogFlags = unixext.NF_LOG_MASK
+)
+
+// Log logs matching packets either to the kernel log or, when Group is set,
+// to an NFLOG group that can be read with the nflog package.
+type Log struct {
+	Prefix string
+	// Level and Flags only apply to the kernel log. If Level is unset, the
+	// kernel logs at LogLevelWarning.
+	Level LogLevel
+	Flags LogFlags
+	// Group, SnapLen and QueueThreshold only apply to NFLOG.
+	Group          *uint16
+	SnapLen        uint32
+	QueueThreshold uint16
+}
+
+func (l *Log) validate() error {
+	// NF_LOG_PREFIXLEN includes the terminating NUL byte.
+	if len(l.Prefix) > 127 {
+		return fmt.Errorf("log prefix must be at most 127 characters")
+	}
+	if l.Level > LogLevelAudit {
+		return fmt.Errorf("invalid log level %d", l.Level)
+	}
+	if l.Flags&^LogFlagAll != 0 {
+		return fmt.Errorf("invalid log flags %#x", l.Flags)
+	}
+	if l.Group == nil && (l.SnapLen > 0 || l.QueueThreshold > 0) {
+		return fmt.Errorf("log snaplen and queue threshold require an NFLOG group")
+	}
+	if l.Group != nil && (l.Level != 0 || l.Flags != 0) {
+		return fmt.Errorf("log level and flags cannot be used with an NFLOG group")
+	}
+	return nil
+}
+
+func logExpr(l *Log) []nftnl.ExprAttrs {
+	attrs := &nftnl.LogAttrs{
+		Group:      l.Group,
+		Prefix:     l.Prefix,
+		SnapLen:    l.SnapLen,
+		QThreshold: l.QueueThreshold,
+		Flags:      uint32(l.Flags),
+	}
+	if l.Level != 0 {
+		level := uint32(l.Level - 1)
+		attrs.Level = &level
+	}
+
+	return appendExpr(nil, attrs)
+}
+
+func logFromExpr(attr *nftnl.LogAttrs) *Log {
+	l := &Log{
+		Group:          attr.Group,
+		Prefix:         attr.Prefix,
+		SnapLen:        attr.SnapLen,
+		QueueThreshold: attr.QThreshold,
+		Flags:          LogFlags(attr.Flags),
+	}
+	// The kernel always reports the level, report its default as unset.
+	if attr.Level != nil && *attr.Level != unixext.NFT_LOGLEVEL_WARNING {
+		l.Level = LogLevel(*attr.Level + 1)
+	}
+	return l
+}
@@ -0,0 +1,333 @@
+package nft
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+// MarkMatch matches a packet or connection mark. The mark is masked with
+// Mask before being compared with Value. If Mask is unset, the whole mark is
+// compared.
+type MarkMatch struct {
+	Value uint32
+	Mask  uint32
+}
+
+// MetaField is packet or connection metadata that can be written by a
+// MetaSet.
+type MetaField uint8
+
+const (
+	MetaFieldMark     MetaField = 0x1
+	MetaFieldPriority MetaField = 0x2
+	MetaFieldNftrace  MetaField = 0x3
+	MetaFieldCtMark   MetaField = 0x4
+	MetaFieldCtZone   MetaField = 0x5
+	MetaFieldCtLabel  MetaField = 0x6
+)
+
+// MetaSet writes Value to Field or, when From is set, copies the current
+// value of From into Field, e.g. to restore the packet mark from the
+// connection mark.
+type MetaSet struct {
+	Field MetaField
+	// Value is the mark, the tc class handle for priority, 0 or 1 for
+	// nftrace, the zone ID for ct zone or the index of the label bit to set
+	// for ct label.
+	Value uint32
+	From  MetaField
+}
+
+func (s *MetaSet) validate() error {
+	switch s.Field {
+	case MetaFieldMark, MetaFieldPriority, MetaFieldCtMark:
+	case MetaFieldNftrace:
+		if s.Value > 1 {
+			return fmt.Errorf("nftrace can only be set to 0 or 1")
+		}
+	case MetaFieldCtZone:
+		if s.Value > 0xffff {
+			return fmt.Errorf("ct zone %d exceeds the maximum zone ID", s.Value)
+		}
+	case MetaFieldCtLabel:
+		if s.Value > 127 {
+			return fmt.Errorf("ct label %d exceeds the maximum label bit", s.Value)
+		}
+	default:
+		return fmt.Errorf("unknown meta field %d", s.Field)
+	}
+
+	if s.From != 0 {
+		if s.Value != 0 {
+			return fmt.Errorf("meta set value and source cannot be combined")
+		}
+		// Only 32 bit fields can be copied into each other.
+		switch s.Field {
+		case MetaFieldMark, MetaFieldPriority, MetaFieldCtMark:
+		default:
+			return fmt.Errorf("meta field %d cannot be copied into", s.Field)
+		}
+		switch s.From {
+		case MetaFieldMark, MetaFieldPriority, MetaFieldCtMark:
+		default:
+			return fmt.Errorf("meta field %d cannot be copied from", s.From)
+		}
+		if s.From == s.Field {
+			return fmt.Errorf("meta field %d cannot be copied into itself", s.Field)
+		}
+	}
+	return nil
+}
+
+// metaFieldLoad returns the expression loading field into register 1.
+func metaFieldLoad(field MetaField) nftnl.ExprDataAttrs {
+	switch field {
+	case MetaFieldMark:
+		return &nftnl.MetaAttrs{DReg: 1, Key: unix.NFT_META_MARK}
+	case MetaFieldPriority:
+		return &nftnl.MetaAttrs{DReg: 1, Key: unix.NFT_META_PRIORITY}
+	case MetaFieldNftrace:
+		return &nftnl.MetaAttrs{DReg: 1, Key: unix.NFT_META_NFTRACE}
+	case MetaFieldCtMark:
+		return &nftnl.CtAttrs{DReg: 1, Key: unix.NFT_CT_MARK}
+	case MetaFieldCtZone:
+		return &nftnl.CtAttrs{DReg: 1, Key: unix.NFT_CT_ZONE}
+	case MetaFieldCtLabel:
+		return &nftnl.CtAttrs{DReg: 1, Key: unix.NFT_CT_LABELS}
+	}
+	return nil
+}
+
+// metaFieldStore returns the expression writing register 1 to field.
+func metaFieldStore(field MetaField) nftnl.ExprDataAttrs {
+	switch field {
+	case MetaFieldMark:
+		return &nftnl.MetaAttrs{SReg: 1, Key: unix.NFT_META_MARK}
+	case MetaFieldPriority:
+		return &nftnl.MetaAttrs{SReg: 1, Key: unix.NFT_META_PRIORITY}
+	case MetaFieldNftrace:
+		return &nftnl.MetaAttrs{SReg: 1, Key: unix.NFT_META_NFTRACE}
+	case MetaFieldCtMark:
+		return &nftnl.CtAttrs{SReg: 1, Key: unix.NFT_CT_MARK}
+	case MetaFieldCtZone:
+		return &nftnl.CtAttrs{SReg: 1, Key: unix.NFT_CT_ZONE}
+	case MetaFieldCtLabel:
+		return &nftnl.CtAttrs{SReg: 1, Key: unix.NFT_CT_LABELS}
+	}
+	return nil
+}
+
+// metaFieldFromExpr returns the field loaded or stored by attr.
+func metaFieldFromExpr(attr nftnl.ExprDataAttrs) MetaField {
+	switch e := attr.(type) {
+	case *nftnl.MetaAttrs:
+		switch e.Key {
+		case unix.NFT_META_MARK:
+			return MetaFieldMark
+		case unix.NFT_META_PRIORITY:
+			return MetaFieldPriority
+		case unix.NFT_META_NFTRACE:
+			return MetaFieldNftrace
+		}
+	case *nftnl.CtAttrs:
+		switch e.Key {
+		case unix.NFT_CT_MARK:
+			return MetaFieldCtMark
+		case unix.NFT_CT_ZONE:
+			return MetaFieldCtZone
+		case unix.NFT_CT_LABELS:
+			return MetaFieldCtLabel
+		}
+	}
+	return 0
+}
+
+// metaFieldValue encodes value the way the kernel stores field in a
+// register.
+func metaFieldValue(field MetaField, value uint32) []byte {
+	switch field {
+	case MetaFieldNftrace:
+		return []byte{byte(value)}
+	case MetaFieldCtZone:
+		b := make([]byte, 2)
+		binary.NativeEndian.PutUint16(b, uint16(value))
+		return b
+	case MetaFieldCtLabel:
+		// Labels are a bitmap of unsigned longs.
+		b := make([]byte, 16)
+		b[value/8] = 1 << (value % 8)
+		return b
+	default:
+		b := make([]byte, 4)
+		binary.NativeEndian.PutUint32(b, value)
+		return b
+	}
+}
+
+func metaFieldValueFromBytes(field MetaField, b []byte) uint32 {
+	switch field {
+	case MetaFieldNftrace:
+		if len(b) < 1 {
+			return 0
+		}
+		return uint32(b[0])
+	case MetaFieldCtZone:
+		if len(b) < 2 {
+			return 0
+		}
+		return uint32(binary.NativeEndian.Uint16(b))
+	case MetaFieldCtLabel:
+		for i, v := range b {
+			for bit := 0; bit < 8; bit++ {
+				if v&(1<<bit) != 0 {
+					return uint32(i*8 + bit)
+				}
+			}
+		}
+		return 0
+	default:
+		if len(b) < 4 {
+			return 0
+		}
+		return binary.NativeEndian.Uint32(b)
+	}
+}
+
+func metaSetExpr(s *MetaSet) []nftnl.ExprAttrs {
+	if s.From != 0 {
+		return appendExpr(nil, metaFieldLoad(s.From), metaFieldStore(s.Field))
+	}
+	return appendExpr(nil,
+		&nftnl.ImmediateAttrs{
+			DReg: 1,
+			Data: &nftnl.DataAttrs{
+				Value: metaFieldValue(s.Field, s.Value),
+			},
+		},
+		metaFieldStore(s.Field),
+	)
+}
+
+func markMatchExpr(load nftnl.ExprDataAttrs, m *MarkMatch) []nftnl.ExprAttrs {
+	exprs := appendExpr(nil, load)
+
+	if m.Mask != 0 && m.Mask != 0xffffffff {
+		mask := make([]byte, 4)
+		binary.NativeEndian.PutUint32(mask, m.Mask)
+		exprs = appendExpr(exprs,
+			&nftnl.BitwiseAttrs{
+				SReg: 1,
+				DReg: 1,
+				Len:  4,
+				Mask: &nftnl.DataAttrs{
+					Value: mask,
+				},
+				Xor: &nftnl.DataAttrs{
+					Value: make([]byte, 4),
+				},
+			},
+		)
+	}
+
+	value := make([]byte, 4)
+	binary.NativeEndian.PutUint32(value, m.Value)
+	return appendExpr(exprs,
+		&nftnl.CmpAttrs{
+			SReg: 1,
+			Op:   unix.NFT_CMP_EQ,
+			Data: &nftnl.DataAttrs{
+				Value: value,
+			},
+		},
+	)
+}
+
+// unmarshalMetaSetExprs decodes mark matches and meta and ct set statements.
+func (r *Rule) unmarshalMetaSetExprs(attrs *nftnl.RuleAttrs) {
+	exprs := attrs.Expressions
+	for i := 0; i < len(exprs); i++ {
+		field := metaFieldFromExpr(exprs[i].Data)
+		if field == 0 {
+			continue
+		}
+
+		var sreg uint32
+		switch e := exprs[i].Data.(type) {
+		case *nftnl.MetaAttrs:
+			sreg = e.SReg
+		case *nftnl.CtAttrs:
+			sreg = e.SReg
+		}
+
+		if sreg == 0 {
+			if field == MetaFieldMark || field == MetaFieldCtMark {
+				if m, n := markMatchFromExprs(exprs[i+1:]); m != nil {
+					if field == MetaFieldMark {
+						r.Mark = m
+					} else {
+						if r.Ct == nil {
+							r.Ct = &CtMatch{}
+						}
+						r.Ct.Mark = m
+					}
+					i += n
+				}
+			}
+			continue
+		}
+
+		if i == 0 {
+			continue
+		}
+		s := &MetaSet{Field: field}
+		switch prev := exprs[i-1].Data.(type) {
+		case *nftnl.ImmediateAttrs:
+			data, ok := prev.Data.(*nftnl.DataAttrs)
+			if !ok || prev.DReg != sreg {
+				continue
+			}
+			s.Value = metaFieldValueFromBytes(field, data.Value)
+		case *nftnl.MetaAttrs:
+			s.From = metaFieldFromExpr(prev)
+			if prev.DReg != sreg || s.From == 0 {
+				continue
+			}
+		case *nftnl.CtAttrs:
+			s.From = metaFieldFromExpr(prev)
+			if prev.DReg != sreg || s.From == 0 {
+				continue
+			}
+		default:
+			continue
+		}
+		r.MetaSets = append(r.MetaSets, s)
+	}
+}
+
+// markMatchFromExprs decodes the optional bitwise and the cmp following a
+// mark load, returning the match and the number of expressions consumed.
+func markMatchFromExprs(exprs []nftnl.ExprAttrs) (*MarkMatch, int) {
+	m := &MarkMatch{}
+	n := 0
+	if n < len(exprs) {
+		if bw, ok := exprs[n].Data.(*nftnl.BitwiseAttrs); ok {
+			if bw.Mask == nil || len(bw.Mask.Value) < 4 {
+				return nil, 0
+			}
+			m.Mask = binary.NativeEndian.Uint32(bw.Mask.Value)
+			n++
+		}
+	}
+	if n >= len(exprs) {
+		return nil, 0
+	}
+	cmp, ok := exprs[n].Data.(*nftnl.CmpAttrs)
+	if !ok || cmp.Op != unix.NFT_CMP_EQ || cmp.Data == nil || len(cmp.Data.Value) < 4 {
+		return nil, 0
+	}
+	m.Value = binary.NativeEndian.Uint32(cmp.Data.Value)
+	return m, n + 1
+}
@@ -0,0 +1,356 @@
+package nft
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"slices"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+type NatType uint8
+
+const (
+	NatTypeSnat NatType = 0x1
+	NatTypeDnat NatType = 0x2
+)
+
+// Nat translates the source or destination of the packet to Addr and Port,
+// or to an address and port selected from Map. Either can be left unset to
+// keep the original value. The kernel only applies nat in chains of the nat
+// type.
+type Nat struct {
+	Type NatType
+	Addr *netip.Addr
+	Port uint16
+	Map  *NatMap
+}
+
+// MapKey computes the key of a map lookup. Exactly one of its fields must be
+// set.
+type MapKey struct {
+	Numgen *Numgen
+	Hash   *Hash
+}
+
+// NatMap selects the translated address and port by looking up Key in an
+// anonymous map, which is created together with the rule. Either all or
+// none of the elements have a port. Elements are reported in key order.
+type NatMap struct {
+	Key      MapKey
+	Elements []NatMapElem
+}
+
+type NatMapElem struct {
+	Key  uint32
+	Addr netip.Addr
+	Port uint16
+}
+
+// Data types of nft, stored in the set so that it lists the map with
+// readable values.
+const (
+	nftTypeInteger     = 4
+	nftTypeIPAddr      = 7
+	nftTypeIP6Addr     = 8
+	nftTypeInetProto   = 12
+	nftTypeInetService = 13
+	nftTypeICMPType    = 14
+	nftTypeICMP6Type   = 29
+	nftTypeDay         = 45
+)
+
+func (k *MapKey) validate(r *Rule) error {
+	switch {
+	case k.Numgen != nil && k.Hash == nil:
+		return k.Numgen.validate()
+	case k.Hash != nil && k.Numgen == nil:
+		return k.Hash.validate(r)
+	}
+	return fmt.Errorf("map key requires exactly one of numgen or hash")
+}
+
+func (k *MapKey) marshal(l3proto uint8, dreg uint32) []nftnl.ExprAttrs {
+	if k.Numgen != nil {
+		return appendExpr(nil, k.Numgen.marshal(dreg))
+	}
+	return k.Hash.marshal(l3proto, dreg)
+}
+
+// mapKeyFromExprs decodes the key computed at exprs[i].
+func mapKeyFromExprs(exprs []nftnl.ExprAttrs, i int) *MapKey {
+	switch e := exprs[i].Data.(type) {
+	case *nftnl.NumgenAttrs:
+		return &MapKey{Numgen: numgenFromAttrs(e)}
+	case *nftnl.HashAttrs:
+		return &MapKey{Hash: hashFromExprs(exprs, i)}
+	}
+	return nil
+}
+
+func (n *Nat) validate(r *Rule) error {
+	switch r.Family {
+	case unix.NFPROTO_IPV4, unix.NFPROTO_IPV6, unix.NFPROTO_INET:
+	default:
+		return fmt.Errorf("nat is only supported in the ipv4, ipv6 and inet families")
+	}
+	if n.Type != NatTypeSnat && n.Type != NatTypeDnat {
+		return fmt.Errorf("unknown nat type %d", n.Type)
+	}
+
+	var addrs []netip.Addr
+	hasPort := n.Port != 0
+	if n.Map != nil {
+		if n.Addr != nil || n.Port != 0 {
+			return fmt.Errorf("nat map cannot be combined with an address or port")
+		}
+		if err := n.Map.Key.validate(r); err != nil {
+			return err
+		}
+		if len(n.Map.Elements) == 0 {
+			return fmt.Errorf("nat map requires at least one element")
+		}
+		hasPort = n.Map.Elements[0].Port != 0
+		for _, e := range n.Map.Elements {
+			if (e.Port != 0) != hasPort {
+				return fmt.Errorf("either all or none of the nat map elements must have a port")
+			}
+			addrs = append(addrs, e.Addr)
+		}
+	} else {
+		if n.Addr == nil && n.Port == 0 {
+			return fmt.Errorf("nat requires an address, a port or a map")
+		}
+		if n.Addr != nil {
+			addrs = append(addrs, *n.Addr)
+		}
+	}
+
+	l3proto := r.l3proto()
+	for _, addr := range addrs {
+		if !addr.IsValid() {
+			return fmt.Errorf("invalid nat address")
+		}
+		proto := uint8(unix.NFPROTO_IPV6)
+		if addr.Is4() {
+			proto = unix.NFPROTO_IPV4
+		}
+		if l3proto == 0 {
+			l3proto = proto
+		}
+		if proto != l3proto {
+			return fmt.Errorf("nat addresses must match the address family of the rule")
+		}
+	}
+	if l3proto == 0 {
+		return fmt.Errorf("L3 protocol must be specified for inet family when translating only the port")
+	}
+	if hasPort && r.l4proto() == 0 {
+		return fmt.Errorf("translating ports requires the L4 protocol to be specified")
+	}
+	return nil
+}
+
+// family returns the address family of the translated addresses.
+func (n *Nat) family(l3proto uint8) uint8 {
+	addr := n.Addr
+	if n.Map != nil {
+		addr = &n.Map.Elements[0].Addr
+	}
+	if addr == nil {
+		return l3proto
+	}
+	if addr.Is4() {
+		return unix.NFPROTO_IPV4
+	}
+	return unix.NFPROTO_IPV6
+}
+
+func natExpr(n *Nat, l3proto uint8, setID uint32) []nftnl.ExprAttrs {
+	family := n.family(l3proto)
+	attrs := &nftnl.NatAttrs{
+		Type:   unix.NFT_NAT_SNAT,
+		Family: uint32(family),
+	}
+	if n.Type == NatTypeDnat {
+		attrs.Type = unix.NFT_NAT_DNAT
+	}
+
+	var exprs []nftnl.ExprAttrs
+	if n.Map != nil {
+		exprs = n.Map.Key.marshal(l3proto, 1)
+		exprs = appendExpr(exprs,
+			&nftnl.LookupAttrs{
+				Set:   anonMapName,
+				SetID: setID,
+				SReg:  1,
+				DReg:  1,
+			},
+		)
+		attrs.RegAddrMin = 1
+		if n.Map.Elements[0].Port != 0 {
+			// The port follows the address in the map data.
+			attrs.RegProtoMin = unix.NFT_REG32_01
+			if family == unix.NFPROTO_IPV6 {
+				attrs.RegProtoMin = unix.NFT_REG32_04
+			}
+		}
+		return appendExpr(exprs, attrs)
+	}
+
+	if n.Addr != nil {
+		attrs.RegAddrMin = 1
+		exprs = appendExpr(exprs,
+			&nftnl.ImmediateAttrs{
+				DReg: attrs.RegAddrMin,
+				Data: &nftnl.DataAttrs{
+					Value: n.Addr.AsSlice(),
+				},
+			},
+		)
+	}
+	if n.Port != 0 {
+		port := make([]byte, 2)
+		binary.BigEndian.PutUint16(port, n.Port)
+		attrs.RegProtoMin = 2
+		exprs = appendExpr(exprs,
+			&nftnl.ImmediateAttrs{
+				DReg: attrs.RegProtoMin,
+				Data: &nftnl.DataAttrs{
+					Value: port,
+				},
+			},
+		)
+	}
+	return appendExpr(exprs, attrs)
+}
+
+// natFromExprs decodes the nat at exprs[i]. The elements of a map are
+// filled in by the caller.
+func natFromExprs(exprs []nftnl.ExprAttrs, i int) *Nat {
+	attr := exprs[i].Data.(*nftnl.NatAttrs)
+	n := &Nat{Type: NatTypeSnat}
+	if attr.Type == unix.NFT_NAT_DNAT {
+		n.Type = NatTypeDnat
+	}
+
+	if i >= 2 {
+		if lookup, ok := exprs[i-1].Data.(*nftnl.LookupAttrs); ok && lookup.DReg == attr.RegAddrMin {
+			if key := mapKeyFromExprs(exprs, i-2); key != nil {
+				n.Map = &NatMap{Key: *key}
+			}
+			return n
+		}
+	}
+
+	for j := i - 1; j >= 0; j-- {
+		imm, ok := exprs[j].Data.(*nftnl.ImmediateAttrs)
+		if !ok {
+			break
+		}
+		data, ok := imm.Data.(*nftnl.DataAttrs)
+		if !ok {
+			break
+		}
+		switch imm.DReg {
+		case attr.RegAddrMin:
+			if addr, ok := netip.AddrFromSlice(data.Value); ok {
+				n.Addr = &addr
+			}
+		case attr.RegProtoMin:
+			if len(data.Value) >= 2 {
+				n.Port = binary.BigEndian.Uint16(data.Value)
+			}
+		}
+	}
+	return n
+}
+
+// natAnonSets returns the anonymous map of the nat of a rule.
+func (r *Rule) natAnonSets() []anonSet {
+	if r.Action == nil || r.Action.Nat == nil || r.Action.Nat.Map == nil {
+		return nil
+	}
+	m := r.Action.Nat.Map
+	family := r.Action.Nat.family(r.l3proto())
+
+	addrLen, dataType := uint32(4), uint32(nftTypeIPAddr)
+	if family == unix.NFPROTO_IPV6 {
+		addrLen, dataType = 16, nftTypeIP6Addr
+	}
+	dataLen := addrLen
+	if m.Elements[0].Port != 0 {
+		// Concatenated values are padded to 32 bits.
+		dataLen += 4
+		dataType = dataType<<6 | nftTypeInetService
+	}
+
+	set := anonSet{
+		match:    m,
+		keyType:  nftTypeInteger,
+		keyLen:   4,
+		dataType: dataType,
+		dataLen:  dataLen,
+	}
+	for _, e := range m.Elements {
+		key := make([]byte, 4)
+		binary.NativeEndian.PutUint32(key, e.Key)
+		data := make([]byte, dataLen)
+		copy(data, e.Addr.AsSlice())
+		if e.Port != 0 {
+			binary.BigEndian.PutUint16(data[addrLen:], e.Port)
+		}
+		set.keys = append(set.keys, key)
+		set.data = append(set.data, data)
+	}
+	return []anonSet{set}
+}
+
+// getNatMapElems fills in the elements of the anonymous map looked up by
+// the nat of a dumped rule.
+func (c *Conn) getNatMapElems(r *Rule, attrs *nftnl.RuleAttrs) error {
+	if r.Action == nil || r.Action.Nat == nil || r.Action.Nat.Map == nil {
+		return nil
+	}
+	elems, err := c.getAnonSetElems(r, attrs, func(data nftnl.ExprDataAttrs) bool {
+		switch data.(type) {
+		case *nftnl.NumgenAttrs, *nftnl.HashAttrs:
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return err
+	}
+
+	m := r.Action.Nat.Map
+	for _, elem := range elems {
+		if elem.Key == nil || elem.Data == nil || len(elem.Key.Value) < 4 {
+			continue
+		}
+		data := elem.Data.Value
+		addrLen := 4
+		if len(data) == 16 || len(data) == 20 {
+			addrLen = 16
+		}
+		addr, ok := netip.AddrFromSlice(data[:addrLen])
+		if !ok {
+			continue
+		}
+		e := NatMapElem{
+			Key:  binary.NativeEndian.Uint32(elem.Key.Value),
+			Addr: addr,
+		}
+		if len(data) >= addrLen+2 {
+			e.Port = binary.BigEndian.Uint16(data[addrLen:])
+		}
+		m.Elements = append(m.Elements, e)
+	}
+	slices.SortFunc(m.Elements, func(a, b NatMapElem) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+	return nil
+}
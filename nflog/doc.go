@@ -0,0 +1,5 @@
+/*
+Package nflog receives packets logged by nftables rules to an NFLOG group over
+netlink, without the need for a userspace logging daemon such as ulogd.
+*/
+package nflog
@@ -0,0 +1,121 @@
+package nflog
+
+import (
+	"net"
+	"time"
+
+	"github.com/mdlayher/netlink"
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+type Config struct {
+	// NetNS is the network namespace to operate in. If 0, the current
+	// network namespace is used.
+	NetNS int
+	// Group is the NFLOG group to subscribe to.
+	Group uint16
+	// CopyRange is the maximum number of bytes copied from each packet. If 0,
+	// the whole packet is copied.
+	CopyRange uint32
+}
+
+type Packet struct {
+	Prefix     string
+	Hook       uint8
+	HwProtocol uint16
+	// Interfaces are reported by index and are 0 when not applicable.
+	InIfindex      uint32
+	OutIfindex     uint32
+	PhysInIfindex  uint32
+	PhysOutIfindex uint32
+	HwAddr         net.HardwareAddr
+	Mark           uint32
+	// UID and GID are only set for packets that belong to a local socket.
+	UID       *uint32
+	GID       *uint32
+	Timestamp time.Time
+	Payload   []byte
+}
+
+type Conn struct {
+	nftnlConn *nftnl.Conn
+}
+
+// Open subscribes to the NFLOG group in config. Only one socket can be
+// subscribed to a group at a time.
+func Open(config *Config) (*Conn, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	nftnlConn, err := nftnl.Open(&nftnl.Config{NetNS: config.NetNS})
+	if err != nil {
+		return nil, err
+	}
+
+	copyRange := config.CopyRange
+	if copyRange == 0 {
+		copyRange = 0xffff
+	}
+
+	_, err = nftnlConn.Send(nftnl.Msg{
+		Header: nftnl.Header{
+			SubsysID: unix.NFNL_SUBSYS_ULOG,
+			MsgType:  unixext.NFULNL_MSG_CONFIG,
+			Flags:    netlink.Request | netlink.Acknowledge,
+		},
+		NfGenMsg: nftnl.NfGenMsg{
+			Family: unix.AF_UNSPEC,
+			ResID:  config.Group,
+		},
+		Attrs: &nftnl.NflogConfigAttrs{
+			Cmd:       unixext.NFULNL_CFG_CMD_BIND,
+			CopyMode:  unixext.NFULNL_COPY_PACKET,
+			CopyRange: copyRange,
+		},
+	})
+	if err != nil {
+		nftnlConn.Close()
+		return nil, err
+	}
+
+	return &Conn{nftnlConn: nftnlConn}, nil
+}
+
+// Receive blocks until one or more logged packets are received.
+func (c *Conn) Receive() ([]*Packet, error) {
+	msgs, err := c.nftnlConn.Receive()
+	if err != nil {
+		return nil, err
+	}
+
+	var packets []*Packet
+	for _, msg := range msgs {
+		attrs, ok := msg.Attrs.(*nftnl.NflogPacketAttrs)
+		if !ok {
+			continue
+		}
+		packets = append(packets, &Packet{
+			Prefix:         attrs.Prefix,
+			Hook:           attrs.Hook,
+			HwProtocol:     attrs.HwProtocol,
+			InIfindex:      attrs.InDev,
+			OutIfindex:     attrs.OutDev,
+			PhysInIfindex:  attrs.PhysInDev,
+			PhysOutIfindex: attrs.PhysOutDev,
+			HwAddr:         net.HardwareAddr(attrs.HwAddr),
+			Mark:           attrs.Mark,
+			UID:            attrs.UID,
+			GID:            attrs.GID,
+			Timestamp:      attrs.Timestamp,
+			Payload:        attrs.Payload,
+		})
+	}
+	return packets, nil
+}
+
+// Close closes the connection. The kernel unsubscribes it from its group.
+func (c *Conn) Close() error {
+	return c.nftnlConn.Close()
+}
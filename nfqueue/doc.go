@@ -0,0 +1,6 @@
+/*
+Package nfqueue receives packets queued by nftables rules to an NFQUEUE over
+netlink and returns a verdict for each of them, so that userspace programs
+can inspect, mark or rewrite traffic before it continues through the stack.
+*/
+package nfqueue
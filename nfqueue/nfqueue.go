@@ -0,0 +1,182 @@
+package nfqueue
+
+import (
+	"net"
+	"time"
+
+	"github.com/mdlayher/netlink"
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+type Config struct {
+	// NetNS is the network namespace to operate in. If 0, the current
+	// network namespace is used.
+	NetNS int
+	// Num is the queue number to bind to.
+	Num uint16
+	// CopyRange is the maximum number of bytes copied from each packet. If 0,
+	// the whole packet is copied.
+	CopyRange uint32
+	// MaxLen is the maximum number of packets waiting for a verdict. If 0,
+	// the kernel default is used.
+	MaxLen uint32
+	// FailOpen accepts packets instead of dropping them once the queue is
+	// full.
+	FailOpen bool
+}
+
+type VerdictCode uint32
+
+const (
+	VerdictCodeDrop   VerdictCode = unixext.NF_DROP
+	VerdictCodeAccept VerdictCode = unixext.NF_ACCEPT
+	// VerdictCodeRepeat reinjects the packet at the start of the hook it was
+	// queued from.
+	VerdictCodeRepeat VerdictCode = unixext.NF_REPEAT
+)
+
+type Packet struct {
+	// ID identifies the packet when setting its verdict.
+	ID         uint32
+	Hook       uint8
+	HwProtocol uint16
+	// Interfaces are reported by index and are 0 when not applicable.
+	InIfindex      uint32
+	OutIfindex     uint32
+	PhysInIfindex  uint32
+	PhysOutIfindex uint32
+	HwAddr         net.HardwareAddr
+	Mark           uint32
+	// UID and GID are only set for packets that belong to a local socket.
+	UID       *uint32
+	GID       *uint32
+	Timestamp time.Time
+	Payload   []byte
+}
+
+type Verdict struct {
+	PacketID uint32
+	Code     VerdictCode
+	// Mark replaces the packet mark when set.
+	Mark *uint32
+	// Payload replaces the packet contents when set. Checksums are not
+	// updated and must already be correct.
+	Payload []byte
+}
+
+type Conn struct {
+	nftnlConn *nftnl.Conn
+	num       uint16
+}
+
+// Open binds to the queue in config. Only one socket can be bound to a queue
+// at a time, and every packet received must be given a verdict.
+func Open(config *Config) (*Conn, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	nftnlConn, err := nftnl.Open(&nftnl.Config{NetNS: config.NetNS})
+	if err != nil {
+		return nil, err
+	}
+
+	copyRange := config.CopyRange
+	if copyRange == 0 {
+		copyRange = 0xffff
+	}
+
+	// The owner of queued packets is always requested so that it can be
+	// reported.
+	flags := uint32(unixext.NFQA_CFG_F_UID_GID)
+	if config.FailOpen {
+		flags |= unixext.NFQA_CFG_F_FAIL_OPEN
+	}
+
+	_, err = nftnlConn.Send(nftnl.Msg{
+		Header: nftnl.Header{
+			SubsysID: unix.NFNL_SUBSYS_QUEUE,
+			MsgType:  unixext.NFQNL_MSG_CONFIG,
+			Flags:    netlink.Request | netlink.Acknowledge,
+		},
+		NfGenMsg: nftnl.NfGenMsg{
+			Family: unix.AF_UNSPEC,
+			ResID:  config.Num,
+		},
+		Attrs: &nftnl.NfqueueConfigAttrs{
+			Cmd:       unixext.NFQNL_CFG_CMD_BIND,
+			CopyMode:  unixext.NFQNL_COPY_PACKET,
+			CopyRange: copyRange,
+			MaxLen:    config.MaxLen,
+			Flags:     flags,
+			Mask:      unixext.NFQA_CFG_F_UID_GID | unixext.NFQA_CFG_F_FAIL_OPEN,
+		},
+	})
+	if err != nil {
+		nftnlConn.Close()
+		return nil, err
+	}
+
+	return &Conn{nftnlConn: nftnlConn, num: config.Num}, nil
+}
+
+// Receive blocks until one or more queued packets are received.
+func (c *Conn) Receive() ([]*Packet, error) {
+	msgs, err := c.nftnlConn.Receive()
+	if err != nil {
+		return nil, err
+	}
+
+	var packets []*Packet
+	for _, msg := range msgs {
+		attrs, ok := msg.Attrs.(*nftnl.NfqueuePacketAttrs)
+		if !ok {
+			continue
+		}
+		packets = append(packets, &Packet{
+			ID:             attrs.PacketID,
+			Hook:           attrs.Hook,
+			HwProtocol:     attrs.HwProtocol,
+			InIfindex:      attrs.InDev,
+			OutIfindex:     attrs.OutDev,
+			PhysInIfindex:  attrs.PhysInDev,
+			PhysOutIfindex: attrs.PhysOutDev,
+			HwAddr:         net.HardwareAddr(attrs.HwAddr),
+			Mark:           attrs.Mark,
+			UID:            attrs.UID,
+			GID:            attrs.GID,
+			Timestamp:      attrs.Timestamp,
+			Payload:        attrs.Payload,
+		})
+	}
+	return packets, nil
+}
+
+// SetVerdict releases a received packet. It is safe to call concurrently
+// with Receive.
+func (c *Conn) SetVerdict(verdict *Verdict) error {
+	return c.nftnlConn.Write(nftnl.Msg{
+		Header: nftnl.Header{
+			SubsysID: unix.NFNL_SUBSYS_QUEUE,
+			MsgType:  unixext.NFQNL_MSG_VERDICT,
+			Flags:    netlink.Request,
+		},
+		NfGenMsg: nftnl.NfGenMsg{
+			Family: unix.AF_UNSPEC,
+			ResID:  c.num,
+		},
+		Attrs: &nftnl.NfqueueVerdictAttrs{
+			Verdict:  uint32(verdict.Code),
+			PacketID: verdict.PacketID,
+			Mark:     verdict.Mark,
+			Payload:  verdict.Payload,
+		},
+	})
+}
+
+// Close closes the connection. The kernel unbinds it from its queue and
+// drops the packets still waiting for a verdict.
+func (c *Conn) Close() error {
+	return c.nftnlConn.Close()
+}
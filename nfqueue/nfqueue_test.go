@@ -0,0 +1,177 @@
+package nfqueue_test
+
+import (
+	"bytes"
+	"flag"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mdlayher/netlink"
+	"github.com/nickgarlis/go-nft/nfqueue"
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+)
+
+var itests = flag.Bool("integration_tests", false, "Run tests that operate against the live kernel")
+
+func setLoopbackUp(t *testing.T) {
+	t.Helper()
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	require.NoError(t, err, "failed to open ioctl socket")
+	defer unix.Close(fd)
+
+	ifr, err := unix.NewIfreq("lo")
+	require.NoError(t, err)
+	require.NoError(t, unix.IoctlIfreq(fd, unix.SIOCGIFFLAGS, ifr), "failed to get lo flags")
+	ifr.SetUint16(ifr.Uint16() | unix.IFF_UP)
+	require.NoError(t, unix.IoctlIfreq(fd, unix.SIOCSIFFLAGS, ifr), "failed to set lo up")
+}
+
+func TestVerdict(t *testing.T) {
+	if !*itests {
+		t.SkipNow()
+	}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	ns, err := netns.New()
+	require.NoError(t, err, "failed to create network namespace")
+	defer ns.Close()
+
+	setLoopbackUp(t)
+
+	num := uint16(7)
+	conn, err := nfqueue.Open(&nfqueue.Config{
+		NetNS: int(ns),
+		Num:   num,
+	})
+	require.NoError(t, err, "failed to open nfqueue connection")
+	defer conn.Close()
+
+	nftnlConn, err := nftnl.Open(&nftnl.Config{NetNS: int(ns)})
+	require.NoError(t, err, "failed to open nftnl connection")
+	defer nftnlConn.Close()
+
+	batch := nftnl.NewBatch()
+	batch.Add(nftnl.Msg{
+		Header: nftnl.Header{
+			SubsysID: unix.NFNL_SUBSYS_NFTABLES,
+			MsgType:  unix.NFT_MSG_NEWTABLE,
+			Flags:    netlink.Request | netlink.Acknowledge | netlink.Create,
+		},
+		NfGenMsg: nftnl.NfGenMsg{Family: unix.NFPROTO_IPV4},
+		Attrs:    &nftnl.TableAttrs{Name: "test-table"},
+	})
+	batch.Add(nftnl.Msg{
+		Header: nftnl.Header{
+			SubsysID: unix.NFNL_SUBSYS_NFTABLES,
+			MsgType:  unix.NFT_MSG_NEWCHAIN,
+			Flags:    netlink.Request | netlink.Acknowledge | netlink.Create,
+		},
+		NfGenMsg: nftnl.NfGenMsg{Family: unix.NFPROTO_IPV4},
+		Attrs: &nftnl.ChainAttrs{
+			Table: "test-table",
+			Name:  "test-chain",
+			Hook: &nftnl.HookAttrs{
+				Number: unix.NF_INET_LOCAL_OUT,
+			},
+			Policy: 1, // NF_ACCEPT
+		},
+	})
+	batch.Add(nftnl.Msg{
+		Header: nftnl.Header{
+			SubsysID: unix.NFNL_SUBSYS_NFTABLES,
+			MsgType:  unix.NFT_MSG_NEWRULE,
+			Flags:    netlink.Request | netlink.Acknowledge | netlink.Create,
+		},
+		NfGenMsg: nftnl.NfGenMsg{Family: unix.NFPROTO_IPV4},
+		Attrs: &nftnl.RuleAttrs{
+			Table: "test-table",
+			Chain: "test-chain",
+			Expressions: []nftnl.ExprAttrs{
+				{
+					Name: "meta",
+					Data: &nftnl.MetaAttrs{
+						DReg: 1,
+						Key:  unix.NFT_META_L4PROTO,
+					},
+				},
+				{
+					Name: "cmp",
+					Data: &nftnl.CmpAttrs{
+						Op:   unix.NFT_CMP_EQ,
+						SReg: 1,
+						Data: &nftnl.DataAttrs{Value: []byte{unix.IPPROTO_UDP}},
+					},
+				},
+				{
+					Name: "queue",
+					Data: &nftnl.QueueAttrs{Num: num},
+				},
+			},
+		},
+	})
+	_, err = nftnlConn.SendBatch(batch)
+	require.NoError(t, err, "failed to create ruleset")
+
+	server, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err, "failed to listen")
+	defer server.Close()
+
+	udp, err := net.DialUDP("udp4", nil, server.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err, "failed to dial")
+	defer udp.Close()
+	_, err = udp.Write([]byte("hello"))
+	require.NoError(t, err, "failed to send packet")
+
+	type result struct {
+		packets []*nfqueue.Packet
+		err     error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		packets, err := conn.Receive()
+		ch <- result{packets, err}
+	}()
+
+	var res result
+	select {
+	case res = <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for queued packet")
+	}
+	require.NoError(t, res.err, "failed to receive packets")
+	require.NotEmpty(t, res.packets, "expected at least one packet")
+
+	packet := res.packets[0]
+	require.Equal(t, uint8(unix.NF_INET_LOCAL_OUT), packet.Hook)
+	require.NotNil(t, packet.UID, "expected the socket owner to be reported")
+	require.Equal(t, uint32(unix.Getuid()), *packet.UID)
+	// IPv4 and UDP headers followed by the payload.
+	require.Equal(t, []byte("hello"), packet.Payload[28:])
+
+	// Rewrite the payload in place so that the length stays the same. The
+	// kernel does not fix up checksums of modified packets, so disable the
+	// optional UDP checksum instead.
+	payload := bytes.Clone(packet.Payload)
+	copy(payload[28:], "HELLO")
+	payload[26], payload[27] = 0, 0
+	mark := uint32(42)
+	err = conn.SetVerdict(&nfqueue.Verdict{
+		PacketID: packet.ID,
+		Code:     nfqueue.VerdictCodeAccept,
+		Mark:     &mark,
+		Payload:  payload,
+	})
+	require.NoError(t, err, "failed to set verdict")
+
+	require.NoError(t, server.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 16)
+	n, err := server.Read(buf)
+	require.NoError(t, err, "failed to receive reinjected packet")
+	require.Equal(t, []byte("HELLO"), buf[:n])
+}
@@ -1,12 +1,22 @@
 package nft_test
 
 import (
+	"encoding/binary"
+	"encoding/hex"
 	"flag"
+	"net"
 	"net/netip"
+	"os"
+	"path/filepath"
 	"runtime"
+	"slices"
 	"testing"
+	"time"
 
+	"github.com/mdlayher/netlink"
 	"github.com/nickgarlis/go-nft"
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vishvananda/netns"
@@ -335,3 +345,2930 @@ func TestRule(t *testing.T) {
 	want.ID = got.ID
 	require.Equal(t, want, got, "expected retrieved rule to match created rule")
 }
+
+const (
+	testTable = "test-table"
+	testChain = "test-chain"
+)
+
+// roundTripRules adds rules to test-chain of test-table in family, or of the
+// table the rules name, and returns the rules dumped from the chain in the
+// order they were added. The table and chain are created beforehand, so
+// batch, if not nil, can hold the sets and objects the rules refer to. The
+// auto-assigned handles and IDs of rules are set to the dumped ones.
+
+func roundTripRules(t *testing.T, conn *nft.Conn, family uint8, batch *nft.Batch, rules []*nft.Rule) []*nft.Rule {
+	t.Helper()
+
+	table := testTable
+	if len(rules) > 0 && rules[0].Table != "" {
+		table = rules[0].Table
+	}
+
+	setup := nft.NewBatch()
+	err := setup.NewTable(&nft.Table{
+		Family: family,
+		Name:   table,
+	})
+	require.NoError(t, err, "failed to add NewTable to batch")
+	err = setup.NewChain(&nft.Chain{
+		Family: family,
+		Table:  table,
+		Name:   testChain,
+	})
+	require.NoError(t, err, "failed to add NewChain to batch")
+	err = conn.SendBatch(setup)
+	require.NoError(t, err, "failed to create table and chain")
+
+	if batch == nil {
+		batch = nft.NewBatch()
+	}
+	for _, rule := range rules {
+		rule.Family = family
+		rule.Table = table
+		rule.Chain = testChain
+		err = batch.NewRule(rule)
+		require.NoError(t, err, "failed to add NewRule to batch")
+	}
+	err = conn.SendBatch(batch)
+	require.NoError(t, err, "failed to create rules")
+
+	got, err := conn.GetRules(&nft.Chain{
+		Family: family,
+		Table:  table,
+		Name:   testChain,
+	})
+	require.NoError(t, err, "failed to get rules")
+	require.Len(t, got, len(rules))
+
+	// Rules are inserted at the head of the chain.
+	slices.Reverse(got)
+	for i, rule := range rules {
+		rule.Handle = got[i].Handle
+		rule.ID = got[i].ID
+	}
+	return got
+}
+
+func TestRuleReject(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	want := []*nft.Rule{
+		{
+			Action: &nft.Action{
+				Reject: &nft.Reject{},
+			},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV6,
+			Action: &nft.Action{
+				Reject: &nft.Reject{},
+			},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV4,
+			Action: &nft.Action{
+				Reject: &nft.Reject{
+					Type: nft.RejectTypeICMP,
+					Code: nft.ICMPCodeHostProhibited,
+				},
+			},
+		},
+		{
+			Action: &nft.Action{
+				Reject: &nft.Reject{
+					Type: nft.RejectTypeICMPx,
+					Code: nft.ICMPxCodeAdminProhibited,
+				},
+			},
+		},
+		{
+			L4Proto: unix.IPPROTO_TCP,
+			Action: &nft.Action{
+				Reject: &nft.Reject{
+					Type: nft.RejectTypeTCPReset,
+				},
+			},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV4,
+			Action: &nft.Action{
+				Reject: &nft.Reject{
+					Type: nft.RejectTypeICMP,
+					Code: nft.ICMPCodePortUnreachable,
+				},
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, nil, want)
+
+	// Rejects without a type are dumped with the type and code resolved for
+	// the rule.
+	want[0].Action.Reject = &nft.Reject{Type: nft.RejectTypeICMPx, Code: nft.ICMPxCodePortUnreachable}
+	want[1].Action.Reject = &nft.Reject{Type: nft.RejectTypeICMPv6, Code: nft.ICMPv6CodePortUnreachable}
+	for i := range want {
+		assert.Equal(t, want[i].Action, got[i].Action, "rule %d", i)
+	}
+}
+
+func TestRuleRejectValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	err := batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Action: &nft.Action{
+			Reject: &nft.Reject{Type: nft.RejectTypeTCPReset},
+		},
+	})
+	assert.ErrorContains(t, err, "reject with tcp reset requires L4 protocol TCP", "expected tcp reset without L4 protocol to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_IPV4,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Action: &nft.Action{
+			Reject: &nft.Reject{Type: nft.RejectTypeICMPx},
+		},
+	})
+	assert.ErrorContains(t, err, "reject with icmpx is only supported in the inet, bridge and netdev families", "expected icmpx in the ipv4 family to be rejected")
+}
+
+func TestRuleLog(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	group := uint16(5)
+	want := []*nft.Rule{
+		{
+			Action: &nft.Action{
+				Log: &nft.Log{
+					Prefix: "dropped: ",
+					Level:  nft.LogLevelInfo,
+					Flags:  nft.LogFlagTCPSeq | nft.LogFlagSkUID,
+				},
+				Verdict: &nft.Verdict{
+					Code: nft.VerdictCodeDrop,
+				},
+			},
+		},
+		{
+			Action: &nft.Action{
+				Log: &nft.Log{
+					Group:          &group,
+					SnapLen:        128,
+					QueueThreshold: 10,
+				},
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_IPV4, nil, want)
+
+	for i := range want {
+		assert.Equal(t, want[i].Action, got[i].Action, "rule %d", i)
+	}
+}
+
+func TestRuleLimit(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	batch := nft.NewBatch()
+	obj := &nft.Object{
+		Family: unix.NFPROTO_INET,
+		Table:  testTable,
+		Name:   "test-limit",
+		Limit: &nft.Limit{
+			Rate:  100,
+			Unit:  nft.LimitUnitMinute,
+			Burst: 20,
+		},
+	}
+	err := batch.NewObject(obj)
+	require.NoError(t, err, "failed to add NewObject to batch")
+
+	want := []*nft.Rule{
+		{
+			Limit: &nft.Limit{
+				Rate: 10,
+				Unit: nft.LimitUnitSecond,
+			},
+		},
+		{
+			Limit: &nft.Limit{
+				Type:  nft.LimitTypeBytes,
+				Rate:  1024 * 1024,
+				Unit:  nft.LimitUnitHour,
+				Burst: 512 * 1024,
+				Over:  true,
+			},
+			Action: &nft.Action{
+				Verdict: &nft.Verdict{
+					Code: nft.VerdictCodeDrop,
+				},
+			},
+		},
+		{
+			ObjectRefs: []*nft.ObjectRef{
+				{Type: nft.ObjectTypeLimit, Name: "test-limit"},
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, batch, want)
+
+	for i := range want {
+		assert.Equal(t, want[i].Limit, got[i].Limit, "rule %d", i)
+		assert.Equal(t, want[i].ObjectRefs, got[i].ObjectRefs, "rule %d", i)
+		assert.Equal(t, want[i].Action, got[i].Action, "rule %d", i)
+	}
+
+	gotObj, err := conn.GetObject(&nft.Object{
+		Family: unix.NFPROTO_INET,
+		Table:  testTable,
+		Name:   "test-limit",
+	})
+	require.NoError(t, err, "failed to get object")
+	assert.Equal(t, nft.ObjectTypeLimit, gotObj.Type)
+	assert.Equal(t, obj.Limit, gotObj.Limit)
+
+	batch = nft.NewBatch()
+	err = batch.DelObject(&nft.Object{
+		Family: unix.NFPROTO_INET,
+		Table:  testTable,
+		Name:   "test-limit",
+		Type:   nft.ObjectTypeLimit,
+	})
+	require.NoError(t, err, "failed to add DelObject to batch")
+	err = conn.SendBatch(batch)
+	assert.ErrorIs(t, err, unix.EBUSY, "expected deleting a referenced object to fail")
+}
+
+func TestRuleQueue(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	want := []*nft.Rule{
+		{
+			Action: &nft.Action{
+				Queue: &nft.Queue{Num: 3},
+			},
+		},
+		{
+			Action: &nft.Action{
+				Queue: &nft.Queue{
+					Num:    4,
+					Total:  4,
+					Bypass: true,
+					Fanout: true,
+				},
+			},
+		},
+		{
+			Action: &nft.Action{
+				Queue: &nft.Queue{
+					NumFrom: &nft.QueueNumSource{Mark: true},
+				},
+			},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV4,
+			Action: &nft.Action{
+				Queue: &nft.Queue{
+					Bypass: true,
+					NumFrom: &nft.QueueNumSource{
+						Hash: &nft.Hash{
+							Type:    nft.HashTypeJenkins,
+							Fields:  []nft.HashField{nft.HashFieldSrcAddr},
+							Modulus: 4,
+							Seed:    1,
+							Offset:  10,
+						},
+					},
+				},
+			},
+		},
+		{
+			Action: &nft.Action{
+				Queue: &nft.Queue{
+					NumFrom: &nft.QueueNumSource{
+						Numgen: &nft.Numgen{Type: nft.NumgenTypeInc, Modulus: 2, Offset: 20},
+					},
+				},
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, nil, want)
+
+	for i := range want {
+		assert.Equal(t, want[i].Action, got[i].Action, "rule %d", i)
+	}
+}
+
+func TestRuleQueueValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	err := batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Action: &nft.Action{
+			Queue: &nft.Queue{Num: 65535, Total: 2},
+		},
+	})
+	assert.ErrorContains(t, err, "queue range 65535-65536 exceeds the maximum queue number", "expected a queue range past 65535 to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Action: &nft.Action{
+			Queue:   &nft.Queue{Num: 1},
+			Verdict: &nft.Verdict{Code: nft.VerdictCodeAccept},
+		},
+	})
+	assert.ErrorContains(t, err, "queue cannot be combined with a verdict or reject in the same rule", "expected queue combined with a verdict to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Action: &nft.Action{
+			Queue: &nft.Queue{
+				Num:     1,
+				NumFrom: &nft.QueueNumSource{Mark: true},
+			},
+		},
+	})
+	assert.ErrorContains(t, err, "queue number and total cannot be used when the queue number is taken from the packet", "expected a queue number with a number source to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Action: &nft.Action{
+			Queue: &nft.Queue{
+				NumFrom: &nft.QueueNumSource{
+					Mark:   true,
+					Numgen: &nft.Numgen{Type: nft.NumgenTypeInc, Modulus: 2},
+				},
+			},
+		},
+	})
+	assert.ErrorContains(t, err, "queue number source requires exactly one of mark, numgen or hash", "expected a queue number from both the mark and numgen to be rejected")
+}
+
+func TestRuleMeta(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	want := []*nft.Rule{
+		{
+			Mark: &nft.MarkMatch{Value: 0x1},
+			MetaSets: []*nft.MetaSet{
+				{Field: nft.MetaFieldCtMark, From: nft.MetaFieldMark},
+			},
+		},
+		{
+			Ct: &nft.CtMatch{
+				Mark: &nft.MarkMatch{Value: 0x100, Mask: 0xff00},
+			},
+			MetaSets: []*nft.MetaSet{
+				{Field: nft.MetaFieldMark, From: nft.MetaFieldCtMark},
+				{Field: nft.MetaFieldPriority, Value: 0x10002},
+			},
+		},
+		{
+			MetaSets: []*nft.MetaSet{
+				{Field: nft.MetaFieldMark, Value: 0x2a},
+				{Field: nft.MetaFieldCtMark, Value: 0x2a},
+				{Field: nft.MetaFieldCtLabel, Value: 65},
+				{Field: nft.MetaFieldCtZone, Value: 5},
+				{Field: nft.MetaFieldNftrace, Value: 1},
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, nil, want)
+
+	for i := range want {
+		assert.Equal(t, want[i].Mark, got[i].Mark, "rule %d", i)
+		assert.Equal(t, want[i].Ct, got[i].Ct, "rule %d", i)
+		assert.Equal(t, want[i].MetaSets, got[i].MetaSets, "rule %d", i)
+	}
+}
+
+func TestRuleMetaValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	err := batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		MetaSets: []*nft.MetaSet{
+			{Field: nft.MetaFieldCtZone, From: nft.MetaFieldMark},
+		},
+	})
+	assert.ErrorContains(t, err, "meta field 5 cannot be copied into", "expected copying into ct zone to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		MetaSets: []*nft.MetaSet{
+			{Field: nft.MetaFieldCtLabel, Value: 128},
+		},
+	})
+	assert.ErrorContains(t, err, "ct label 128 exceeds the maximum label bit", "expected an out of range ct label to be rejected")
+}
+
+func TestRulePayloadSet(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	want := []*nft.Rule{
+		{
+			L3Proto: unix.NFPROTO_IPV4,
+			PayloadSets: []*nft.PayloadSet{
+				{Field: nft.PayloadFieldDSCP, Value: 46},
+				{Field: nft.PayloadFieldECN, Value: 1},
+				{Field: nft.PayloadFieldTTL, Value: 64},
+			},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV6,
+			PayloadSets: []*nft.PayloadSet{
+				{Field: nft.PayloadFieldDSCP, Value: 10},
+				{Field: nft.PayloadFieldECN, Value: 2},
+				{Field: nft.PayloadFieldHopLimit, Value: 255},
+			},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV4,
+			L4Proto: unix.IPPROTO_TCP,
+			PayloadSets: []*nft.PayloadSet{
+				{Field: nft.PayloadFieldSrcPort, Value: 1024},
+				{Field: nft.PayloadFieldDstPort, Value: 8080},
+			},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV4,
+			L4Proto: unix.IPPROTO_UDP,
+			PayloadSets: []*nft.PayloadSet{
+				{
+					Field:  nft.PayloadFieldRaw,
+					Base:   nft.PayloadBaseNetwork,
+					Offset: 16,
+					Data:   []byte{10, 0, 0, 1},
+				},
+				{
+					Field:  nft.PayloadFieldRaw,
+					Base:   nft.PayloadBaseNetwork,
+					Offset: 1,
+					Data:   []byte{0x10},
+				},
+				{
+					Field:  nft.PayloadFieldRaw,
+					Base:   nft.PayloadBaseTransport,
+					Offset: 8,
+					Data:   []byte{0xde, 0xad},
+				},
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, nil, want)
+
+	for i := range want {
+		assert.Equal(t, want[i].PayloadSets, got[i].PayloadSets, "rule %d", i)
+		assert.Nil(t, got[i].DstIPv4, "rule %d", i)
+	}
+}
+
+func TestRulePayloadSetValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	err := batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		PayloadSets: []*nft.PayloadSet{
+			{Field: nft.PayloadFieldTTL, Value: 64},
+		},
+	})
+	assert.ErrorContains(t, err, "setting ttl requires the ipv4 family or L3 protocol IPv4", "expected setting ttl without L3 protocol to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_IPV4,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		PayloadSets: []*nft.PayloadSet{
+			{Field: nft.PayloadFieldDstPort, Value: 80},
+		},
+	})
+	assert.ErrorContains(t, err, "setting ports requires L4 protocol TCP, UDP, UDP-Lite or SCTP", "expected setting ports without L4 protocol to be rejected")
+}
+
+func TestRuleFib(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	want := []*nft.Rule{
+		{
+			Fib: &nft.FibMatch{
+				Flags:   nft.FibFlagSAddr | nft.FibFlagIIF,
+				Result:  nft.FibResultOif,
+				Missing: true,
+			},
+			Action: &nft.Action{Verdict: &nft.Verdict{Code: nft.VerdictCodeDrop}},
+		},
+		{
+			Fib: &nft.FibMatch{
+				Flags:    nft.FibFlagDAddr,
+				Result:   nft.FibResultAddrType,
+				AddrType: nft.FibAddrTypeLocal,
+			},
+		},
+		{
+			Fib: &nft.FibMatch{
+				Flags:   nft.FibFlagDAddr | nft.FibFlagMark,
+				Result:  nft.FibResultOifName,
+				OifName: "lo",
+			},
+		},
+		{
+			Fib: &nft.FibMatch{
+				Flags:  nft.FibFlagSAddr | nft.FibFlagIIF,
+				Result: nft.FibResultOif,
+				Oif:    1,
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, nil, want)
+
+	for i := range want {
+		assert.Equal(t, want[i].Fib, got[i].Fib, "rule %d", i)
+	}
+}
+
+func TestRuleFibValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	err := batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Fib: &nft.FibMatch{
+			Flags:  nft.FibFlagSAddr | nft.FibFlagDAddr,
+			Result: nft.FibResultAddrType,
+		},
+	})
+	assert.ErrorContains(t, err, "fib requires exactly one of saddr or daddr", "expected looking up both saddr and daddr to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Fib: &nft.FibMatch{
+			Flags:  nft.FibFlagDAddr | nft.FibFlagOIF,
+			Result: nft.FibResultOif,
+		},
+	})
+	assert.ErrorContains(t, err, "fib oif cannot be used as a key when looking up the output interface", "expected an oif key with an oif result to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_BRIDGE,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Fib: &nft.FibMatch{
+			Flags:  nft.FibFlagDAddr,
+			Result: nft.FibResultAddrType,
+		},
+	})
+	assert.ErrorContains(t, err, "fib is only supported in the ipv4, ipv6, inet and netdev families", "expected fib in the bridge family to be rejected")
+}
+
+func TestRuleRt(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	nexthop4 := netip.MustParseAddr("192.0.2.1")
+	nexthop6 := netip.MustParseAddr("2001:db8::1")
+
+	want := []*nft.Rule{
+		{
+			Rt: &nft.RtMatch{Key: nft.RtKeyClassID, ClassID: 0x10001},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV4,
+			Rt:      &nft.RtMatch{Key: nft.RtKeyNexthop, Nexthop: &nexthop4},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV6,
+			Rt:      &nft.RtMatch{Key: nft.RtKeyNexthop, Nexthop: &nexthop6},
+		},
+		{
+			Rt: &nft.RtMatch{Key: nft.RtKeyTCPMSS, TCPMSS: 1460},
+		},
+		{
+			Rt: &nft.RtMatch{Key: nft.RtKeyIPsec, IPsec: true},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, nil, want)
+
+	for i := range want {
+		assert.Equal(t, want[i].Rt, got[i].Rt, "rule %d", i)
+	}
+}
+
+func TestRuleSocket(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	want := []*nft.Rule{
+		{
+			Socket: &nft.SocketMatch{Key: nft.SocketKeyTransparent, Transparent: true},
+		},
+		{
+			Socket: &nft.SocketMatch{
+				Key:  nft.SocketKeyMark,
+				Mark: &nft.MarkMatch{Value: 0x1, Mask: 0xff},
+			},
+		},
+		{
+			Socket: &nft.SocketMatch{Key: nft.SocketKeyWildcard},
+		},
+		{
+			Socket: &nft.SocketMatch{Key: nft.SocketKeyCgroupV2, Level: 1, CgroupID: 4242},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, nil, want)
+
+	for i := range want {
+		assert.Equal(t, want[i].Socket, got[i].Socket, "rule %d", i)
+	}
+}
+
+func TestRuleTproxy(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	addr4 := netip.MustParseAddr("127.0.0.1")
+	addr6 := netip.MustParseAddr("::1")
+
+	want := []*nft.Rule{
+		{
+			L3Proto: unix.NFPROTO_IPV4,
+			L4Proto: unix.IPPROTO_TCP,
+			Tproxy:  &nft.Tproxy{Addr: &addr4, Port: 8080},
+			MetaSets: []*nft.MetaSet{
+				{Field: nft.MetaFieldMark, Value: 1},
+			},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV6,
+			L4Proto: unix.IPPROTO_UDP,
+			Tproxy:  &nft.Tproxy{Addr: &addr6},
+		},
+		{
+			L4Proto: unix.IPPROTO_TCP,
+			Tproxy:  &nft.Tproxy{Port: 3128},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, nil, want)
+
+	for i := range want {
+		assert.Equal(t, want[i].Tproxy, got[i].Tproxy, "rule %d", i)
+		assert.Equal(t, want[i].MetaSets, got[i].MetaSets, "rule %d", i)
+	}
+}
+
+func TestRuleTproxyHook(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	batch := nft.NewBatch()
+	err := batch.NewTable(&nft.Table{
+		Family: unix.NFPROTO_INET,
+		Name:   testTable,
+	})
+	require.NoError(t, err, "failed to add NewTable to batch")
+	batch.Add(nftnl.Msg{
+		Header: nftnl.Header{
+			SubsysID: unix.NFNL_SUBSYS_NFTABLES,
+			MsgType:  unix.NFT_MSG_NEWCHAIN,
+			Flags:    netlink.Request | netlink.Acknowledge | netlink.Create,
+		},
+		NfGenMsg: nftnl.NfGenMsg{Family: unix.NFPROTO_INET},
+		Attrs: &nftnl.ChainAttrs{
+			Table: testTable,
+			Name:  "input",
+			Type:  "filter",
+			Hook:  &nftnl.HookAttrs{Number: unix.NF_INET_LOCAL_IN},
+		},
+	})
+	err = batch.NewRule(&nft.Rule{
+		Family:  unix.NFPROTO_INET,
+		Table:   testTable,
+		Chain:   "input",
+		L4Proto: unix.IPPROTO_TCP,
+		Tproxy:  &nft.Tproxy{Port: 8080},
+	})
+	require.NoError(t, err, "failed to add NewRule to batch")
+
+	err = conn.SendBatch(batch)
+	assert.ErrorIs(t, err, unix.EOPNOTSUPP, "expected tproxy in an input chain to be rejected by the kernel")
+}
+
+func TestRuleTproxyValidation(t *testing.T) {
+	batch := nft.NewBatch()
+	addr := netip.MustParseAddr("127.0.0.1")
+
+	err := batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Tproxy: &nft.Tproxy{Port: 8080},
+	})
+	assert.ErrorContains(t, err, "tproxy requires the L4 protocol to be tcp or udp", "expected tproxy without L4 protocol to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family:  unix.NFPROTO_IPV6,
+		Table:   "test-table",
+		Chain:   "test-chain",
+		L4Proto: unix.IPPROTO_TCP,
+		Tproxy:  &nft.Tproxy{Addr: &addr},
+	})
+	assert.ErrorContains(t, err, "tproxy address family does not match the rule", "expected an IPv4 tproxy address in the ipv6 family to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_NETDEV,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Socket: &nft.SocketMatch{Key: nft.SocketKeyTransparent, Transparent: true},
+	})
+	assert.ErrorContains(t, err, "socket is only supported in the ipv4, ipv6 and inet families", "expected socket in the netdev family to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_BRIDGE,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Rt:     &nft.RtMatch{Key: nft.RtKeyTCPMSS, TCPMSS: 1460},
+	})
+	assert.ErrorContains(t, err, "rt is only supported in the ipv4, ipv6 and inet families", "expected rt in the bridge family to be rejected")
+}
+
+func TestRuleExthdr(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	want := []*nft.Rule{
+		{
+			L3Proto: unix.NFPROTO_IPV6,
+			Exthdrs: []*nft.ExthdrMatch{
+				{Op: nft.ExthdrOpIPv6, Type: nft.ExthdrTypeFragment},
+				{Op: nft.ExthdrOpIPv6, Type: nft.ExthdrTypeHopByHop, Missing: true},
+				// Routing header type 0.
+				{Op: nft.ExthdrOpIPv6, Type: nft.ExthdrTypeRouting, Offset: 2, Value: []byte{0}},
+			},
+		},
+		{
+			L4Proto: unix.IPPROTO_TCP,
+			Exthdrs: []*nft.ExthdrMatch{
+				{Op: nft.ExthdrOpTCPOption, Type: nft.TCPOptionSACKPermitted},
+				{Op: nft.ExthdrOpTCPOption, Type: nft.TCPOptionTimestamp, Missing: true},
+				{Op: nft.ExthdrOpTCPOption, Type: nft.TCPOptionWindowScale, Offset: 2, Value: []byte{7}},
+				{Op: nft.ExthdrOpTCPOption, Type: nft.TCPOptionMSS, Offset: 2, Value: []byte{0x05, 0xb4}},
+			},
+		},
+		{
+			L4Proto: unix.IPPROTO_TCP,
+			ExthdrSets: []*nft.ExthdrSet{
+				{Op: nft.ExthdrOpTCPOption, Type: nft.TCPOptionMSS, Offset: 2, Value: []byte{0x05, 0x78}},
+			},
+		},
+		{
+			L4Proto: unix.IPPROTO_TCP,
+			ExthdrSets: []*nft.ExthdrSet{
+				{Op: nft.ExthdrOpTCPOption, Type: nft.TCPOptionMSS, Offset: 2, FromRtMSS: true},
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, nil, want)
+
+	for i := range want {
+		assert.Equal(t, want[i].Exthdrs, got[i].Exthdrs, "rule %d", i)
+		assert.Equal(t, want[i].ExthdrSets, got[i].ExthdrSets, "rule %d", i)
+		assert.Nil(t, got[i].Rt, "rule %d", i)
+	}
+}
+
+func TestRuleExthdrValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	err := batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Exthdrs: []*nft.ExthdrMatch{
+			{Op: nft.ExthdrOpIPv6, Type: nft.ExthdrTypeFragment},
+		},
+	})
+	assert.ErrorContains(t, err, "IPv6 extension headers require the L3 protocol to be IPv6", "expected IPv6 extension headers without L3 protocol to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family:  unix.NFPROTO_IPV4,
+		Table:   "test-table",
+		Chain:   "test-chain",
+		L4Proto: unix.IPPROTO_UDP,
+		Exthdrs: []*nft.ExthdrMatch{
+			{Op: nft.ExthdrOpTCPOption, Type: nft.TCPOptionMSS},
+		},
+	})
+	assert.ErrorContains(t, err, "TCP options require the L4 protocol to be tcp", "expected TCP options on udp to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family:  unix.NFPROTO_IPV4,
+		Table:   "test-table",
+		Chain:   "test-chain",
+		L4Proto: unix.IPPROTO_TCP,
+		ExthdrSets: []*nft.ExthdrSet{
+			{Op: nft.ExthdrOpTCPOption, Type: nft.TCPOptionWindowScale, Offset: 2, FromRtMSS: true},
+		},
+	})
+	assert.ErrorContains(t, err, "the route MSS can only be written to the MSS option", "expected the route MSS to be rejected for other options")
+}
+
+func TestRuleNat(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	addr := netip.MustParseAddr("192.0.2.1")
+	want := []*nft.Rule{
+		{
+			L3Proto: unix.NFPROTO_IPV4,
+			L4Proto: unix.IPPROTO_TCP,
+			Action: &nft.Action{
+				Nat: &nft.Nat{Type: nft.NatTypeDnat, Addr: &addr, Port: 8080},
+			},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV4,
+			Action: &nft.Action{
+				Nat: &nft.Nat{Type: nft.NatTypeSnat, Addr: &addr},
+			},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV4,
+			L4Proto: unix.IPPROTO_TCP,
+			DstPort: &nft.PortMatch{Ports: []uint16{80, 8080}},
+			Action: &nft.Action{
+				Nat: &nft.Nat{
+					Type: nft.NatTypeDnat,
+					Map: &nft.NatMap{
+						Key: nft.MapKey{
+							Numgen: &nft.Numgen{Type: nft.NumgenTypeInc, Modulus: 3},
+						},
+						Elements: []nft.NatMapElem{
+							{Key: 0, Addr: netip.MustParseAddr("10.0.0.1"), Port: 80},
+							{Key: 1, Addr: netip.MustParseAddr("10.0.0.2"), Port: 80},
+							{Key: 2, Addr: netip.MustParseAddr("10.0.0.3"), Port: 8080},
+						},
+					},
+				},
+			},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV6,
+			L4Proto: unix.IPPROTO_UDP,
+			Action: &nft.Action{
+				Nat: &nft.Nat{
+					Type: nft.NatTypeDnat,
+					Map: &nft.NatMap{
+						Key: nft.MapKey{
+							Hash: &nft.Hash{
+								Type:    nft.HashTypeJenkins,
+								Fields:  []nft.HashField{nft.HashFieldSrcAddr, nft.HashFieldSrcPort},
+								Modulus: 2,
+								Seed:    0xdead,
+								Offset:  10,
+							},
+						},
+						Elements: []nft.NatMapElem{
+							{Key: 10, Addr: netip.MustParseAddr("2001:db8::1")},
+							{Key: 11, Addr: netip.MustParseAddr("2001:db8::2")},
+						},
+					},
+				},
+			},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV4,
+			L4Proto: unix.IPPROTO_TCP,
+			Action: &nft.Action{
+				Nat: &nft.Nat{
+					Type: nft.NatTypeDnat,
+					Map: &nft.NatMap{
+						Key: nft.MapKey{
+							Hash: &nft.Hash{
+								Type:    nft.HashTypeJenkins,
+								Fields:  []nft.HashField{nft.HashFieldSrcAddr, nft.HashFieldSrcPort, nft.HashFieldMark},
+								Modulus: 2,
+							},
+						},
+						Elements: []nft.NatMapElem{
+							{Key: 0, Addr: netip.MustParseAddr("10.0.0.1"), Port: 443},
+							{Key: 1, Addr: netip.MustParseAddr("10.0.0.2"), Port: 443},
+						},
+					},
+				},
+			},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV4,
+			Action: &nft.Action{
+				Nat: &nft.Nat{
+					Type: nft.NatTypeSnat,
+					Map: &nft.NatMap{
+						Key: nft.MapKey{
+							Hash: &nft.Hash{Type: nft.HashTypeSymmetric, Modulus: 2},
+						},
+						Elements: []nft.NatMapElem{
+							{Key: 0, Addr: netip.MustParseAddr("198.51.100.1")},
+							{Key: 1, Addr: netip.MustParseAddr("198.51.100.2")},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, nil, want)
+
+	for i := range want {
+		assert.Equal(t, want[i].Action, got[i].Action, "rule %d", i)
+		assert.Nil(t, got[i].SrcIPv6, "rule %d", i)
+	}
+}
+
+func TestRuleNatValidation(t *testing.T) {
+	batch := nft.NewBatch()
+	addr := netip.MustParseAddr("192.0.2.1")
+
+	err := batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Action: &nft.Action{
+			Nat: &nft.Nat{Type: nft.NatTypeDnat, Port: 80},
+		},
+	})
+	assert.ErrorContains(t, err, "L3 protocol must be specified for inet family when translating only the port", "expected translating only the port without L3 protocol to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family:  unix.NFPROTO_IPV4,
+		Table:   "test-table",
+		Chain:   "test-chain",
+		L4Proto: unix.IPPROTO_TCP,
+		Action: &nft.Action{
+			Nat: &nft.Nat{
+				Type: nft.NatTypeDnat,
+				Map: &nft.NatMap{
+					Key: nft.MapKey{Numgen: &nft.Numgen{Type: nft.NumgenTypeRandom, Modulus: 2}},
+					Elements: []nft.NatMapElem{
+						{Key: 0, Addr: addr, Port: 80},
+						{Key: 1, Addr: addr},
+					},
+				},
+			},
+		},
+	})
+	assert.ErrorContains(t, err, "either all or none of the nat map elements must have a port", "expected mixing elements with and without ports to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_IPV4,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Action: &nft.Action{
+			Nat: &nft.Nat{
+				Type: nft.NatTypeDnat,
+				Map: &nft.NatMap{
+					Key: nft.MapKey{
+						Hash: &nft.Hash{
+							Type:    nft.HashTypeJenkins,
+							Fields:  []nft.HashField{nft.HashFieldSrcPort},
+							Modulus: 2,
+						},
+					},
+					Elements: []nft.NatMapElem{{Key: 0, Addr: addr}},
+				},
+			},
+		},
+	})
+	assert.ErrorContains(t, err, "hashing ports requires the L4 protocol to be specified", "expected hashing ports without L4 protocol to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_IPV4,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Action: &nft.Action{
+			Verdict: &nft.Verdict{Code: nft.VerdictCodeAccept},
+			Nat:     &nft.Nat{Type: nft.NatTypeSnat, Addr: &addr},
+		},
+	})
+	assert.ErrorContains(t, err, "nat cannot be combined with a verdict, reject or queue in the same rule", "expected nat with a verdict to be rejected")
+}
+
+func TestRuleXfrm(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	daddr := netip.MustParseAddr("192.0.2.1")
+	saddr := netip.MustParseAddr("2001:db8::1")
+
+	want := []*nft.Rule{
+		{
+			Xfrm: []*nft.XfrmMatch{
+				{Dir: nft.XfrmDirIn, Key: nft.XfrmKeyReqID, ReqID: 1},
+				{Dir: nft.XfrmDirIn, SPNum: 1, Key: nft.XfrmKeySPI, SPI: 0x1000},
+			},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV4,
+			Xfrm: []*nft.XfrmMatch{
+				{Dir: nft.XfrmDirOut, Key: nft.XfrmKeyDAddr, Addr: &daddr},
+			},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV6,
+			Xfrm: []*nft.XfrmMatch{
+				{Dir: nft.XfrmDirIn, Key: nft.XfrmKeySAddr, Addr: &saddr},
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, nil, want)
+
+	for i := range want {
+		assert.Equal(t, want[i].Xfrm, got[i].Xfrm, "rule %d", i)
+	}
+}
+
+func TestRuleOsf(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	want := []*nft.Rule{
+		{
+			Osf: &nft.OsfMatch{Name: "Linux"},
+		},
+		{
+			Osf: &nft.OsfMatch{Name: "Linux:3.11", Version: true, TTL: nft.OsfTTLLoose},
+		},
+		{
+			Osf: &nft.OsfMatch{Name: "unknown", TTL: nft.OsfTTLNoCheck},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, nil, want)
+
+	for i := range want {
+		assert.Equal(t, want[i].Osf, got[i].Osf, "rule %d", i)
+	}
+}
+
+func TestRuleXfrmOsfValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	err := batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Xfrm: []*nft.XfrmMatch{
+			{Key: nft.XfrmKeyReqID, ReqID: 1},
+		},
+	})
+	assert.ErrorContains(t, err, "unknown xfrm direction 0", "expected an xfrm match without a direction to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Xfrm: []*nft.XfrmMatch{
+			{Dir: nft.XfrmDirIn, SPNum: 6, Key: nft.XfrmKeyReqID, ReqID: 1},
+		},
+	})
+	assert.ErrorContains(t, err, "xfrm spnum must be less than 6", "expected an spnum beyond the maximum depth to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Xfrm: []*nft.XfrmMatch{
+			{Dir: nft.XfrmDirOut, Key: nft.XfrmKeyDAddr},
+		},
+	})
+	assert.ErrorContains(t, err, "xfrm address key requires an address", "expected an xfrm address match without an address to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Osf:    &nft.OsfMatch{Name: "a-very-long-genre"},
+	})
+	assert.ErrorContains(t, err, "osf name must be shorter than 16 characters", "expected an osf name that does not fit the register to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_BRIDGE,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Osf:    &nft.OsfMatch{Name: "Linux"},
+	})
+	assert.ErrorContains(t, err, "osf is only supported in the ipv4, ipv6 and inet families", "expected osf in the bridge family to be rejected")
+}
+
+func TestRuleDup(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	addr4 := netip.MustParseAddr("192.0.2.1")
+	addr6 := netip.MustParseAddr("2001:db8::1")
+
+	// The loopback interface is the first interface of a new namespace.
+	tests := []struct {
+		family uint8
+		want   []*nft.Rule
+	}{
+		{
+			family: unix.NFPROTO_IPV4,
+			want: []*nft.Rule{
+				{
+					Dup: &nft.Dup{Addr: &addr4},
+				},
+				{
+					Dup: &nft.Dup{Addr: &addr4, Ifindex: 1},
+				},
+			},
+		},
+		{
+			family: unix.NFPROTO_IPV6,
+			want: []*nft.Rule{
+				{
+					Dup: &nft.Dup{Addr: &addr6, Ifindex: 1},
+				},
+			},
+		},
+		{
+			family: unix.NFPROTO_NETDEV,
+			want: []*nft.Rule{
+				{
+					Dup: &nft.Dup{Ifindex: 1},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got := roundTripRules(t, conn, tt.family, nil, tt.want)
+
+		for i := range tt.want {
+			assert.Equal(t, tt.want[i].Dup, got[i].Dup, "family %d rule %d", tt.family, i)
+		}
+	}
+}
+
+func TestRuleFwd(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	addr4 := netip.MustParseAddr("192.0.2.1")
+	addr6 := netip.MustParseAddr("2001:db8::1")
+
+	want := []*nft.Rule{
+		{
+			Action: &nft.Action{Fwd: &nft.Fwd{Ifindex: 1}},
+		},
+		{
+			Action: &nft.Action{Fwd: &nft.Fwd{Ifindex: 1, Addr: &addr4}},
+		},
+		{
+			Action: &nft.Action{Fwd: &nft.Fwd{Ifindex: 1, Addr: &addr6}},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_NETDEV, nil, want)
+
+	for i := range want {
+		assert.Equal(t, want[i].Action, got[i].Action, "rule %d", i)
+	}
+}
+
+func TestRuleDupFwdValidation(t *testing.T) {
+	batch := nft.NewBatch()
+	addr4 := netip.MustParseAddr("192.0.2.1")
+
+	err := batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_IPV6,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Dup:    &nft.Dup{Addr: &addr4},
+	})
+	assert.ErrorContains(t, err, "dup address family does not match the rule", "expected a dup address of the wrong family to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_NETDEV,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Dup:    &nft.Dup{Addr: &addr4, Ifindex: 1},
+	})
+	assert.ErrorContains(t, err, "dup in the netdev family does not support an address", "expected a dup address in the netdev family to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Action: &nft.Action{Fwd: &nft.Fwd{Ifindex: 1}},
+	})
+	assert.ErrorContains(t, err, "fwd is only supported in the netdev family", "expected fwd outside the netdev family to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_NETDEV,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Action: &nft.Action{Fwd: &nft.Fwd{}},
+	})
+	assert.ErrorContains(t, err, "fwd requires an interface", "expected fwd without an interface to be rejected")
+}
+
+func TestRuleConnlimit(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	batch := nft.NewBatch()
+	// Dynamic sets of IPv4 and IPv6 addresses, the latter with a timeout.
+	for _, set := range []*nftnl.SetAttrs{
+		{Name: "per-source", ID: 1, Flags: unix.NFT_SET_EVAL, KeyType: 7, KeyLen: 4},
+		{Name: "recent", ID: 2, Flags: unix.NFT_SET_EVAL | unix.NFT_SET_TIMEOUT, KeyType: 8, KeyLen: 16},
+	} {
+		set.Table = testTable
+		batch.Add(nftnl.Msg{
+			Header: nftnl.Header{
+				SubsysID: unix.NFNL_SUBSYS_NFTABLES,
+				MsgType:  unix.NFT_MSG_NEWSET,
+				Flags:    netlink.Request | netlink.Acknowledge | netlink.Create,
+			},
+			NfGenMsg: nftnl.NfGenMsg{Family: unix.NFPROTO_INET},
+			Attrs:    set,
+		})
+	}
+
+	want := []*nft.Rule{
+		{
+			Connlimit: &nft.Connlimit{Count: 10},
+			Last:      &nft.Last{},
+		},
+		{
+			Connlimit: &nft.Connlimit{Count: 2, Over: true},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV4,
+			SetUpdates: []*nft.SetUpdate{
+				{
+					Op:        nft.SetUpdateOpAdd,
+					Set:       "per-source",
+					Key:       nft.SetKeySrcAddr,
+					Connlimit: &nft.Connlimit{Count: 4, Over: true},
+					Last:      &nft.Last{},
+				},
+			},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV6,
+			SetUpdates: []*nft.SetUpdate{
+				{
+					Op:      nft.SetUpdateOpUpdate,
+					Set:     "recent",
+					Key:     nft.SetKeyDstAddr,
+					Timeout: time.Minute,
+					Last:    &nft.Last{},
+				},
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, batch, want)
+
+	for i := range want {
+		g := got[i]
+		assert.Equal(t, want[i].Connlimit, g.Connlimit, "rule %d", i)
+		assert.Equal(t, want[i].Last, g.Last, "rule %d", i)
+		assert.Equal(t, want[i].SetUpdates, g.SetUpdates, "rule %d", i)
+	}
+}
+
+func TestRuleSetUpdateValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	err := batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		SetUpdates: []*nft.SetUpdate{
+			{Op: nft.SetUpdateOpAdd, Set: "per-source", Key: nft.SetKeySrcAddr},
+		},
+	})
+	assert.ErrorContains(t, err, "set key on an address requires the L3 protocol to be specified", "expected an address key without an L3 protocol to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_IPV4,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		SetUpdates: []*nft.SetUpdate{
+			{Op: nft.SetUpdateOpAdd, Key: nft.SetKeySrcAddr},
+		},
+	})
+	assert.ErrorContains(t, err, "set update requires a set name", "expected a set update without a set name to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_IPV4,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		SetUpdates: []*nft.SetUpdate{
+			{Set: "per-source", Key: nft.SetKeySrcAddr},
+		},
+	})
+	assert.ErrorContains(t, err, "unknown set update op 0", "expected a set update without an op to be rejected")
+}
+
+func TestRuleSynproxy(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	batch := nft.NewBatch()
+	obj := &nft.Object{
+		Family:   unix.NFPROTO_INET,
+		Table:    testTable,
+		Name:     "test-synproxy",
+		Synproxy: &nft.Synproxy{MSS: 1460, Wscale: 7, Timestamp: true},
+	}
+	err := batch.NewObject(obj)
+	require.NoError(t, err, "failed to add NewObject to batch")
+
+	// A map from source addresses to synproxy objects.
+	batch.Add(nftnl.Msg{
+		Header: nftnl.Header{
+			SubsysID: unix.NFNL_SUBSYS_NFTABLES,
+			MsgType:  unix.NFT_MSG_NEWSET,
+			Flags:    netlink.Request | netlink.Acknowledge | netlink.Create,
+		},
+		NfGenMsg: nftnl.NfGenMsg{Family: unix.NFPROTO_INET},
+		Attrs: &nftnl.SetAttrs{
+			Table:   testTable,
+			Name:    "synproxies",
+			ID:      1,
+			Flags:   unix.NFT_SET_OBJECT,
+			KeyType: 7,
+			KeyLen:  4,
+			ObjType: uint32(nft.ObjectTypeSynproxy),
+		},
+	})
+	batch.Add(nftnl.Msg{
+		Header: nftnl.Header{
+			SubsysID: unix.NFNL_SUBSYS_NFTABLES,
+			MsgType:  unix.NFT_MSG_NEWSETELEM,
+			Flags:    netlink.Request | netlink.Acknowledge | netlink.Create,
+		},
+		NfGenMsg: nftnl.NfGenMsg{Family: unix.NFPROTO_INET},
+		Attrs: &nftnl.SetElemListAttrs{
+			Table: testTable,
+			Set:   "synproxies",
+			Elements: []nftnl.SetElemAttrs{
+				{
+					Key:    &nftnl.DataAttrs{Value: []byte{192, 0, 2, 1}},
+					ObjRef: "test-synproxy",
+				},
+			},
+		},
+	})
+
+	want := []*nft.Rule{
+		{
+			Synproxy: &nft.Synproxy{MSS: 1460, Wscale: 7, Timestamp: true, SackPerm: true},
+		},
+		{
+			Synproxy: &nft.Synproxy{},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV4,
+			ObjectRefs: []*nft.ObjectRef{
+				{Map: "synproxies", Key: nft.SetKeySrcAddr},
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, batch, want)
+
+	for i := range want {
+		assert.Equal(t, want[i].Synproxy, got[i].Synproxy, "rule %d", i)
+		assert.Equal(t, want[i].ObjectRefs, got[i].ObjectRefs, "rule %d", i)
+	}
+
+	gotObj, err := conn.GetObject(&nft.Object{
+		Family: unix.NFPROTO_INET,
+		Table:  testTable,
+		Name:   "test-synproxy",
+	})
+	require.NoError(t, err, "failed to get object")
+	assert.Equal(t, nft.ObjectTypeSynproxy, gotObj.Type)
+	assert.Equal(t, obj.Synproxy, gotObj.Synproxy)
+}
+
+func TestNewSynproxy(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	tableName := "test-table"
+
+	batch := nft.NewBatch()
+	err := batch.NewTable(&nft.Table{
+		Family: unix.NFPROTO_INET,
+		Name:   tableName,
+	})
+	require.NoError(t, err, "failed to add NewTable to batch")
+	for _, chain := range []*nftnl.ChainAttrs{
+		{Name: "raw", Hook: &nftnl.HookAttrs{Number: unix.NF_INET_PRE_ROUTING, Priority: -300}},
+		{Name: "filter", Hook: &nftnl.HookAttrs{Number: unix.NF_INET_LOCAL_IN}},
+	} {
+		chain.Table = tableName
+		chain.Type = "filter"
+		chain.Policy = unixext.NF_ACCEPT
+		batch.Add(nftnl.Msg{
+			Header: nftnl.Header{
+				SubsysID: unix.NFNL_SUBSYS_NFTABLES,
+				MsgType:  unix.NFT_MSG_NEWCHAIN,
+				Flags:    netlink.Request | netlink.Acknowledge | netlink.Create,
+			},
+			NfGenMsg: nftnl.NfGenMsg{Family: unix.NFPROTO_INET},
+			Attrs:    chain,
+		})
+	}
+
+	synproxy := nft.Synproxy{MSS: 1460, Wscale: 7, Timestamp: true, SackPerm: true}
+	err = batch.NewSynproxy(&nft.SynproxySetup{
+		Family:      unix.NFPROTO_INET,
+		Table:       tableName,
+		RawChain:    "raw",
+		FilterChain: "filter",
+		Port:        8080,
+		Synproxy:    synproxy,
+	})
+	require.NoError(t, err, "failed to add NewSynproxy to batch")
+
+	err = conn.SendBatch(batch)
+	require.NoError(t, err, "failed to create synproxy rules")
+
+	got, err := conn.GetRules(&nft.Chain{
+		Family: unix.NFPROTO_INET,
+		Table:  tableName,
+		Name:   "raw",
+	})
+	require.NoError(t, err, "failed to get rules")
+	assert.Len(t, got, 1)
+
+	got, err = conn.GetRules(&nft.Chain{
+		Family: unix.NFPROTO_INET,
+		Table:  tableName,
+		Name:   "filter",
+	})
+	require.NoError(t, err, "failed to get rules")
+	require.Len(t, got, 2)
+	assert.Equal(t, &synproxy, got[0].Synproxy)
+	assert.Equal(t, &nft.Action{Verdict: &nft.Verdict{Code: nft.VerdictCodeDrop}}, got[1].Action)
+}
+
+func TestRuleSynproxyValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	err := batch.NewRule(&nft.Rule{
+		Family:   unix.NFPROTO_INET,
+		Table:    "test-table",
+		Chain:    "test-chain",
+		Synproxy: &nft.Synproxy{Wscale: 15},
+	})
+	assert.ErrorContains(t, err, "synproxy window scale must be at most 14", "expected a window scale above 14 to be rejected")
+
+	err = batch.NewObject(&nft.Object{
+		Family:   unix.NFPROTO_INET,
+		Table:    "test-table",
+		Name:     "wscale",
+		Synproxy: &nft.Synproxy{Wscale: 15},
+	})
+	assert.ErrorContains(t, err, "synproxy window scale must be at most 14", "expected a synproxy object with a window scale above 14 to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family:   unix.NFPROTO_NETDEV,
+		Table:    "test-table",
+		Chain:    "test-chain",
+		Synproxy: &nft.Synproxy{},
+	})
+	assert.ErrorContains(t, err, "synproxy is only supported in the ipv4, ipv6 and inet families", "expected synproxy in the netdev family to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		ObjectRefs: []*nft.ObjectRef{
+			{Map: "synproxies", Key: nft.SetKeySrcAddr},
+		},
+	})
+	assert.ErrorContains(t, err, "set key on an address requires the L3 protocol to be specified", "expected an object map keyed on an address without an L3 protocol to be rejected")
+
+	err = batch.NewSynproxy(&nft.SynproxySetup{
+		Family:      unix.NFPROTO_INET,
+		Table:       "test-table",
+		RawChain:    "raw",
+		FilterChain: "filter",
+	})
+	assert.ErrorContains(t, err, "synproxy port must be specified", "expected a synproxy setup without a port to be rejected")
+}
+
+func TestRuleCtObjects(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	batch := nft.NewBatch()
+	objs := []*nft.Object{
+		{
+			Name:     "ftp-std",
+			CtHelper: &nft.CtHelper{Name: "ftp", L4Proto: unix.IPPROTO_TCP},
+		},
+		{
+			Name:     "tftp-v4",
+			CtHelper: &nft.CtHelper{Name: "tftp", L3Proto: unix.NFPROTO_IPV4, L4Proto: unix.IPPROTO_UDP},
+		},
+		{
+			Name: "tcp-short",
+			CtTimeout: &nft.CtTimeout{
+				L4Proto: unix.IPPROTO_TCP,
+				Timeouts: map[nft.CtTimeoutState]time.Duration{
+					nft.CtTimeoutEstablished: 2 * time.Minute,
+					nft.CtTimeoutClose:       10 * time.Second,
+				},
+			},
+		},
+		{
+			Name: "udp-short",
+			CtTimeout: &nft.CtTimeout{
+				L3Proto: unix.NFPROTO_IPV6,
+				L4Proto: unix.IPPROTO_UDP,
+				Timeouts: map[nft.CtTimeoutState]time.Duration{
+					nft.CtTimeoutReplied: 30 * time.Second,
+				},
+			},
+		},
+		{
+			Name: "expect-data",
+			CtExpect: &nft.CtExpect{
+				L3Proto: unix.NFPROTO_IPV4,
+				L4Proto: unix.IPPROTO_TCP,
+				DPort:   2121,
+				Timeout: 30 * time.Second,
+				Size:    8,
+			},
+		},
+	}
+	for _, obj := range objs {
+		obj.Family = unix.NFPROTO_INET
+		obj.Table = testTable
+		err := batch.NewObject(obj)
+		require.NoError(t, err, "failed to add NewObject to batch")
+	}
+
+	want := []*nft.Rule{
+		{
+			L4Proto:    unix.IPPROTO_TCP,
+			ObjectRefs: []*nft.ObjectRef{{Type: nft.ObjectTypeCtHelper, Name: "ftp-std"}},
+		},
+		{
+			ObjectRefs: []*nft.ObjectRef{{Type: nft.ObjectTypeCtTimeout, Name: "tcp-short"}},
+		},
+		{
+			ObjectRefs: []*nft.ObjectRef{{Type: nft.ObjectTypeCtExpect, Name: "expect-data"}},
+		},
+		{
+			Notrack: true,
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, batch, want)
+
+	for i := range want {
+		assert.Equal(t, want[i].ObjectRefs, got[i].ObjectRefs, "rule %d", i)
+		assert.Equal(t, want[i].Notrack, got[i].Notrack, "rule %d", i)
+	}
+
+	for _, obj := range objs {
+		gotObj, err := conn.GetObject(obj)
+		require.NoError(t, err, "failed to get object %q", obj.Name)
+		assert.Equal(t, obj.CtHelper, gotObj.CtHelper, "object %q", obj.Name)
+		assert.Equal(t, obj.CtExpect, gotObj.CtExpect, "object %q", obj.Name)
+		if obj.CtTimeout != nil {
+			// The kernel reports the defaults of the states that were not set.
+			require.NotNil(t, gotObj.CtTimeout, "object %q", obj.Name)
+			assert.Equal(t, obj.CtTimeout.L3Proto, gotObj.CtTimeout.L3Proto, "object %q", obj.Name)
+			assert.Equal(t, obj.CtTimeout.L4Proto, gotObj.CtTimeout.L4Proto, "object %q", obj.Name)
+			for state, timeout := range obj.CtTimeout.Timeouts {
+				assert.Equal(t, timeout, gotObj.CtTimeout.Timeouts[state], "object %q state %q", obj.Name, state)
+			}
+		}
+	}
+}
+
+func TestRuleCtObjectsValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	for _, tc := range []struct {
+		name string
+		err  string
+		obj  *nft.Object
+	}{
+		{
+			name: "ct helper without a name",
+			err:  "ct helper name must be specified",
+			obj:  &nft.Object{CtHelper: &nft.CtHelper{L4Proto: unix.IPPROTO_TCP}},
+		},
+		{
+			name: "ct helper with a name longer than 15 characters",
+			err:  "ct helper name must be at most 15 characters",
+			obj:  &nft.Object{CtHelper: &nft.CtHelper{Name: "a-very-long-helper", L4Proto: unix.IPPROTO_TCP}},
+		},
+		{
+			name: "ct helper for icmp",
+			err:  "ct helper L4 protocol must be tcp or udp",
+			obj:  &nft.Object{CtHelper: &nft.CtHelper{Name: "ftp", L4Proto: unix.IPPROTO_ICMP}},
+		},
+		{
+			name: "ct timeout without an L4 protocol",
+			err:  "ct timeout L4 protocol must be specified",
+			obj:  &nft.Object{CtTimeout: &nft.CtTimeout{}},
+		},
+		{
+			name: "ct timeout with a tcp state for udp",
+			err:  `ct timeout state "established" is not valid for L4 protocol 17`,
+			obj: &nft.Object{CtTimeout: &nft.CtTimeout{
+				L4Proto:  unix.IPPROTO_UDP,
+				Timeouts: map[nft.CtTimeoutState]time.Duration{nft.CtTimeoutEstablished: time.Minute},
+			}},
+		},
+		{
+			name: "ct timeout for sctp",
+			err:  "ct timeout L4 protocol must be tcp, udp, udplite, icmp or icmpv6",
+			obj: &nft.Object{CtTimeout: &nft.CtTimeout{
+				L4Proto:  unix.IPPROTO_SCTP,
+				Timeouts: map[nft.CtTimeoutState]time.Duration{nft.CtTimeoutGeneric: time.Minute},
+			}},
+		},
+		{
+			name: "ct expectation without a port",
+			err:  "ct expectation destination port must be specified",
+			obj:  &nft.Object{CtExpect: &nft.CtExpect{L3Proto: unix.NFPROTO_IPV4, L4Proto: unix.IPPROTO_TCP, Size: 1}},
+		},
+		{
+			name: "ct expectation without an L3 protocol in the inet family",
+			err:  "ct expectation L3 protocol must be ipv4 or ipv6 in the inet family",
+			obj:  &nft.Object{CtExpect: &nft.CtExpect{L4Proto: unix.IPPROTO_TCP, DPort: 21, Size: 1}},
+		},
+		{
+			name: "ct expectation for IPv6 in the ipv4 family",
+			err:  "ct expectation L3 protocol must match the family of the table",
+			obj: &nft.Object{
+				Family:   unix.NFPROTO_IPV4,
+				CtExpect: &nft.CtExpect{L3Proto: unix.NFPROTO_IPV6, L4Proto: unix.IPPROTO_TCP, DPort: 21, Size: 1},
+			},
+		},
+		{
+			name: "ct helper and ct expectation",
+			err:  "exactly one of counter, quota, limit, synproxy, ct helper, ct timeout, ct expectation or tunnel must be specified",
+			obj: &nft.Object{
+				CtHelper: &nft.CtHelper{Name: "ftp", L4Proto: unix.IPPROTO_TCP},
+				CtExpect: &nft.CtExpect{L4Proto: unix.IPPROTO_TCP, DPort: 21, Size: 1},
+			},
+		},
+	} {
+		if tc.obj.Family == 0 {
+			tc.obj.Family = unix.NFPROTO_INET
+		}
+		tc.obj.Table = "test-table"
+		tc.obj.Name = "test-object"
+		err := batch.NewObject(tc.obj)
+		assert.ErrorContains(t, err, tc.err, "expected %s to be rejected", tc.name)
+	}
+}
+
+func TestRuleInner(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	vni := uint32(4242)
+	addr4 := netip.MustParseAddr("10.0.0.1")
+	prefix4 := netip.MustParsePrefix("10.1.0.0/16")
+	prefix6 := netip.MustParsePrefix("2001:db8::/32")
+
+	want := []*nft.Rule{
+		{
+			L4Proto: unix.IPPROTO_UDP,
+			Inner: &nft.InnerMatch{
+				Tunnel:  nft.InnerTunnelVxlan,
+				VNI:     &vni,
+				L3Proto: unix.NFPROTO_IPV4,
+				SrcIP:   &nft.IPMatch{Addr: &addr4},
+				DstIP:   &nft.IPMatch{Prefix: &prefix4},
+			},
+		},
+		{
+			L4Proto: unix.IPPROTO_UDP,
+			Inner: &nft.InnerMatch{
+				Tunnel:  nft.InnerTunnelGeneve,
+				L3Proto: unix.NFPROTO_IPV6,
+				L4Proto: unix.IPPROTO_TCP,
+				SrcIP:   &nft.IPMatch{Prefix: &prefix6},
+			},
+		},
+		{
+			L4Proto: unix.IPPROTO_GRE,
+			Inner: &nft.InnerMatch{
+				Tunnel:  nft.InnerTunnelGre,
+				L3Proto: unix.NFPROTO_IPV4,
+				DstIP:   &nft.IPMatch{Addr: &addr4},
+			},
+		},
+		{
+			L4Proto: unix.IPPROTO_UDP,
+			Inner: &nft.InnerMatch{
+				Tunnel:  nft.InnerTunnelGreUDP,
+				L4Proto: unix.IPPROTO_ICMP,
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, nil, want)
+
+	for i := range want {
+		assert.Equal(t, want[i].L4Proto, got[i].L4Proto, "rule %d", i)
+		assert.Equal(t, want[i].Inner, got[i].Inner, "rule %d", i)
+	}
+}
+
+func TestRuleTunnel(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	batch := nft.NewBatch()
+	src4 := netip.MustParseAddr("192.0.2.1")
+	dst4 := netip.MustParseAddr("192.0.2.2")
+	dst6 := netip.MustParseAddr("2001:db8::2")
+
+	objs := []*nft.Object{
+		{
+			Name: "vxlan-tenant",
+			Tunnel: &nft.Tunnel{
+				ID:           4242,
+				Src:          &src4,
+				Dst:          &dst4,
+				DstPort:      4789,
+				TTL:          64,
+				DontFragment: true,
+				VxlanGBP:     200,
+			},
+		},
+		{
+			Name: "geneve-tenant",
+			Tunnel: &nft.Tunnel{
+				ID:           7,
+				Dst:          &dst6,
+				FlowLabel:    0x12345,
+				DstPort:      6081,
+				ZeroChecksum: true,
+				Geneve: []nft.TunnelGeneveOpt{
+					{Class: 0x102, Type: 0x80, Data: []byte{0, 0, 0, 1}},
+					{Class: 0x102, Type: 0x81, Data: []byte{0, 0, 0, 2, 0, 0, 0, 3}},
+				},
+			},
+		},
+		{
+			Name: "erspan",
+			Tunnel: &nft.Tunnel{
+				ID:     1,
+				Dst:    &dst4,
+				Erspan: &nft.TunnelErspan{Version: 2, HWID: 3, Dir: 1},
+			},
+		},
+	}
+	for _, obj := range objs {
+		obj.Family = unix.NFPROTO_NETDEV
+		obj.Table = testTable
+		err := batch.NewObject(obj)
+		require.NoError(t, err, "failed to add NewObject to batch")
+	}
+
+	want := []*nft.Rule{
+		{
+			Tunnel: &nft.TunnelMatch{Key: nft.TunnelKeyPath, Path: true},
+		},
+		{
+			Tunnel: &nft.TunnelMatch{Key: nft.TunnelKeyID, Mode: nft.TunnelModeRx, ID: 4242},
+		},
+		{
+			ObjectRefs: []*nft.ObjectRef{{Type: nft.ObjectTypeTunnel, Name: "vxlan-tenant"}},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_NETDEV, batch, want)
+
+	for i := range want {
+		assert.Equal(t, want[i].Tunnel, got[i].Tunnel, "rule %d", i)
+		assert.Equal(t, want[i].ObjectRefs, got[i].ObjectRefs, "rule %d", i)
+	}
+
+	for _, obj := range objs {
+		gotObj, err := conn.GetObject(obj)
+		require.NoError(t, err, "failed to get object %q", obj.Name)
+		assert.Equal(t, nft.ObjectTypeTunnel, gotObj.Type)
+		assert.Equal(t, obj.Tunnel, gotObj.Tunnel, "object %q", obj.Name)
+	}
+}
+
+func TestRuleInnerTunnelValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	vni := uint32(1 << 24)
+	addr6 := netip.MustParseAddr("2001:db8::1")
+	dst4 := netip.MustParseAddr("192.0.2.2")
+
+	for _, tc := range []struct {
+		name string
+		err  string
+		rule *nft.Rule
+	}{
+		{
+			name: "vxlan inner match without L4 protocol UDP",
+			err:  "inner match on a UDP tunnel requires L4 protocol UDP",
+			rule: &nft.Rule{
+				Family: unix.NFPROTO_INET,
+				Inner:  &nft.InnerMatch{Tunnel: nft.InnerTunnelVxlan, L4Proto: unix.IPPROTO_TCP},
+			},
+		},
+		{
+			name: "gre inner match with a VNI",
+			err:  "inner VNI is only supported with VXLAN and Geneve",
+			rule: &nft.Rule{
+				Family:  unix.NFPROTO_INET,
+				L4Proto: unix.IPPROTO_GRE,
+				Inner:   &nft.InnerMatch{Tunnel: nft.InnerTunnelGre, VNI: new(uint32)},
+			},
+		},
+		{
+			name: "a VNI above 24 bits",
+			err:  "invalid VNI 16777216",
+			rule: &nft.Rule{
+				Family:  unix.NFPROTO_INET,
+				L4Proto: unix.IPPROTO_UDP,
+				Inner:   &nft.InnerMatch{Tunnel: nft.InnerTunnelVxlan, VNI: &vni},
+			},
+		},
+		{
+			name: "an inner address without an inner L3 protocol",
+			err:  "inner L3 protocol must be specified when matching on inner addresses",
+			rule: &nft.Rule{
+				Family:  unix.NFPROTO_INET,
+				L4Proto: unix.IPPROTO_UDP,
+				Inner:   &nft.InnerMatch{Tunnel: nft.InnerTunnelVxlan, SrcIP: &nft.IPMatch{Addr: &addr6}},
+			},
+		},
+		{
+			name: "an IPv6 inner address with inner L3 protocol IPv4",
+			err:  "inner address 2001:db8::1 does not match the inner L3 protocol",
+			rule: &nft.Rule{
+				Family:  unix.NFPROTO_INET,
+				L4Proto: unix.IPPROTO_UDP,
+				Inner: &nft.InnerMatch{
+					Tunnel:  nft.InnerTunnelVxlan,
+					L3Proto: unix.NFPROTO_IPV4,
+					SrcIP:   &nft.IPMatch{Addr: &addr6},
+				},
+			},
+		},
+		{
+			name: "a tunnel match outside the netdev family",
+			err:  "tunnel is only supported in the netdev family",
+			rule: &nft.Rule{
+				Family: unix.NFPROTO_INET,
+				Tunnel: &nft.TunnelMatch{Key: nft.TunnelKeyPath},
+			},
+		},
+		{
+			name: "a tunnel object reference outside the netdev family",
+			err:  "tunnel objects are only supported in the netdev family",
+			rule: &nft.Rule{
+				Family:     unix.NFPROTO_INET,
+				ObjectRefs: []*nft.ObjectRef{{Type: nft.ObjectTypeTunnel, Name: "tunnel"}},
+			},
+		},
+	} {
+		tc.rule.Table = "test-table"
+		tc.rule.Chain = "test-chain"
+		err := batch.NewRule(tc.rule)
+		assert.ErrorContains(t, err, tc.err, "expected %s to be rejected", tc.name)
+	}
+
+	for _, tc := range []struct {
+		name string
+		err  string
+		obj  *nft.Object
+	}{
+		{
+			name: "a tunnel object outside the netdev family",
+			err:  "tunnel objects are only supported in the netdev family",
+			obj:  &nft.Object{Family: unix.NFPROTO_INET, Tunnel: &nft.Tunnel{Dst: &dst4}},
+		},
+		{
+			name: "a tunnel object without a destination",
+			err:  "tunnel destination address must be specified",
+			obj:  &nft.Object{Family: unix.NFPROTO_NETDEV, Tunnel: &nft.Tunnel{}},
+		},
+		{
+			name: "a tunnel object with vxlan and geneve options",
+			err:  "at most one of vxlan, erspan or geneve options may be specified",
+			obj: &nft.Object{Family: unix.NFPROTO_NETDEV, Tunnel: &nft.Tunnel{
+				Dst:      &dst4,
+				VxlanGBP: 1,
+				Geneve:   []nft.TunnelGeneveOpt{{Data: []byte{0, 0, 0, 1}}},
+			}},
+		},
+		{
+			name: "a geneve option of 3 bytes",
+			err:  "geneve option data must be a multiple of 4 bytes, up to 124",
+			obj: &nft.Object{Family: unix.NFPROTO_NETDEV, Tunnel: &nft.Tunnel{
+				Dst:    &dst4,
+				Geneve: []nft.TunnelGeneveOpt{{Data: []byte{0, 0, 1}}},
+			}},
+		},
+	} {
+		tc.obj.Table = "test-table"
+		tc.obj.Name = "test-object"
+		err := batch.NewObject(tc.obj)
+		assert.ErrorContains(t, err, tc.err, "expected %s to be rejected", tc.name)
+	}
+}
+
+func TestRuleXt(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	comment := make([]byte, unixext.XT_MAX_COMMENT_LEN)
+	copy(comment, "allow web")
+
+	// struct xt_conntrack_mtinfo3
+	conntrack := make([]byte, 164)
+	copy(conntrack[32:], []byte{10, 0, 0, 0})
+	copy(conntrack[48:], []byte{255, 0, 0, 0})
+	binary.NativeEndian.PutUint16(conntrack[136:], unix.IPPROTO_TCP)
+	binary.NativeEndian.PutUint16(conntrack[140:], 80)
+	binary.NativeEndian.PutUint16(conntrack[146:], unixext.XT_CONNTRACK_STATE|unixext.XT_CONNTRACK_PROTO|
+		unixext.XT_CONNTRACK_ORIGDST|unixext.XT_CONNTRACK_ORIGDST_PORT)
+	binary.NativeEndian.PutUint16(conntrack[150:], uint16(nft.CtStateEstablished|nft.CtStateRelated))
+	binary.NativeEndian.PutUint16(conntrack[156:], 90)
+
+	// struct xt_multiport_v1
+	multiport := make([]byte, 48)
+	multiport[0] = unixext.XT_MULTIPORT_DESTINATION
+	multiport[1] = 3
+	for i, port := range []uint16{22, 8000, 8080} {
+		binary.NativeEndian.PutUint16(multiport[2+2*i:], port)
+	}
+	multiport[33] = 1
+
+	// struct xt_mark_mtinfo1
+	mark := make([]byte, 12)
+	binary.NativeEndian.PutUint32(mark[0:], 0x10)
+	binary.NativeEndian.PutUint32(mark[4:], 0xff)
+	mark[8] = 1
+
+	// struct nf_nat_range2
+	dnat := make([]byte, 44)
+	binary.NativeEndian.PutUint32(dnat[0:], unixext.NF_NAT_RANGE_MAP_IPS|unixext.NF_NAT_RANGE_PROTO_SPECIFIED)
+	copy(dnat[4:], []byte{192, 0, 2, 10})
+	copy(dnat[20:], []byte{192, 0, 2, 10})
+	binary.BigEndian.PutUint16(dnat[36:], 8080)
+	binary.BigEndian.PutUint16(dnat[38:], 8080)
+
+	// struct nf_nat_ipv4_multi_range_compat
+	masquerade := make([]byte, 20)
+	binary.NativeEndian.PutUint32(masquerade[0:], 1)
+	binary.NativeEndian.PutUint32(masquerade[4:], unixext.NF_NAT_RANGE_PROTO_RANDOM_FULLY)
+
+	origDst := netip.MustParsePrefix("10.0.0.0/8")
+	dnatAddr := netip.MustParseAddr("192.0.2.10")
+
+	rules := []*nft.Rule{
+		{
+			XtMatches: []*nft.XtMatch{
+				{Name: "comment", Info: comment},
+				{Name: "mark", Rev: 1, Info: mark},
+			},
+		},
+		{
+			L4Proto: unix.IPPROTO_TCP,
+			XtMatches: []*nft.XtMatch{
+				{Name: "conntrack", Rev: 3, Info: conntrack},
+				{Name: "multiport", Rev: 1, Info: multiport},
+			},
+		},
+		{
+			XtTarget: &nft.XtTarget{Name: "DNAT", Rev: 2, Info: dnat},
+		},
+		{
+			XtTarget: &nft.XtTarget{Name: "MASQUERADE", Info: masquerade},
+		},
+	}
+
+	// The nat targets can only be used in a table named nat.
+	for _, rule := range rules {
+		rule.Table = "nat"
+	}
+	got := roundTripRules(t, conn, unix.NFPROTO_IPV4, nil, rules)
+
+	matches := got[0].XtMatches
+	require.Len(t, matches, 2)
+	assert.Equal(t, "allow web", matches[0].Comment)
+	assert.Equal(t, &nft.XtMark{Mark: 0x10, Mask: 0xff, Invert: true}, matches[1].Mark)
+
+	matches = got[1].XtMatches
+	require.Len(t, matches, 2)
+	assert.Equal(t, &nft.XtConntrack{
+		Flags: unixext.XT_CONNTRACK_STATE | unixext.XT_CONNTRACK_PROTO |
+			unixext.XT_CONNTRACK_ORIGDST | unixext.XT_CONNTRACK_ORIGDST_PORT,
+		States:      []nft.CtState{nft.CtStateEstablished, nft.CtStateRelated},
+		L4Proto:     unix.IPPROTO_TCP,
+		OrigDst:     &origDst,
+		OrigDstPort: nft.XtPortRange{Min: 80, Max: 90},
+	}, matches[0].Conntrack)
+	assert.Equal(t, &nft.XtMultiport{
+		Dir:   nft.XtMultiportDst,
+		Ports: []nft.XtPortRange{{Min: 22, Max: 22}, {Min: 8000, Max: 8080}},
+	}, matches[1].Multiport)
+
+	assert.Equal(t, &nft.XtNatRange{
+		MinAddr: &dnatAddr,
+		MaxAddr: &dnatAddr,
+		MinPort: 8080,
+		MaxPort: 8080,
+	}, got[2].XtTarget.DNAT)
+	assert.Equal(t, &nft.XtNatRange{RandomFully: true}, got[3].XtTarget.Masquerade)
+}
+
+func TestRuleXtConntrack(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	if binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		t.Skip("the conntrack match info was encoded on a little-endian host")
+	}
+
+	// struct xt_conntrack_mtinfo3 as iptables-nft encodes "-m conntrack
+	// --ctstate ESTABLISHED,RELATED --ctproto tcp --ctorigdst 10.0.0.0/8
+	// --ctorigdstport 80".
+	info, err := hex.DecodeString("" +
+		"00000000000000000000000000000000" + // origsrc_addr
+		"00000000000000000000000000000000" + // origsrc_mask
+		"0a000000000000000000000000000000" + // origdst_addr
+		"ff000000000000000000000000000000" + // origdst_mask
+		"00000000000000000000000000000000" + // replsrc_addr
+		"00000000000000000000000000000000" + // replsrc_mask
+		"00000000000000000000000000000000" + // repldst_addr
+		"00000000000000000000000000000000" + // repldst_mask
+		"00000000" + "00000000" + // expires_min, expires_max
+		"0600" + "0000" + "5000" + "0000" + "0000" + // l4proto, the low ends of the ports
+		"0b02" + "0000" + "0600" + "0000" + // match_flags, invert_flags, state_mask, status_mask
+		"0000" + "5000" + "0000" + "0000" + // the high ends of the ports
+		"0000")
+	require.NoError(t, err)
+
+	want := []*nft.Rule{
+		{
+			L4Proto: unix.IPPROTO_TCP,
+			XtMatches: []*nft.XtMatch{
+				{Name: "conntrack", Rev: 3, Info: info},
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_IPV4, nil, want)
+
+	origDst := netip.MustParsePrefix("10.0.0.0/8")
+	require.Len(t, got[0].XtMatches, 1)
+	assert.Equal(t, &nft.XtConntrack{
+		Flags: unixext.XT_CONNTRACK_STATE | unixext.XT_CONNTRACK_PROTO |
+			unixext.XT_CONNTRACK_ORIGDST | unixext.XT_CONNTRACK_ORIGDST_PORT,
+		States:      []nft.CtState{nft.CtStateEstablished, nft.CtStateRelated},
+		L4Proto:     unix.IPPROTO_TCP,
+		OrigDst:     &origDst,
+		OrigDstPort: nft.XtPortRange{Min: 80, Max: 80},
+	}, got[0].XtMatches[0].Conntrack)
+}
+
+func TestRuleXtValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	err := batch.NewRule(&nft.Rule{
+		Family:    unix.NFPROTO_IPV4,
+		Table:     "test-table",
+		Chain:     "test-chain",
+		XtMatches: []*nft.XtMatch{{Info: make([]byte, 4)}},
+	})
+	assert.ErrorContains(t, err, "xt extension name must be specified", "expected a match without a name to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family:   unix.NFPROTO_IPV4,
+		Table:    "test-table",
+		Chain:    "test-chain",
+		XtTarget: &nft.XtTarget{Name: "A-TARGET-NAME-LONGER-THAN-28-BYTES"},
+	})
+	assert.ErrorContains(t, err, "xt extension name must be at most 28 characters", "expected a target name longer than 28 characters to be rejected")
+}
+
+func TestRulePort(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	batch := nft.NewBatch()
+	// A named set of ports.
+	setID := batch.NewID()
+	batch.Add(nftnl.Msg{
+		Header: nftnl.Header{
+			SubsysID: unix.NFNL_SUBSYS_NFTABLES,
+			MsgType:  unix.NFT_MSG_NEWSET,
+			Flags:    netlink.Request | netlink.Acknowledge | netlink.Create,
+		},
+		NfGenMsg: nftnl.NfGenMsg{Family: unix.NFPROTO_INET},
+		Attrs: &nftnl.SetAttrs{
+			Table:   testTable,
+			Name:    "web-ports",
+			ID:      setID,
+			KeyType: 13,
+			KeyLen:  2,
+		},
+	})
+
+	want := []*nft.Rule{
+		{
+			L4Proto: unix.IPPROTO_TCP,
+			DstPort: &nft.PortMatch{Port: 22},
+		},
+		{
+			L4Proto: unix.IPPROTO_UDP,
+			SrcPort: &nft.PortMatch{Port: 1024, MaxPort: 65535},
+			DstPort: &nft.PortMatch{Ports: []uint16{53, 123, 5353}},
+		},
+		{
+			DstPort: &nft.PortMatch{Ports: []uint16{80, 443}},
+		},
+		{
+			L4Proto: unix.IPPROTO_SCTP,
+			SrcPort: &nft.PortMatch{Port: 9},
+		},
+		{
+			L4Proto: unix.IPPROTO_TCP,
+			DstPort: &nft.PortMatch{Set: "web-ports", SetID: setID},
+		},
+		{
+			Ct: &nft.CtMatch{
+				SrcPort: &nft.PortMatch{Port: 1024, MaxPort: 2048},
+				DstPort: &nft.PortMatch{Ports: []uint16{8080, 8443}},
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, batch, want)
+	// The set ID is not dumped.
+	want[4].DstPort.SetID = 0
+
+	for i := range want {
+		assert.Equal(t, want[i], got[i], "rule %d", i)
+	}
+}
+
+func TestRulePortValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	err := batch.NewRule(&nft.Rule{
+		Family:  unix.NFPROTO_INET,
+		Table:   "test-table",
+		Chain:   "test-chain",
+		L4Proto: unix.IPPROTO_ICMP,
+		DstPort: &nft.PortMatch{Port: 22},
+	})
+	assert.ErrorContains(t, err, "port matching requires the tcp, udp, udplite, sctp or dccp L4 protocol", "expected ports to be rejected for icmp")
+
+	err = batch.NewRule(&nft.Rule{
+		Family:  unix.NFPROTO_INET,
+		Table:   "test-table",
+		Chain:   "test-chain",
+		L4Proto: unix.IPPROTO_TCP,
+		DstPort: &nft.PortMatch{Port: 22, Ports: []uint16{80}},
+	})
+	assert.ErrorContains(t, err, "port match requires exactly one of a port, a range, a list or a set", "expected a port and a port list to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family:  unix.NFPROTO_INET,
+		Table:   "test-table",
+		Chain:   "test-chain",
+		L4Proto: unix.IPPROTO_TCP,
+		DstPort: &nft.PortMatch{Port: 2000, MaxPort: 1000},
+	})
+	assert.ErrorContains(t, err, "port range maximum 1000 must be greater than the minimum 2000", "expected an empty port range to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family:  unix.NFPROTO_INET,
+		Table:   "test-table",
+		Chain:   "test-chain",
+		L4Proto: unix.IPPROTO_TCP,
+		DstPort: &nft.PortMatch{},
+	})
+	assert.ErrorContains(t, err, "port match requires exactly one of a port, a range, a list or a set", "expected an empty port match to be rejected")
+}
+
+func TestRuleICMP(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	portUnreachable := uint8(nft.ICMPCodePortUnreachable)
+
+	want := []*nft.Rule{
+		{
+			ICMP: &nft.ICMPMatch{Type: nft.ICMPTypeEchoRequest},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV4,
+			ICMP: &nft.ICMPMatch{
+				Type: nft.ICMPTypeDestUnreachable,
+				Code: &portUnreachable,
+			},
+		},
+		{
+			ICMPv6: &nft.ICMPv6Match{
+				Types: []nft.ICMPv6Type{
+					nft.ICMPv6TypeNDRouterSolicit,
+					nft.ICMPv6TypeNDRouterAdvert,
+					nft.ICMPv6TypeNDNeighborSolicit,
+					nft.ICMPv6TypeNDNeighborAdvert,
+				},
+			},
+			Action: &nft.Action{
+				Verdict: &nft.Verdict{
+					Code: nft.VerdictCodeAccept,
+				},
+			},
+		},
+		{
+			ICMPv6: &nft.ICMPv6Match{Type: nft.ICMPv6TypePacketTooBig},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, nil, want)
+
+	for i := range want {
+		assert.Equal(t, want[i], got[i], "rule %d", i)
+	}
+}
+
+func TestRuleICMPValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	err := batch.NewRule(&nft.Rule{
+		Family:  unix.NFPROTO_INET,
+		Table:   "test-table",
+		Chain:   "test-chain",
+		L4Proto: unix.IPPROTO_TCP,
+		ICMP:    &nft.ICMPMatch{Type: nft.ICMPTypeEchoRequest},
+	})
+	assert.ErrorContains(t, err, "icmp matching conflicts with L4 protocol 6", "expected icmp to be rejected for tcp")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_IPV4,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		ICMPv6: &nft.ICMPv6Match{Type: nft.ICMPv6TypeEchoRequest},
+	})
+	assert.ErrorContains(t, err, "icmpv6 requires an ipv6 rule", "expected icmpv6 to be rejected in the ipv4 family")
+
+	err = batch.NewRule(&nft.Rule{
+		Family:  unix.NFPROTO_INET,
+		Table:   "test-table",
+		Chain:   "test-chain",
+		ICMP:    &nft.ICMPMatch{Type: nft.ICMPTypeEchoRequest},
+		DstPort: &nft.PortMatch{Port: 22},
+	})
+	assert.ErrorContains(t, err, "port matching requires the tcp, udp, udplite, sctp or dccp L4 protocol", "expected icmp and ports to be rejected")
+}
+
+func TestRuleTCPFlags(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	want := []*nft.Rule{
+		{
+			// New connections.
+			TCPFlags: &nft.TCPFlagsMatch{
+				Flags: nft.TCPFlagSyn,
+				Mask:  nft.TCPFlagSyn | nft.TCPFlagAck | nft.TCPFlagRst | nft.TCPFlagFin,
+			},
+			DstPort: &nft.PortMatch{Port: 22},
+			Limit: &nft.Limit{
+				Rate: 10,
+				Unit: nft.LimitUnitSecond,
+			},
+		},
+		{
+			// XMAS scans.
+			TCPFlags: &nft.TCPFlagsMatch{
+				Flags: nft.TCPFlagFin | nft.TCPFlagPsh | nft.TCPFlagUrg,
+				Mask:  nft.TCPFlagFin | nft.TCPFlagPsh | nft.TCPFlagUrg,
+			},
+			Action: &nft.Action{
+				Verdict: &nft.Verdict{
+					Code: nft.VerdictCodeDrop,
+				},
+			},
+		},
+		{
+			// NULL scans.
+			TCPFlags: &nft.TCPFlagsMatch{},
+			Action: &nft.Action{
+				Verdict: &nft.Verdict{
+					Code: nft.VerdictCodeDrop,
+				},
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, nil, want)
+
+	for i := range want {
+		assert.Equal(t, want[i], got[i], "rule %d", i)
+	}
+}
+
+func TestRuleTCPFlagsValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	err := batch.NewRule(&nft.Rule{
+		Family:   unix.NFPROTO_INET,
+		Table:    "test-table",
+		Chain:    "test-chain",
+		L4Proto:  unix.IPPROTO_UDP,
+		TCPFlags: &nft.TCPFlagsMatch{Flags: nft.TCPFlagSyn},
+	})
+	assert.ErrorContains(t, err, "tcp flags matching requires the tcp L4 protocol", "expected tcp flags to be rejected for udp")
+
+	err = batch.NewRule(&nft.Rule{
+		Family:   unix.NFPROTO_INET,
+		Table:    "test-table",
+		Chain:    "test-chain",
+		ICMP:     &nft.ICMPMatch{Type: nft.ICMPTypeEchoRequest},
+		TCPFlags: &nft.TCPFlagsMatch{Flags: nft.TCPFlagSyn},
+	})
+	assert.ErrorContains(t, err, "tcp flags matching requires the tcp L4 protocol", "expected tcp flags and icmp to be rejected")
+}
+
+func TestRuleNegate(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	batch := nft.NewBatch()
+	// A named set of addresses.
+	setID := batch.NewID()
+	batch.Add(nftnl.Msg{
+		Header: nftnl.Header{
+			SubsysID: unix.NFNL_SUBSYS_NFTABLES,
+			MsgType:  unix.NFT_MSG_NEWSET,
+			Flags:    netlink.Request | netlink.Acknowledge | netlink.Create,
+		},
+		NfGenMsg: nftnl.NfGenMsg{Family: unix.NFPROTO_INET},
+		Attrs: &nftnl.SetAttrs{
+			Table:   testTable,
+			Name:    "trusted",
+			ID:      setID,
+			KeyType: 7,
+			KeyLen:  4,
+		},
+	})
+
+	prefix := netip.MustParsePrefix("10.0.0.0/8")
+	addr := netip.MustParseAddr("2001:db8::1")
+
+	want := []*nft.Rule{
+		{
+			IIface:       "lo",
+			NegateIIface: true,
+		},
+		{
+			L4Proto:       unix.IPPROTO_TCP,
+			NegateL4Proto: true,
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV4,
+			SrcIPv4: &nft.IPMatch{Prefix: &prefix, Negate: true},
+			DstIPv4: &nft.IPMatch{Set: "trusted", SetID: setID, Negate: true},
+		},
+		{
+			L3Proto: unix.NFPROTO_IPV6,
+			DstIPv6: &nft.IPMatch{Addr: &addr, Negate: true},
+		},
+		{
+			L4Proto: unix.IPPROTO_TCP,
+			SrcPort: &nft.PortMatch{Port: 1024, MaxPort: 65535, Negate: true},
+			DstPort: &nft.PortMatch{Port: 22, Negate: true},
+		},
+		{
+			L4Proto: unix.IPPROTO_UDP,
+			DstPort: &nft.PortMatch{Ports: []uint16{53, 123}, Negate: true},
+		},
+		{
+			Ct: &nft.CtMatch{
+				SrcIPv4: &nft.IPMatch{Prefix: &prefix, Negate: true},
+				DstIPv4: &nft.IPMatch{Set: "trusted", SetID: setID, Negate: true},
+			},
+		},
+		{
+			Ct: &nft.CtMatch{
+				DstIPv6: &nft.IPMatch{Addr: &addr, Negate: true},
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, batch, want)
+	// The set ID is not dumped.
+	want[2].DstIPv4.SetID = 0
+	want[6].Ct.DstIPv4.SetID = 0
+
+	for i := range want {
+		assert.Equal(t, want[i], got[i], "rule %d", i)
+	}
+}
+
+func TestRuleNegateValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	err := batch.NewRule(&nft.Rule{
+		Family:        unix.NFPROTO_INET,
+		Table:         "test-table",
+		Chain:         "test-chain",
+		NegateL4Proto: true,
+	})
+	assert.ErrorContains(t, err, "cannot negate an unset L4 protocol", "expected a negated unset L4 protocol to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family:        unix.NFPROTO_INET,
+		Table:         "test-table",
+		Chain:         "test-chain",
+		L4Proto:       unix.IPPROTO_TCP,
+		NegateL4Proto: true,
+		DstPort:       &nft.PortMatch{Port: 22},
+	})
+	assert.ErrorContains(t, err, "port, icmp and tcp flags matching require a non-negated L4 protocol", "expected ports with a negated L4 protocol to be rejected")
+}
+
+func TestRuleIface(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	defaultGroup := uint32(0)
+	group := uint32(10)
+
+	want := []*nft.Rule{
+		{
+			IIface: "veth*",
+		},
+		{
+			IIface:       "eth0",
+			OIface:       "wg*",
+			NegateOIface: true,
+		},
+		{
+			IIfaceMatch: &nft.IfaceMatch{Index: 1},
+		},
+		{
+			IIfaceMatch: &nft.IfaceMatch{
+				Group: &defaultGroup,
+				Type:  unix.ARPHRD_ETHER,
+			},
+		},
+		{
+			OIfaceMatch: &nft.IfaceMatch{Kind: "wireguard"},
+		},
+		{
+			IIfaceMatch: &nft.IfaceMatch{Kind: "veth", Group: &group},
+			OIfaceMatch: &nft.IfaceMatch{Index: 2},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, nil, want)
+
+	for i := range want {
+		assert.Equal(t, want[i], got[i], "rule %d", i)
+	}
+}
+
+func TestRuleIfaceValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	err := batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		IIface: "*",
+	})
+	assert.ErrorContains(t, err, `interface wildcard "*" requires a prefix`, "expected a wildcard without a prefix to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		OIface: "eth*0",
+	})
+	assert.ErrorContains(t, err, `interface name "eth*0" may only end with a wildcard`, "expected a wildcard in the middle of the name to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		IIface: "a-very-long-interface",
+	})
+	assert.ErrorContains(t, err, `interface name "a-very-long-interface" exceeds 15 characters`, "expected a too long interface name to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family:      unix.NFPROTO_INET,
+		Table:       "test-table",
+		Chain:       "test-chain",
+		IIfaceMatch: &nft.IfaceMatch{},
+	})
+	assert.ErrorContains(t, err, "interface match requires an index, a group, a type or a kind", "expected an empty interface match to be rejected")
+}
+
+func TestRuleOwner(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	root, err := nft.LookupUser("root")
+	require.NoError(t, err, "failed to look up the root user")
+	require.Equal(t, uint32(0), root)
+
+	want := []*nft.Rule{
+		{
+			SkUID: &nft.OwnerMatch{ID: root},
+		},
+		{
+			SkUID: &nft.OwnerMatch{ID: 1000, MaxID: 60000},
+			SkGID: &nft.OwnerMatch{ID: 100, Negate: true},
+		},
+		{
+			SkGID: &nft.OwnerMatch{ID: 1, MaxID: 999, Negate: true},
+			Action: &nft.Action{
+				Verdict: &nft.Verdict{
+					Code: nft.VerdictCodeDrop,
+				},
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, nil, want)
+
+	for i := range want {
+		assert.Equal(t, want[i], got[i], "rule %d", i)
+	}
+}
+
+func TestRuleOwnerValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	err := batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		SkUID:  &nft.OwnerMatch{ID: 1000, MaxID: 1000},
+	})
+	assert.ErrorContains(t, err, "owner range maximum 1000 must be greater than the minimum 1000", "expected an empty owner range to be rejected")
+}
+
+func TestNewSocketCgroupV2Match(t *testing.T) {
+	if !*itests {
+		t.SkipNow()
+	}
+
+	root, err := nft.NewSocketCgroupV2Match("/")
+	require.NoError(t, err, "failed to resolve the root cgroup")
+	assert.Equal(t, uint32(0), root.Level)
+
+	// Find the mount point through the ID of the root cgroup.
+	var mount string
+	for _, dir := range []string{"/sys/fs/cgroup", "/sys/fs/cgroup/unified"} {
+		var st unix.Stat_t
+		if unix.Stat(dir, &st) == nil && st.Ino == root.CgroupID {
+			mount = dir
+		}
+	}
+	require.NotEmpty(t, mount, "failed to find the cgroup2 mount point")
+
+	dir := filepath.Join(mount, "go-nft-test", "svc")
+	require.NoError(t, os.MkdirAll(dir, 0o755), "failed to create cgroup")
+	defer os.Remove(filepath.Dir(dir))
+	defer os.Remove(dir)
+
+	var st unix.Stat_t
+	require.NoError(t, unix.Stat(dir, &st))
+
+	m, err := nft.NewSocketCgroupV2Match("/go-nft-test/svc/")
+	require.NoError(t, err, "failed to resolve cgroup")
+	assert.Equal(t, &nft.SocketMatch{
+		Key:      nft.SocketKeyCgroupV2,
+		Level:    2,
+		CgroupID: st.Ino,
+	}, m)
+
+	_, err = nft.NewSocketCgroupV2Match("/go-nft-test/missing")
+	assert.ErrorIs(t, err, unix.ENOENT, "expected a missing cgroup to be rejected")
+}
+
+func TestRuleTime(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	cest := time.FixedZone("CEST", 2*60*60)
+
+	want := []*nft.Rule{
+		{
+			Time: &nft.TimeMatch{
+				After:  time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+				Before: time.Date(2024, time.December, 31, 23, 59, 59, 0, time.UTC),
+			},
+		},
+		{
+			Time: &nft.TimeMatch{
+				After: time.Date(2030, time.June, 1, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			Time: &nft.TimeMatch{
+				Days: []time.Weekday{time.Sunday, time.Saturday},
+			},
+		},
+		{
+			Time: &nft.TimeMatch{
+				Days:     []time.Weekday{time.Monday},
+				HourFrom: 8 * time.Hour,
+				HourTo:   17*time.Hour + 30*time.Minute,
+				Location: time.UTC,
+			},
+		},
+		{
+			// Wraps past midnight.
+			Time: &nft.TimeMatch{
+				HourFrom: 22 * time.Hour,
+				HourTo:   6 * time.Hour,
+				Location: time.UTC,
+			},
+		},
+		{
+			Time: &nft.TimeMatch{
+				HourFrom: 8 * time.Hour,
+				HourTo:   18 * time.Hour,
+				Location: cest,
+			},
+		},
+		{
+			// Wraps past midnight in UTC only.
+			Time: &nft.TimeMatch{
+				HourFrom: 1 * time.Hour,
+				HourTo:   5 * time.Hour,
+				Location: cest,
+			},
+		},
+		{
+			// The previous weekdays in UTC.
+			Time: &nft.TimeMatch{
+				Days:     []time.Weekday{time.Monday, time.Friday},
+				HourFrom: 30 * time.Minute,
+				HourTo:   1*time.Hour + 30*time.Minute,
+				Location: cest,
+			},
+		},
+	}
+
+	got := roundTripRules(t, conn, unix.NFPROTO_INET, nil, want)
+	// Dumped rules have their hours in UTC.
+	want[5].Time.HourFrom, want[5].Time.HourTo, want[5].Time.Location = 6*time.Hour, 16*time.Hour, time.UTC
+	want[6].Time.HourFrom, want[6].Time.HourTo, want[6].Time.Location = 23*time.Hour, 3*time.Hour, time.UTC
+	want[7].Time.HourFrom, want[7].Time.HourTo, want[7].Time.Location = 22*time.Hour+30*time.Minute, 23*time.Hour+30*time.Minute, time.UTC
+	want[7].Time.Days = []time.Weekday{time.Sunday, time.Thursday}
+
+	for i := range want {
+		assert.Equal(t, want[i], got[i], "rule %d", i)
+	}
+}
+
+func TestRuleTimeValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	err := batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Time:   &nft.TimeMatch{},
+	})
+	assert.ErrorContains(t, err, "time match requires a range of time, days or hours", "expected an empty time match to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Time: &nft.TimeMatch{
+			After:  time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC),
+			Before: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+	})
+	assert.ErrorContains(t, err, "time range ends at", "expected a time range ending before it starts to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Time:   &nft.TimeMatch{Days: []time.Weekday{time.Monday, time.Monday}},
+	})
+	assert.ErrorContains(t, err, "weekday Monday is listed more than once", "expected duplicate weekdays to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Time:   &nft.TimeMatch{HourFrom: 8 * time.Hour, HourTo: 24 * time.Hour},
+	})
+	assert.ErrorContains(t, err, "hour 24h0m0s is outside of a day", "expected an hour outside of a day to be rejected")
+
+	cest := time.FixedZone("CEST", 2*60*60)
+	for _, m := range []*nft.TimeMatch{
+		{Days: []time.Weekday{time.Monday}, HourFrom: 1 * time.Hour, HourTo: 5 * time.Hour, Location: cest},
+		{Days: []time.Weekday{time.Monday}, HourFrom: 22 * time.Hour, HourTo: 6 * time.Hour, Location: cest},
+	} {
+		err = batch.NewRule(&nft.Rule{
+			Family: unix.NFPROTO_INET,
+			Table:  "test-table",
+			Chain:  "test-chain",
+			Time:   m,
+		})
+		assert.ErrorContains(t, err, "span two weekdays in UTC and cannot be matched along with days", "expected days with hours spanning two weekdays in UTC to be rejected")
+	}
+}
+
+func TestRuleEther(t *testing.T) {
+	conn, closer := OpenSystemConn(t)
+	defer closer()
+
+	src := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+	dst := net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	senderIP := netip.MustParseAddr("192.0.2.1")
+	targetIP := netip.MustParseAddr("192.0.2.2")
+	id10, id100 := uint16(10), uint16(100)
+	pcp := uint8(5)
+	dei := false
+	broadcast := nft.PacketTypeBroadcast
+
+	tests := []struct {
+		family uint8
+		want   []*nft.Rule
+	}{
+		{
+			family: unix.NFPROTO_BRIDGE,
+			want: []*nft.Rule{
+				{
+					Ether: &nft.EtherMatch{Src: src, Dst: dst},
+				},
+				{
+					Ether: &nft.EtherMatch{Type: unix.ETH_P_IPV6},
+					VLAN:  &nft.VLANMatch{ID: &id10, PCP: &pcp},
+				},
+				{
+					// QinQ.
+					Ether:       &nft.EtherMatch{Type: unix.ETH_P_IP},
+					ServiceVLAN: &nft.VLANMatch{ID: &id100},
+					VLAN:        &nft.VLANMatch{ID: &id10, DEI: &dei},
+				},
+				{
+					VLAN: &nft.VLANMatch{},
+				},
+				{
+					ARP: &nft.ARPMatch{
+						Op:       nft.ARPOpRequest,
+						SenderIP: &senderIP,
+						TargetHW: dst,
+					},
+				},
+				{
+					PktType: &broadcast,
+					Action: &nft.Action{
+						Verdict: &nft.Verdict{
+							Code: nft.VerdictCodeDrop,
+						},
+					},
+				},
+			},
+		},
+		{
+			family: unix.NFPROTO_NETDEV,
+			want: []*nft.Rule{
+				{
+					Ether: &nft.EtherMatch{Dst: dst},
+				},
+				{
+					ARP: &nft.ARPMatch{Op: nft.ARPOpReply, SenderHW: src},
+				},
+			},
+		},
+		{
+			family: unix.NFPROTO_ARP,
+			want: []*nft.Rule{
+				{
+					ARP: &nft.ARPMatch{Op: nft.ARPOpRequest, TargetIP: &targetIP},
+				},
+				{
+					Ether: &nft.EtherMatch{Src: src},
+					ARP:   &nft.ARPMatch{SenderHW: src},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got := roundTripRules(t, conn, tt.family, nil, tt.want)
+
+		for i := range tt.want {
+			assert.Equal(t, tt.want[i], got[i], "family %d rule %d", tt.family, i)
+		}
+	}
+}
+
+func TestRuleEtherValidation(t *testing.T) {
+	batch := nft.NewBatch()
+
+	err := batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_BRIDGE,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Ether:  &nft.EtherMatch{Src: net.HardwareAddr{0x02, 0x00}},
+	})
+	assert.ErrorContains(t, err, "ether address 02:00 is not a MAC-48 address", "expected a short MAC address to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_BRIDGE,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		Ether:  &nft.EtherMatch{Type: unix.ETH_P_8021Q},
+	})
+	assert.ErrorContains(t, err, "VLAN tags must be matched with VLAN and ServiceVLAN", "expected a VLAN ethertype to be rejected")
+
+	id := uint16(4096)
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_BRIDGE,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		VLAN:   &nft.VLANMatch{ID: &id},
+	})
+	assert.ErrorContains(t, err, "VLAN ID 4096 exceeds 4095", "expected an out of range VLAN ID to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family:      unix.NFPROTO_NETDEV,
+		Table:       "test-table",
+		Chain:       "test-chain",
+		IIfaceMatch: &nft.IfaceMatch{Type: unix.ARPHRD_LOOPBACK},
+		Ether:       &nft.EtherMatch{Type: unix.ETH_P_IP},
+	})
+	assert.ErrorContains(t, err, "ether matching requires an Ethernet input interface", "expected ether matching on a non-Ethernet interface to be rejected")
+
+	err = batch.NewRule(&nft.Rule{
+		Family: unix.NFPROTO_INET,
+		Table:  "test-table",
+		Chain:  "test-chain",
+		ARP:    &nft.ARPMatch{Op: nft.ARPOpRequest},
+	})
+	assert.ErrorContains(t, err, "arp is only supported in the arp, bridge and netdev families", "expected arp matching to be rejected in the inet family")
+}
@@ -13,7 +13,17 @@ type Attrs interface {
 	unmarshal(data []byte) error
 }
 
-func attrFactory(msgType uint16) (Attrs, error) {
+func attrFactory(subsysID uint16, msgType uint16) (Attrs, error) {
+	switch subsysID {
+	case unix.NFNL_SUBSYS_NFTABLES:
+	case unix.NFNL_SUBSYS_ULOG:
+		return nflogAttrFactory(msgType)
+	case unix.NFNL_SUBSYS_QUEUE:
+		return nfqueueAttrFactory(msgType)
+	default:
+		return nil, fmt.Errorf("unknown subsystem %d", subsysID)
+	}
+
 	switch msgType {
 	case unix.NFT_MSG_NEWTABLE, unix.NFT_MSG_GETTABLE, unix.NFT_MSG_DELTABLE:
 		return &TableAttrs{}, nil
@@ -21,8 +31,14 @@ func attrFactory(msgType uint16) (Attrs, error) {
 		return &ChainAttrs{}, nil
 	case unix.NFT_MSG_NEWRULE, unix.NFT_MSG_GETRULE, unix.NFT_MSG_DELRULE:
 		return &RuleAttrs{}, nil
+	case unix.NFT_MSG_NEWSET, unix.NFT_MSG_GETSET, unix.NFT_MSG_DELSET:
+		return &SetAttrs{}, nil
+	case unix.NFT_MSG_NEWSETELEM, unix.NFT_MSG_GETSETELEM, unix.NFT_MSG_DELSETELEM:
+		return &SetElemListAttrs{}, nil
 	case unix.NFT_MSG_NEWGEN, unix.NFT_MSG_GETGEN:
 		return &GenAttrs{}, nil
+	case unix.NFT_MSG_NEWOBJ, unix.NFT_MSG_GETOBJ, unix.NFT_MSG_DELOBJ:
+		return &ObjAttrs{}, nil
 	default:
 		return nil, fmt.Errorf("unknown message type %d", msgType)
 	}
@@ -0,0 +1,53 @@
+package nftnl
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type ByteorderAttrs struct {
+	SReg uint32
+	DReg uint32
+	Op   uint32
+	Len  uint32
+	Size uint32
+}
+
+func (a ByteorderAttrs) ExprName() string {
+	return "byteorder"
+}
+
+func (a *ByteorderAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.Uint32(unix.NFTA_BYTEORDER_SREG, a.SReg)
+	ae.Uint32(unix.NFTA_BYTEORDER_DREG, a.DReg)
+	ae.Uint32(unix.NFTA_BYTEORDER_OP, a.Op)
+	ae.Uint32(unix.NFTA_BYTEORDER_LEN, a.Len)
+	ae.Uint32(unix.NFTA_BYTEORDER_SIZE, a.Size)
+
+	return ae.Encode()
+}
+
+func (a *ByteorderAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_BYTEORDER_SREG:
+			a.SReg = ad.Uint32()
+		case unix.NFTA_BYTEORDER_DREG:
+			a.DReg = ad.Uint32()
+		case unix.NFTA_BYTEORDER_OP:
+			a.Op = ad.Uint32()
+		case unix.NFTA_BYTEORDER_LEN:
+			a.Len = ad.Uint32()
+		case unix.NFTA_BYTEORDER_SIZE:
+			a.Size = ad.Uint32()
+		}
+	}
+
+	return nil
+}
@@ -84,6 +84,9 @@ func (a *ChainAttrs) marshal() ([]byte, error) {
 		ae.Bytes(unix.NLA_F_NESTED|unix.NFTA_CHAIN_HOOK, hook)
 		ae.Uint32(unix.NFTA_CHAIN_POLICY, a.Policy)
 	}
+	if a.Type != "" {
+		ae.String(unix.NFTA_CHAIN_TYPE, a.Type)
+	}
 
 	return ae.Encode()
 }
@@ -109,10 +112,12 @@ func (a *ChainAttrs) unmarshal(data []byte) error {
 		case unix.NFTA_CHAIN_TYPE:
 			a.Type = ad.String()
 		case unix.NFTA_CHAIN_COUNTERS:
+			a.Counters = &CounterAttrs{}
 			if err := a.Counters.unmarshal(ad.Bytes()); err != nil {
 				return err
 			}
 		case unix.NFTA_CHAIN_HOOK:
+			a.Hook = &HookAttrs{}
 			if err := a.Hook.unmarshal(ad.Bytes()); err != nil {
 				return err
 			}
@@ -52,18 +52,7 @@ func (c *Conn) receive() ([]Msg, error) {
 			firstErr = err
 		}
 
-		for _, m := range res {
-			// Filter out non-nftables messages.
-			// In practice, this would only be netlink.Error messages.
-			// Those are handled by the netlink library itself and should be reported
-			// as errors by nlconn.Receive().
-			subsystem := m.Header.Type >> 8
-			if subsystem != unix.NFNL_SUBSYS_NFTABLES {
-				continue
-			}
-
-			replies = append(replies, m)
-		}
+		replies = append(replies, filterControlMessages(res)...)
 	}
 
 	if firstErr != nil {
@@ -85,6 +74,47 @@ func (c *Conn) Send(msg Msg) ([]Msg, error) {
 	return c.receive()
 }
 
+// Receive blocks until the kernel sends one or more messages and returns
+// them. It is meant for connections subscribed to netfilter events, such as
+// NFLOG groups, and must not be used concurrently with Send.
+func (c *Conn) Receive() ([]Msg, error) {
+	res, err := c.nlconn.Receive()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.unmarshalNetlinkMessages(filterControlMessages(res))
+}
+
+// Write sends msg without waiting for a reply.
+func (c *Conn) Write(msg Msg) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	nlMsg, err := msg.marshal()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.nlconn.Send(nlMsg)
+	return err
+}
+
+// filterControlMessages drops netlink control messages such as
+// acknowledgements. In practice, those would only be netlink.Error messages,
+// which are handled by the netlink library itself and reported as errors by
+// nlconn.Receive().
+func filterControlMessages(msgs []netlink.Message) []netlink.Message {
+	var filtered []netlink.Message
+	for _, m := range msgs {
+		if m.Header.Type < unix.NLMSG_MIN_TYPE {
+			continue
+		}
+		filtered = append(filtered, m)
+	}
+	return filtered
+}
+
 func (c *Conn) sendMessages(msgs []Msg) ([]Msg, error) {
 	nlMsgs, err := c.marshalNetlinkMessages(msgs)
 	if err != nil {
@@ -0,0 +1,43 @@
+package nftnl
+
+import (
+	"github.com/nickgarlis/go-nft/unixext"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type ConnlimitAttrs struct {
+	Count uint32
+	Flags uint32
+}
+
+func (a ConnlimitAttrs) ExprName() string {
+	return "connlimit"
+}
+
+func (a *ConnlimitAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.Uint32(unixext.NFTA_CONNLIMIT_COUNT, a.Count)
+	if a.Flags > 0 {
+		ae.Uint32(unixext.NFTA_CONNLIMIT_FLAGS, a.Flags)
+	}
+
+	return ae.Encode()
+}
+
+func (a *ConnlimitAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFTA_CONNLIMIT_COUNT:
+			a.Count = ad.Uint32()
+		case unixext.NFTA_CONNLIMIT_FLAGS:
+			a.Flags = ad.Uint32()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package nftnl
+
+import (
+	"github.com/nickgarlis/go-nft/unixext"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type CtExpectAttrs struct {
+	L3Proto uint16
+	L4Proto uint8
+	DPort   uint16
+	// Timeout in milliseconds.
+	Timeout uint32
+	Size    uint8
+}
+
+func (a CtExpectAttrs) ExprName() string {
+	return "ct_expect"
+}
+
+func (a *CtExpectAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	if a.L3Proto > 0 {
+		ae.Uint16(unixext.NFTA_CT_EXPECT_L3PROTO, a.L3Proto)
+	}
+	ae.Uint8(unixext.NFTA_CT_EXPECT_L4PROTO, a.L4Proto)
+	ae.Uint16(unixext.NFTA_CT_EXPECT_DPORT, a.DPort)
+	ae.Uint32(unixext.NFTA_CT_EXPECT_TIMEOUT, a.Timeout)
+	ae.Uint8(unixext.NFTA_CT_EXPECT_SIZE, a.Size)
+
+	return ae.Encode()
+}
+
+func (a *CtExpectAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFTA_CT_EXPECT_L3PROTO:
+			a.L3Proto = ad.Uint16()
+		case unixext.NFTA_CT_EXPECT_L4PROTO:
+			a.L4Proto = ad.Uint8()
+		case unixext.NFTA_CT_EXPECT_DPORT:
+			a.DPort = ad.Uint16()
+		case unixext.NFTA_CT_EXPECT_TIMEOUT:
+			a.Timeout = ad.Uint32()
+		case unixext.NFTA_CT_EXPECT_SIZE:
+			a.Size = ad.Uint8()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package nftnl
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type CtHelperAttrs struct {
+	Name    string
+	L3Proto uint16
+	L4Proto uint8
+}
+
+func (a CtHelperAttrs) ExprName() string {
+	return "ct_helper"
+}
+
+func (a *CtHelperAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.String(unix.NFTA_CT_HELPER_NAME, a.Name)
+	if a.L3Proto > 0 {
+		ae.Uint16(unix.NFTA_CT_HELPER_L3PROTO, a.L3Proto)
+	}
+	ae.Uint8(unix.NFTA_CT_HELPER_L4PROTO, a.L4Proto)
+
+	return ae.Encode()
+}
+
+func (a *CtHelperAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_CT_HELPER_NAME:
+			a.Name = ad.String()
+		case unix.NFTA_CT_HELPER_L3PROTO:
+			a.L3Proto = ad.Uint16()
+		case unix.NFTA_CT_HELPER_L4PROTO:
+			a.L4Proto = ad.Uint8()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package nftnl
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/mdlayher/netlink"
+	"github.com/nickgarlis/go-nft/unixext"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type CtTimeoutAttrs struct {
+	L3Proto uint16
+	L4Proto uint8
+	// Timeouts maps the CTA_TIMEOUT_* attributes of the L4 protocol to
+	// timeouts in seconds.
+	Timeouts map[uint16]uint32
+}
+
+func (a CtTimeoutAttrs) ExprName() string {
+	return "ct_timeout"
+}
+
+func (a *CtTimeoutAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	if a.L3Proto > 0 {
+		ae.Uint16(unixext.NFTA_CT_TIMEOUT_L3PROTO, a.L3Proto)
+	}
+	ae.Uint8(unixext.NFTA_CT_TIMEOUT_L4PROTO, a.L4Proto)
+	ae.Nested(unixext.NFTA_CT_TIMEOUT_DATA, func(nae *netlink.AttributeEncoder) error {
+		for _, typ := range slices.Sorted(maps.Keys(a.Timeouts)) {
+			nae.Uint32(typ, a.Timeouts[typ])
+		}
+		return nil
+	})
+
+	return ae.Encode()
+}
+
+func (a *CtTimeoutAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFTA_CT_TIMEOUT_L3PROTO:
+			a.L3Proto = ad.Uint16()
+		case unixext.NFTA_CT_TIMEOUT_L4PROTO:
+			a.L4Proto = ad.Uint8()
+		case unixext.NFTA_CT_TIMEOUT_DATA:
+			a.Timeouts = make(map[uint16]uint32)
+			ad.Nested(func(nad *netlink.AttributeDecoder) error {
+				for nad.Next() {
+					a.Timeouts[nad.Type()] = nad.Uint32()
+				}
+				return nil
+			})
+		}
+	}
+
+	return ad.Err()
+}
@@ -0,0 +1,45 @@
+package nftnl
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type DupAttrs struct {
+	SRegAddr uint32
+	SRegDev  uint32
+}
+
+func (a DupAttrs) ExprName() string {
+	return "dup"
+}
+
+func (a *DupAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	if a.SRegAddr > 0 {
+		ae.Uint32(unix.NFTA_DUP_SREG_ADDR, a.SRegAddr)
+	}
+	if a.SRegDev > 0 {
+		ae.Uint32(unix.NFTA_DUP_SREG_DEV, a.SRegDev)
+	}
+
+	return ae.Encode()
+}
+
+func (a *DupAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_DUP_SREG_ADDR:
+			a.SRegAddr = ad.Uint32()
+		case unix.NFTA_DUP_SREG_DEV:
+			a.SRegDev = ad.Uint32()
+		}
+	}
+
+	return nil
+}
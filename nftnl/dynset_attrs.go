@@ -0,0 +1,101 @@
+package nftnl
+
+import (
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type DynsetAttrs struct {
+	SetName  string
+	SetID    uint32
+	Op       uint32
+	SRegKey  uint32
+	SRegData uint32
+	// Timeout of the added elements, in milliseconds.
+	Timeout uint64
+	// Expressions are attached to the elements and evaluated for every
+	// packet that adds or updates them.
+	Expressions []ExprDataAttrs
+	Flags       uint32
+}
+
+func (a DynsetAttrs) ExprName() string {
+	return "dynset"
+}
+
+func (a *DynsetAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.String(unix.NFTA_DYNSET_SET_NAME, a.SetName)
+	if a.SetID > 0 {
+		ae.Uint32(unix.NFTA_DYNSET_SET_ID, a.SetID)
+	}
+	ae.Uint32(unix.NFTA_DYNSET_OP, a.Op)
+	ae.Uint32(unix.NFTA_DYNSET_SREG_KEY, a.SRegKey)
+	if a.SRegData > 0 {
+		ae.Uint32(unix.NFTA_DYNSET_SREG_DATA, a.SRegData)
+	}
+	if a.Timeout > 0 {
+		ae.Uint64(unix.NFTA_DYNSET_TIMEOUT, a.Timeout)
+	}
+	if a.Flags > 0 {
+		ae.Uint32(unix.NFTA_DYNSET_FLAGS, a.Flags)
+	}
+	switch len(a.Expressions) {
+	case 0:
+	case 1:
+		expr, err := marshalExprData(a.Expressions[0])
+		if err != nil {
+			return nil, err
+		}
+		ae.Bytes(unix.NLA_F_NESTED|unix.NFTA_DYNSET_EXPR, expr)
+	default:
+		exprs, err := marshalExprDataList(a.Expressions)
+		if err != nil {
+			return nil, err
+		}
+		ae.Bytes(unix.NLA_F_NESTED|unixext.NFTA_DYNSET_EXPRESSIONS, exprs)
+	}
+
+	return ae.Encode()
+}
+
+func (a *DynsetAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_DYNSET_SET_NAME:
+			a.SetName = ad.String()
+		case unix.NFTA_DYNSET_SET_ID:
+			a.SetID = ad.Uint32()
+		case unix.NFTA_DYNSET_OP:
+			a.Op = ad.Uint32()
+		case unix.NFTA_DYNSET_SREG_KEY:
+			a.SRegKey = ad.Uint32()
+		case unix.NFTA_DYNSET_SREG_DATA:
+			a.SRegData = ad.Uint32()
+		case unix.NFTA_DYNSET_TIMEOUT:
+			a.Timeout = ad.Uint64()
+		case unix.NFTA_DYNSET_FLAGS:
+			a.Flags = ad.Uint32()
+		case unix.NFTA_DYNSET_EXPR:
+			expr, err := unmarshalExprData(ad.Bytes())
+			if err != nil {
+				return err
+			}
+			a.Expressions = []ExprDataAttrs{expr}
+		case unixext.NFTA_DYNSET_EXPRESSIONS:
+			exprs, err := unmarshalExprDataList(ad.Bytes())
+			if err != nil {
+				return err
+			}
+			a.Expressions = exprs
+		}
+	}
+
+	return nil
+}
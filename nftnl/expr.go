@@ -51,6 +51,16 @@ func (a *ExprAttrs) unmarshal(data []byte) error {
 		}
 	}
 
+	// Expressions without attributes, such as notrack, are dumped without
+	// NFTA_EXPR_DATA.
+	if a.Data == nil && a.Name != "" {
+		exprData, err := exprDataFactory(a.Name)
+		if err != nil {
+			return err
+		}
+		a.Data = exprData
+	}
+
 	return nil
 }
 
@@ -58,46 +68,136 @@ func exprDataFactory(name string) (ExprDataAttrs, error) {
 	switch name {
 	case "bitwise":
 		return &BitwiseAttrs{}, nil
+	case "byteorder":
+		return &ByteorderAttrs{}, nil
 	case "cmp":
 		return &CmpAttrs{}, nil
+	case "connlimit":
+		return &ConnlimitAttrs{}, nil
 	case "counter":
 		return &CounterAttrs{}, nil
 	case "ct":
 		return &CtAttrs{}, nil
-	case "immediage":
+	case "dup":
+		return &DupAttrs{}, nil
+	case "dynset":
+		return &DynsetAttrs{}, nil
+	case "exthdr":
+		return &ExthdrAttrs{}, nil
+	case "fib":
+		return &FibAttrs{}, nil
+	case "fwd":
+		return &FwdAttrs{}, nil
+	case "hash":
+		return &HashAttrs{}, nil
+	case "immediate":
 		return &ImmediateAttrs{}, nil
+	case "inner":
+		return &InnerAttrs{}, nil
+	case "last":
+		return &LastAttrs{}, nil
+	case "limit":
+		return &LimitAttrs{}, nil
+	case "log":
+		return &LogAttrs{}, nil
+	case "lookup":
+		return &LookupAttrs{}, nil
+	case "match":
+		return &MatchAttrs{}, nil
 	case "meta":
 		return &MetaAttrs{}, nil
+	case "nat":
+		return &NatAttrs{}, nil
+	case "notrack":
+		return &NotrackAttrs{}, nil
+	case "numgen":
+		return &NumgenAttrs{}, nil
+	case "objref":
+		return &ObjrefAttrs{}, nil
+	case "osf":
+		return &OsfAttrs{}, nil
 	case "payload":
 		return &PayloadAttrs{}, nil
+	case "queue":
+		return &QueueAttrs{}, nil
+	case "range":
+		return &RangeAttrs{}, nil
+	case "reject":
+		return &RejectAttrs{}, nil
+	case "rt":
+		return &RtAttrs{}, nil
+	case "socket":
+		return &SocketAttrs{}, nil
+	case "synproxy":
+		return &SynproxyAttrs{}, nil
+	case "target":
+		return &TargetAttrs{}, nil
+	case "tproxy":
+		return &TproxyAttrs{}, nil
+	case "tunnel":
+		return &TunnelAttrs{}, nil
 	case "verdict":
 		return &VerdictAttrs{}, nil
+	case "xfrm":
+		return &XfrmAttrs{}, nil
 	default:
 		return nil, fmt.Errorf("unknown expr name %q", name)
 	}
 }
 
 func unmarshalExpr(data []byte) (*ExprAttrs, error) {
+	exprAttrs := &ExprAttrs{}
+	if err := exprAttrs.unmarshal(data); err != nil {
+		return nil, err
+	}
+	return exprAttrs, nil
+}
+
+// marshalExprData encodes a single expression nested in another attribute,
+// such as the expression attached to the elements of a set.
+func marshalExprData(data ExprDataAttrs) ([]byte, error) {
+	expr := &ExprAttrs{Name: data.ExprName(), Data: data}
+	return expr.marshal()
+}
+
+func unmarshalExprData(data []byte) (ExprDataAttrs, error) {
+	expr := &ExprAttrs{}
+	if err := expr.unmarshal(data); err != nil {
+		return nil, err
+	}
+	return expr.Data, nil
+}
+
+// marshalExprDataList encodes expressions as a list of NFTA_LIST_ELEM
+// attributes.
+func marshalExprDataList(exprs []ExprDataAttrs) ([]byte, error) {
+	ae := NewAttributeEncoder()
+	for _, data := range exprs {
+		expr, err := marshalExprData(data)
+		if err != nil {
+			return nil, err
+		}
+		ae.Bytes(unix.NLA_F_NESTED|unix.NFTA_LIST_ELEM, expr)
+	}
+	return ae.Encode()
+}
+
+func unmarshalExprDataList(data []byte) ([]ExprDataAttrs, error) {
 	ad, err := NewAttributeDecoder(data)
 	if err != nil {
 		return nil, err
 	}
 
-	var exprAttrs = &ExprAttrs{}
-
+	var exprs []ExprDataAttrs
 	for ad.Next() {
-		switch ad.Type() {
-		case unix.NFTA_EXPR_NAME:
-			exprAttrs.Name = ad.String()
-		case unix.NFTA_EXPR_DATA:
-			exprData, err := exprDataFactory(exprAttrs.Name)
-			if err != nil {
-				return nil, err
-			}
-			exprData.unmarshal(ad.Bytes())
-			exprAttrs.Data = exprData
+		if ad.Type() != unix.NFTA_LIST_ELEM {
+			continue
 		}
+		expr, err := unmarshalExprData(ad.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
 	}
-
-	return exprAttrs, nil
+	return exprs, nil
 }
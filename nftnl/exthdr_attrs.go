@@ -0,0 +1,69 @@
+package nftnl
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type ExthdrAttrs struct {
+	DReg   uint32
+	Type   uint8
+	Offset uint32
+	Len    uint32
+	Flags  uint32
+	Op     uint32
+	SReg   uint32
+}
+
+func (a ExthdrAttrs) ExprName() string {
+	return "exthdr"
+}
+
+func (a *ExthdrAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	if a.DReg > 0 {
+		ae.Uint32(unix.NFTA_EXTHDR_DREG, a.DReg)
+	}
+	if a.SReg > 0 {
+		ae.Uint32(unix.NFTA_EXTHDR_SREG, a.SReg)
+	}
+	ae.Uint8(unix.NFTA_EXTHDR_TYPE, a.Type)
+	ae.Uint32(unix.NFTA_EXTHDR_OFFSET, a.Offset)
+	ae.Uint32(unix.NFTA_EXTHDR_LEN, a.Len)
+	if a.Flags > 0 {
+		ae.Uint32(unix.NFTA_EXTHDR_FLAGS, a.Flags)
+	}
+	if a.Op > 0 {
+		ae.Uint32(unix.NFTA_EXTHDR_OP, a.Op)
+	}
+
+	return ae.Encode()
+}
+
+func (a *ExthdrAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_EXTHDR_DREG:
+			a.DReg = ad.Uint32()
+		case unix.NFTA_EXTHDR_TYPE:
+			a.Type = ad.Uint8()
+		case unix.NFTA_EXTHDR_OFFSET:
+			a.Offset = ad.Uint32()
+		case unix.NFTA_EXTHDR_LEN:
+			a.Len = ad.Uint32()
+		case unix.NFTA_EXTHDR_FLAGS:
+			a.Flags = ad.Uint32()
+		case unix.NFTA_EXTHDR_OP:
+			a.Op = ad.Uint32()
+		case unix.NFTA_EXTHDR_SREG:
+			a.SReg = ad.Uint32()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package nftnl
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type FibAttrs struct {
+	DReg   uint32
+	Result uint32
+	Flags  uint32
+}
+
+func (a FibAttrs) ExprName() string {
+	return "fib"
+}
+
+func (a *FibAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.Uint32(unix.NFTA_FIB_DREG, a.DReg)
+	ae.Uint32(unix.NFTA_FIB_RESULT, a.Result)
+	ae.Uint32(unix.NFTA_FIB_FLAGS, a.Flags)
+
+	return ae.Encode()
+}
+
+func (a *FibAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_FIB_DREG:
+			a.DReg = ad.Uint32()
+		case unix.NFTA_FIB_RESULT:
+			a.Result = ad.Uint32()
+		case unix.NFTA_FIB_FLAGS:
+			a.Flags = ad.Uint32()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package nftnl
+
+import (
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type FwdAttrs struct {
+	SRegDev  uint32
+	SRegAddr uint32
+	NFProto  uint32
+}
+
+func (a FwdAttrs) ExprName() string {
+	return "fwd"
+}
+
+func (a *FwdAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.Uint32(unix.NFTA_FWD_SREG_DEV, a.SRegDev)
+	if a.SRegAddr > 0 {
+		ae.Uint32(unixext.NFTA_FWD_SREG_ADDR, a.SRegAddr)
+		ae.Uint32(unixext.NFTA_FWD_NFPROTO, a.NFProto)
+	}
+
+	return ae.Encode()
+}
+
+func (a *FwdAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_FWD_SREG_DEV:
+			a.SRegDev = ad.Uint32()
+		case unixext.NFTA_FWD_SREG_ADDR:
+			a.SRegAddr = ad.Uint32()
+		case unixext.NFTA_FWD_NFPROTO:
+			a.NFProto = ad.Uint32()
+		}
+	}
+
+	return nil
+}
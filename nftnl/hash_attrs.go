@@ -0,0 +1,71 @@
+package nftnl
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type HashAttrs struct {
+	SReg    uint32
+	DReg    uint32
+	Len     uint32
+	Modulus uint32
+	// Seed of the jenkins hash. The kernel generates a random seed when it
+	// is not set.
+	Seed   uint32
+	Offset uint32
+	Type   uint32
+}
+
+func (a HashAttrs) ExprName() string {
+	return "hash"
+}
+
+func (a *HashAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	if a.SReg > 0 {
+		ae.Uint32(unix.NFTA_HASH_SREG, a.SReg)
+	}
+	ae.Uint32(unix.NFTA_HASH_DREG, a.DReg)
+	if a.Len > 0 {
+		ae.Uint32(unix.NFTA_HASH_LEN, a.Len)
+	}
+	ae.Uint32(unix.NFTA_HASH_MODULUS, a.Modulus)
+	if a.Seed > 0 {
+		ae.Uint32(unix.NFTA_HASH_SEED, a.Seed)
+	}
+	if a.Offset > 0 {
+		ae.Uint32(unix.NFTA_HASH_OFFSET, a.Offset)
+	}
+	ae.Uint32(unix.NFTA_HASH_TYPE, a.Type)
+
+	return ae.Encode()
+}
+
+func (a *HashAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_HASH_SREG:
+			a.SReg = ad.Uint32()
+		case unix.NFTA_HASH_DREG:
+			a.DReg = ad.Uint32()
+		case unix.NFTA_HASH_LEN:
+			a.Len = ad.Uint32()
+		case unix.NFTA_HASH_MODULUS:
+			a.Modulus = ad.Uint32()
+		case unix.NFTA_HASH_SEED:
+			a.Seed = ad.Uint32()
+		case unix.NFTA_HASH_OFFSET:
+			a.Offset = ad.Uint32()
+		case unix.NFTA_HASH_TYPE:
+			a.Type = ad.Uint32()
+		}
+	}
+
+	return nil
+}
@@ -34,6 +34,13 @@ func (a *ImmediateAttrs) marshal() ([]byte, error) {
 				nae.Bytes(unix.NLA_F_NESTED|unix.NFTA_DATA_VERDICT, data)
 				return nil
 			})
+		case *DataAttrs:
+			ae.Uint32(unix.NFTA_IMMEDIATE_DREG, a.DReg)
+			data, err := a.Data.marshal()
+			if err != nil {
+				return nil, err
+			}
+			ae.Bytes(unix.NLA_F_NESTED|unix.NFTA_IMMEDIATE_DATA, data)
 		default:
 			return nil, fmt.Errorf("unsupported immediate data expr type %T", a.Data)
 		}
@@ -61,6 +68,8 @@ func (a *ImmediateAttrs) unmarshal(data []byte) error {
 						if err := a.Data.unmarshal(nad.Bytes()); err != nil {
 							return err
 						}
+					case unix.NFTA_DATA_VALUE:
+						a.Data = &DataAttrs{Value: nad.Bytes()}
 					default:
 						return fmt.Errorf("unsupported immediate data expr attr type %d", nad.Type())
 					}
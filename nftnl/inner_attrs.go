@@ -0,0 +1,65 @@
+package nftnl
+
+import (
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type InnerAttrs struct {
+	Num     uint32
+	Type    uint32
+	Flags   uint32
+	HdrSize uint32
+	// Expr is evaluated against the encapsulated headers. The kernel only
+	// accepts payload and meta loads.
+	Expr ExprDataAttrs
+}
+
+func (a InnerAttrs) ExprName() string {
+	return "inner"
+}
+
+func (a *InnerAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.Uint32(unixext.NFTA_INNER_NUM, a.Num)
+	ae.Uint32(unixext.NFTA_INNER_TYPE, a.Type)
+	ae.Uint32(unixext.NFTA_INNER_FLAGS, a.Flags)
+	ae.Uint32(unixext.NFTA_INNER_HDRSIZE, a.HdrSize)
+	if a.Expr != nil {
+		expr, err := marshalExprData(a.Expr)
+		if err != nil {
+			return nil, err
+		}
+		ae.Bytes(unix.NLA_F_NESTED|unixext.NFTA_INNER_EXPR, expr)
+	}
+	return ae.Encode()
+}
+
+func (a *InnerAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFTA_INNER_NUM:
+			a.Num = ad.Uint32()
+		case unixext.NFTA_INNER_TYPE:
+			a.Type = ad.Uint32()
+		case unixext.NFTA_INNER_FLAGS:
+			a.Flags = ad.Uint32()
+		case unixext.NFTA_INNER_HDRSIZE:
+			a.HdrSize = ad.Uint32()
+		case unixext.NFTA_INNER_EXPR:
+			expr, err := unmarshalExprData(ad.Bytes())
+			if err != nil {
+				return err
+			}
+			a.Expr = expr
+		}
+	}
+
+	return ad.Err()
+}
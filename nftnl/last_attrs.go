@@ -0,0 +1,45 @@
+package nftnl
+
+import (
+	"github.com/nickgarlis/go-nft/unixext"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type LastAttrs struct {
+	// Set is 1 once a packet has been seen.
+	Set uint32
+	// Msecs is the time elapsed since the last packet, in milliseconds.
+	Msecs uint64
+}
+
+func (a LastAttrs) ExprName() string {
+	return "last"
+}
+
+func (a *LastAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	if a.Set > 0 {
+		ae.Uint32(unixext.NFTA_LAST_SET, a.Set)
+		ae.Uint64(unixext.NFTA_LAST_MSECS, a.Msecs)
+	}
+
+	return ae.Encode()
+}
+
+func (a *LastAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFTA_LAST_SET:
+			a.Set = ad.Uint32()
+		case unixext.NFTA_LAST_MSECS:
+			a.Msecs = ad.Uint64()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package nftnl
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type LimitAttrs struct {
+	Rate  uint64
+	Unit  uint64
+	Burst uint32
+	Type  uint32
+	Flags uint32
+}
+
+func (a LimitAttrs) ExprName() string {
+	return "limit"
+}
+
+func (a *LimitAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.Uint64(unix.NFTA_LIMIT_RATE, a.Rate)
+	ae.Uint64(unix.NFTA_LIMIT_UNIT, a.Unit)
+	if a.Burst > 0 {
+		ae.Uint32(unix.NFTA_LIMIT_BURST, a.Burst)
+	}
+	ae.Uint32(unix.NFTA_LIMIT_TYPE, a.Type)
+	if a.Flags > 0 {
+		ae.Uint32(unix.NFTA_LIMIT_FLAGS, a.Flags)
+	}
+
+	return ae.Encode()
+}
+
+func (a *LimitAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_LIMIT_RATE:
+			a.Rate = ad.Uint64()
+		case unix.NFTA_LIMIT_UNIT:
+			a.Unit = ad.Uint64()
+		case unix.NFTA_LIMIT_BURST:
+			a.Burst = ad.Uint32()
+		case unix.NFTA_LIMIT_TYPE:
+			a.Type = ad.Uint32()
+		case unix.NFTA_LIMIT_FLAGS:
+			a.Flags = ad.Uint32()
+		}
+	}
+
+	return nil
+}
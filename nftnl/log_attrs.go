@@ -0,0 +1,76 @@
+package nftnl
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type LogAttrs struct {
+	// Group is the NFLOG group to send packets to. When nil, packets are
+	// logged to the kernel log instead.
+	Group  *uint16
+	Prefix string
+	// SnapLen and QThreshold only apply when logging to an NFLOG group.
+	SnapLen    uint32
+	QThreshold uint16
+	// Level and Flags only apply when logging to the kernel log. A nil Level
+	// lets the kernel pick its default.
+	Level *uint32
+	Flags uint32
+}
+
+func (a LogAttrs) ExprName() string {
+	return "log"
+}
+
+func (a *LogAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	if a.Group != nil {
+		ae.Uint16(unix.NFTA_LOG_GROUP, *a.Group)
+	}
+	if a.Prefix != "" {
+		ae.String(unix.NFTA_LOG_PREFIX, a.Prefix)
+	}
+	if a.SnapLen > 0 {
+		ae.Uint32(unix.NFTA_LOG_SNAPLEN, a.SnapLen)
+	}
+	if a.QThreshold > 0 {
+		ae.Uint16(unix.NFTA_LOG_QTHRESHOLD, a.QThreshold)
+	}
+	if a.Level != nil {
+		ae.Uint32(unix.NFTA_LOG_LEVEL, *a.Level)
+	}
+	if a.Flags > 0 {
+		ae.Uint32(unix.NFTA_LOG_FLAGS, a.Flags)
+	}
+
+	return ae.Encode()
+}
+
+func (a *LogAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_LOG_GROUP:
+			group := ad.Uint16()
+			a.Group = &group
+		case unix.NFTA_LOG_PREFIX:
+			a.Prefix = ad.String()
+		case unix.NFTA_LOG_SNAPLEN:
+			a.SnapLen = ad.Uint32()
+		case unix.NFTA_LOG_QTHRESHOLD:
+			a.QThreshold = ad.Uint16()
+		case unix.NFTA_LOG_LEVEL:
+			level := ad.Uint32()
+			a.Level = &level
+		case unix.NFTA_LOG_FLAGS:
+			a.Flags = ad.Uint32()
+		}
+	}
+
+	return nil
+}
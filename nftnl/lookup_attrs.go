@@ -0,0 +1,59 @@
+package nftnl
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type LookupAttrs struct {
+	Set   string
+	SReg  uint32
+	DReg  uint32
+	SetID uint32
+	Flags uint32
+}
+
+func (a LookupAttrs) ExprName() string {
+	return "lookup"
+}
+
+func (a *LookupAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.String(unix.NFTA_LOOKUP_SET, a.Set)
+	ae.Uint32(unix.NFTA_LOOKUP_SREG, a.SReg)
+	if a.DReg > 0 {
+		ae.Uint32(unix.NFTA_LOOKUP_DREG, a.DReg)
+	}
+	if a.SetID > 0 {
+		ae.Uint32(unix.NFTA_LOOKUP_SET_ID, a.SetID)
+	}
+	if a.Flags > 0 {
+		ae.Uint32(unix.NFTA_LOOKUP_FLAGS, a.Flags)
+	}
+
+	return ae.Encode()
+}
+
+func (a *LookupAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_LOOKUP_SET:
+			a.Set = ad.String()
+		case unix.NFTA_LOOKUP_SREG:
+			a.SReg = ad.Uint32()
+		case unix.NFTA_LOOKUP_DREG:
+			a.DReg = ad.Uint32()
+		case unix.NFTA_LOOKUP_SET_ID:
+			a.SetID = ad.Uint32()
+		case unix.NFTA_LOOKUP_FLAGS:
+			a.Flags = ad.Uint32()
+		}
+	}
+
+	return nil
+}
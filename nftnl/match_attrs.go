@@ -0,0 +1,46 @@
+package nftnl
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables_compat.h
+type MatchAttrs struct {
+	Name string
+	Rev  uint32
+	// Info is the xt_*_info structure of the match, as laid out by the
+	// kernel.
+	Info []byte
+}
+
+func (a MatchAttrs) ExprName() string {
+	return "match"
+}
+
+func (a *MatchAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.String(unix.NFTA_MATCH_NAME, a.Name)
+	ae.Uint32(unix.NFTA_MATCH_REV, a.Rev)
+	ae.Bytes(unix.NFTA_MATCH_INFO, a.Info)
+	return ae.Encode()
+}
+
+func (a *MatchAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_MATCH_NAME:
+			a.Name = ad.String()
+		case unix.NFTA_MATCH_REV:
+			a.Rev = ad.Uint32()
+		case unix.NFTA_MATCH_INFO:
+			a.Info = ad.Bytes()
+		}
+	}
+
+	return nil
+}
@@ -43,7 +43,7 @@ func (m *Msg) unmarshal(msg netlink.Message) error {
 	}
 
 	if len(msg.Data) > 4 {
-		attr, err := attrFactory(m.Header.MsgType)
+		attr, err := attrFactory(m.Header.SubsysID, m.Header.MsgType)
 		if err != nil {
 			return err
 		}
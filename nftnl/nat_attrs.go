@@ -0,0 +1,71 @@
+package nftnl
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type NatAttrs struct {
+	Type        uint32
+	Family      uint32
+	RegAddrMin  uint32
+	RegAddrMax  uint32
+	RegProtoMin uint32
+	RegProtoMax uint32
+	Flags       uint32
+}
+
+func (a NatAttrs) ExprName() string {
+	return "nat"
+}
+
+func (a *NatAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.Uint32(unix.NFTA_NAT_TYPE, a.Type)
+	ae.Uint32(unix.NFTA_NAT_FAMILY, a.Family)
+	if a.RegAddrMin > 0 {
+		ae.Uint32(unix.NFTA_NAT_REG_ADDR_MIN, a.RegAddrMin)
+	}
+	if a.RegAddrMax > 0 {
+		ae.Uint32(unix.NFTA_NAT_REG_ADDR_MAX, a.RegAddrMax)
+	}
+	if a.RegProtoMin > 0 {
+		ae.Uint32(unix.NFTA_NAT_REG_PROTO_MIN, a.RegProtoMin)
+	}
+	if a.RegProtoMax > 0 {
+		ae.Uint32(unix.NFTA_NAT_REG_PROTO_MAX, a.RegProtoMax)
+	}
+	if a.Flags > 0 {
+		ae.Uint32(unix.NFTA_NAT_FLAGS, a.Flags)
+	}
+
+	return ae.Encode()
+}
+
+func (a *NatAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_NAT_TYPE:
+			a.Type = ad.Uint32()
+		case unix.NFTA_NAT_FAMILY:
+			a.Family = ad.Uint32()
+		case unix.NFTA_NAT_REG_ADDR_MIN:
+			a.RegAddrMin = ad.Uint32()
+		case unix.NFTA_NAT_REG_ADDR_MAX:
+			a.RegAddrMax = ad.Uint32()
+		case unix.NFTA_NAT_REG_PROTO_MIN:
+			a.RegProtoMin = ad.Uint32()
+		case unix.NFTA_NAT_REG_PROTO_MAX:
+			a.RegProtoMax = ad.Uint32()
+		case unix.NFTA_NAT_FLAGS:
+			a.Flags = ad.Uint32()
+		}
+	}
+
+	return nil
+}
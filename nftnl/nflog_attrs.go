@@ -0,0 +1,239 @@
+package nftnl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/nickgarlis/go-nft/unixext"
+)
+
+func nflogAttrFactory(msgType uint16) (Attrs, error) {
+	switch msgType {
+	case unixext.NFULNL_MSG_PACKET:
+		return &NflogPacketAttrs{}, nil
+	case unixext.NFULNL_MSG_CONFIG:
+		return &NflogConfigAttrs{}, nil
+	default:
+		return nil, fmt.Errorf("unknown nflog message type %d", msgType)
+	}
+}
+
+// https://github.com/torvalds/linux/blob/8b789f2b7602a818e7c7488c74414fae21392b63/include/uapi/linux/netfilter/nfnetlink_log.h
+type NflogConfigAttrs struct {
+	Cmd uint8
+	// CopyMode and CopyRange are only sent when CopyMode is set.
+	CopyMode   uint8
+	CopyRange  uint32
+	BufSize    uint32
+	Timeout    uint32
+	QThreshold uint32
+	Flags      uint16
+}
+
+func (a *NflogConfigAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	if a.Cmd > 0 {
+		ae.Bytes(unixext.NFULA_CFG_CMD, []byte{a.Cmd})
+	}
+	if a.CopyMode > 0 {
+		mode := make([]byte, 6)
+		binary.BigEndian.PutUint32(mode, a.CopyRange)
+		mode[4] = a.CopyMode
+		ae.Bytes(unixext.NFULA_CFG_MODE, mode)
+	}
+	if a.BufSize > 0 {
+		ae.Uint32(unixext.NFULA_CFG_NLBUFSIZ, a.BufSize)
+	}
+	if a.Timeout > 0 {
+		ae.Uint32(unixext.NFULA_CFG_TIMEOUT, a.Timeout)
+	}
+	if a.QThreshold > 0 {
+		ae.Uint32(unixext.NFULA_CFG_QTHRESH, a.QThreshold)
+	}
+	if a.Flags > 0 {
+		ae.Uint16(unixext.NFULA_CFG_FLAGS, a.Flags)
+	}
+
+	return ae.Encode()
+}
+
+func (a *NflogConfigAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFULA_CFG_CMD:
+			if b := ad.Bytes(); len(b) > 0 {
+				a.Cmd = b[0]
+			}
+		case unixext.NFULA_CFG_MODE:
+			if b := ad.Bytes(); len(b) >= 5 {
+				a.CopyRange = binary.BigEndian.Uint32(b)
+				a.CopyMode = b[4]
+			}
+		case unixext.NFULA_CFG_NLBUFSIZ:
+			a.BufSize = ad.Uint32()
+		case unixext.NFULA_CFG_TIMEOUT:
+			a.Timeout = ad.Uint32()
+		case unixext.NFULA_CFG_QTHRESH:
+			a.QThreshold = ad.Uint32()
+		case unixext.NFULA_CFG_FLAGS:
+			a.Flags = ad.Uint16()
+		}
+	}
+
+	return nil
+}
+
+// https://github.com/torvalds/linux/blob/8b789f2b7602a818e7c7488c74414fae21392b63/include/uapi/linux/netfilter/nfnetlink_log.h
+type NflogPacketAttrs struct {
+	HwProtocol uint16
+	Hook       uint8
+	Mark       uint32
+	Timestamp  time.Time
+	InDev      uint32
+	OutDev     uint32
+	PhysInDev  uint32
+	PhysOutDev uint32
+	HwAddr     []byte
+	Payload    []byte
+	Prefix     string
+	// UID and GID are only present for packets that belong to a local socket.
+	UID       *uint32
+	GID       *uint32
+	Seq       uint32
+	SeqGlobal uint32
+	HwType    uint16
+	HwHeader  []byte
+}
+
+func (a *NflogPacketAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+
+	hdr := make([]byte, 4)
+	binary.BigEndian.PutUint16(hdr, a.HwProtocol)
+	hdr[2] = a.Hook
+	ae.Bytes(unixext.NFULA_PACKET_HDR, hdr)
+
+	if a.Mark > 0 {
+		ae.Uint32(unixext.NFULA_MARK, a.Mark)
+	}
+	if !a.Timestamp.IsZero() {
+		ts := make([]byte, 16)
+		binary.BigEndian.PutUint64(ts, uint64(a.Timestamp.Unix()))
+		binary.BigEndian.PutUint64(ts[8:], uint64(a.Timestamp.Nanosecond()/1000))
+		ae.Bytes(unixext.NFULA_TIMESTAMP, ts)
+	}
+	if a.InDev > 0 {
+		ae.Uint32(unixext.NFULA_IFINDEX_INDEV, a.InDev)
+	}
+	if a.OutDev > 0 {
+		ae.Uint32(unixext.NFULA_IFINDEX_OUTDEV, a.OutDev)
+	}
+	if a.PhysInDev > 0 {
+		ae.Uint32(unixext.NFULA_IFINDEX_PHYSINDEV, a.PhysInDev)
+	}
+	if a.PhysOutDev > 0 {
+		ae.Uint32(unixext.NFULA_IFINDEX_PHYSOUTDEV, a.PhysOutDev)
+	}
+	if len(a.HwAddr) > 0 {
+		hwaddr := make([]byte, 12)
+		binary.BigEndian.PutUint16(hwaddr, uint16(len(a.HwAddr)))
+		copy(hwaddr[4:], a.HwAddr)
+		ae.Bytes(unixext.NFULA_HWADDR, hwaddr)
+	}
+	if len(a.Payload) > 0 {
+		ae.Bytes(unixext.NFULA_PAYLOAD, a.Payload)
+	}
+	if a.Prefix != "" {
+		ae.String(unixext.NFULA_PREFIX, a.Prefix)
+	}
+	if a.UID != nil {
+		ae.Uint32(unixext.NFULA_UID, *a.UID)
+	}
+	if a.GID != nil {
+		ae.Uint32(unixext.NFULA_GID, *a.GID)
+	}
+	if a.Seq > 0 {
+		ae.Uint32(unixext.NFULA_SEQ, a.Seq)
+	}
+	if a.SeqGlobal > 0 {
+		ae.Uint32(unixext.NFULA_SEQ_GLOBAL, a.SeqGlobal)
+	}
+	if a.HwType > 0 {
+		ae.Uint16(unixext.NFULA_HWTYPE, a.HwType)
+	}
+	if len(a.HwHeader) > 0 {
+		ae.Bytes(unixext.NFULA_HWHEADER, a.HwHeader)
+		ae.Uint16(unixext.NFULA_HWLEN, uint16(len(a.HwHeader)))
+	}
+
+	return ae.Encode()
+}
+
+func (a *NflogPacketAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFULA_PACKET_HDR:
+			if b := ad.Bytes(); len(b) >= 3 {
+				a.HwProtocol = binary.BigEndian.Uint16(b)
+				a.Hook = b[2]
+			}
+		case unixext.NFULA_MARK:
+			a.Mark = ad.Uint32()
+		case unixext.NFULA_TIMESTAMP:
+			if b := ad.Bytes(); len(b) >= 16 {
+				sec := binary.BigEndian.Uint64(b)
+				usec := binary.BigEndian.Uint64(b[8:])
+				a.Timestamp = time.Unix(int64(sec), int64(usec)*1000)
+			}
+		case unixext.NFULA_IFINDEX_INDEV:
+			a.InDev = ad.Uint32()
+		case unixext.NFULA_IFINDEX_OUTDEV:
+			a.OutDev = ad.Uint32()
+		case unixext.NFULA_IFINDEX_PHYSINDEV:
+			a.PhysInDev = ad.Uint32()
+		case unixext.NFULA_IFINDEX_PHYSOUTDEV:
+			a.PhysOutDev = ad.Uint32()
+		case unixext.NFULA_HWADDR:
+			// struct nfulnl_msg_packet_hw: __be16 hw_addrlen, __u16 _pad,
+			// __u8 hw_addr[8]
+			if b := ad.Bytes(); len(b) >= 4 {
+				n := int(binary.BigEndian.Uint16(b))
+				if n > len(b)-4 {
+					n = len(b) - 4
+				}
+				a.HwAddr = b[4 : 4+n]
+			}
+		case unixext.NFULA_PAYLOAD:
+			a.Payload = ad.Bytes()
+		case unixext.NFULA_PREFIX:
+			a.Prefix = ad.String()
+		case unixext.NFULA_UID:
+			uid := ad.Uint32()
+			a.UID = &uid
+		case unixext.NFULA_GID:
+			gid := ad.Uint32()
+			a.GID = &gid
+		case unixext.NFULA_SEQ:
+			a.Seq = ad.Uint32()
+		case unixext.NFULA_SEQ_GLOBAL:
+			a.SeqGlobal = ad.Uint32()
+		case unixext.NFULA_HWTYPE:
+			a.HwType = ad.Uint16()
+		case unixext.NFULA_HWHEADER:
+			a.HwHeader = ad.Bytes()
+		}
+	}
+
+	return nil
+}
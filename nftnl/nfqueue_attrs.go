@@ -0,0 +1,294 @@
+package nftnl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/nickgarlis/go-nft/unixext"
+)
+
+func nfqueueAttrFactory(msgType uint16) (Attrs, error) {
+	switch msgType {
+	case unixext.NFQNL_MSG_PACKET:
+		return &NfqueuePacketAttrs{}, nil
+	case unixext.NFQNL_MSG_VERDICT:
+		return &NfqueueVerdictAttrs{}, nil
+	case unixext.NFQNL_MSG_CONFIG:
+		return &NfqueueConfigAttrs{}, nil
+	default:
+		return nil, fmt.Errorf("unknown nfqueue message type %d", msgType)
+	}
+}
+
+// https://github.com/torvalds/linux/blob/8b789f2b7602a818e7c7488c74414fae21392b63/include/uapi/linux/netfilter/nfnetlink_queue.h
+type NfqueueConfigAttrs struct {
+	Cmd uint8
+	PF  uint16
+	// CopyMode and CopyRange are only sent when CopyMode is set.
+	CopyMode  uint8
+	CopyRange uint32
+	MaxLen    uint32
+	// Flags are only sent together with a non-zero Mask selecting the flags
+	// to change.
+	Flags uint32
+	Mask  uint32
+}
+
+func (a *NfqueueConfigAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	if a.Cmd > 0 {
+		cmd := make([]byte, 4)
+		cmd[0] = a.Cmd
+		binary.BigEndian.PutUint16(cmd[2:], a.PF)
+		ae.Bytes(unixext.NFQA_CFG_CMD, cmd)
+	}
+	if a.CopyMode > 0 {
+		// struct nfqnl_msg_config_params is packed.
+		params := make([]byte, 5)
+		binary.BigEndian.PutUint32(params, a.CopyRange)
+		params[4] = a.CopyMode
+		ae.Bytes(unixext.NFQA_CFG_PARAMS, params)
+	}
+	if a.MaxLen > 0 {
+		ae.Uint32(unixext.NFQA_CFG_QUEUE_MAXLEN, a.MaxLen)
+	}
+	if a.Mask > 0 {
+		ae.Uint32(unixext.NFQA_CFG_FLAGS, a.Flags)
+		ae.Uint32(unixext.NFQA_CFG_MASK, a.Mask)
+	}
+
+	return ae.Encode()
+}
+
+func (a *NfqueueConfigAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFQA_CFG_CMD:
+			if b := ad.Bytes(); len(b) >= 4 {
+				a.Cmd = b[0]
+				a.PF = binary.BigEndian.Uint16(b[2:])
+			}
+		case unixext.NFQA_CFG_PARAMS:
+			if b := ad.Bytes(); len(b) >= 5 {
+				a.CopyRange = binary.BigEndian.Uint32(b)
+				a.CopyMode = b[4]
+			}
+		case unixext.NFQA_CFG_QUEUE_MAXLEN:
+			a.MaxLen = ad.Uint32()
+		case unixext.NFQA_CFG_FLAGS:
+			a.Flags = ad.Uint32()
+		case unixext.NFQA_CFG_MASK:
+			a.Mask = ad.Uint32()
+		}
+	}
+
+	return nil
+}
+
+// https://github.com/torvalds/linux/blob/8b789f2b7602a818e7c7488c74414fae21392b63/include/uapi/linux/netfilter/nfnetlink_queue.h
+type NfqueuePacketAttrs struct {
+	PacketID   uint32
+	HwProtocol uint16
+	Hook       uint8
+	Mark       uint32
+	Timestamp  time.Time
+	InDev      uint32
+	OutDev     uint32
+	PhysInDev  uint32
+	PhysOutDev uint32
+	HwAddr     []byte
+	Payload    []byte
+	// CapLen is the original length of the packet when Payload was
+	// truncated to the configured copy range.
+	CapLen  uint32
+	SkbInfo uint32
+	// UID and GID are only present for packets that belong to a local socket
+	// and when the queue was configured with NFQA_CFG_F_UID_GID.
+	UID      *uint32
+	GID      *uint32
+	L2Hdr    []byte
+	Priority uint32
+}
+
+func (a *NfqueuePacketAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+
+	hdr := make([]byte, 7)
+	binary.BigEndian.PutUint32(hdr, a.PacketID)
+	binary.BigEndian.PutUint16(hdr[4:], a.HwProtocol)
+	hdr[6] = a.Hook
+	ae.Bytes(unixext.NFQA_PACKET_HDR, hdr)
+
+	if a.Mark > 0 {
+		ae.Uint32(unixext.NFQA_MARK, a.Mark)
+	}
+	if !a.Timestamp.IsZero() {
+		ts := make([]byte, 16)
+		binary.BigEndian.PutUint64(ts, uint64(a.Timestamp.Unix()))
+		binary.BigEndian.PutUint64(ts[8:], uint64(a.Timestamp.Nanosecond()/1000))
+		ae.Bytes(unixext.NFQA_TIMESTAMP, ts)
+	}
+	if a.InDev > 0 {
+		ae.Uint32(unixext.NFQA_IFINDEX_INDEV, a.InDev)
+	}
+	if a.OutDev > 0 {
+		ae.Uint32(unixext.NFQA_IFINDEX_OUTDEV, a.OutDev)
+	}
+	if a.PhysInDev > 0 {
+		ae.Uint32(unixext.NFQA_IFINDEX_PHYSINDEV, a.PhysInDev)
+	}
+	if a.PhysOutDev > 0 {
+		ae.Uint32(unixext.NFQA_IFINDEX_PHYSOUTDEV, a.PhysOutDev)
+	}
+	if len(a.HwAddr) > 0 {
+		hwaddr := make([]byte, 12)
+		binary.BigEndian.PutUint16(hwaddr, uint16(len(a.HwAddr)))
+		copy(hwaddr[4:], a.HwAddr)
+		ae.Bytes(unixext.NFQA_HWADDR, hwaddr)
+	}
+	if len(a.Payload) > 0 {
+		ae.Bytes(unixext.NFQA_PAYLOAD, a.Payload)
+	}
+	if a.CapLen > 0 {
+		ae.Uint32(unixext.NFQA_CAP_LEN, a.CapLen)
+	}
+	if a.SkbInfo > 0 {
+		ae.Uint32(unixext.NFQA_SKB_INFO, a.SkbInfo)
+	}
+	if a.UID != nil {
+		ae.Uint32(unixext.NFQA_UID, *a.UID)
+	}
+	if a.GID != nil {
+		ae.Uint32(unixext.NFQA_GID, *a.GID)
+	}
+	if len(a.L2Hdr) > 0 {
+		ae.Bytes(unixext.NFQA_L2HDR, a.L2Hdr)
+	}
+	if a.Priority > 0 {
+		ae.Uint32(unixext.NFQA_PRIORITY, a.Priority)
+	}
+
+	return ae.Encode()
+}
+
+func (a *NfqueuePacketAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFQA_PACKET_HDR:
+			// struct nfqnl_msg_packet_hdr: __be32 packet_id, __be16
+			// hw_protocol, __u8 hook
+			if b := ad.Bytes(); len(b) >= 7 {
+				a.PacketID = binary.BigEndian.Uint32(b)
+				a.HwProtocol = binary.BigEndian.Uint16(b[4:])
+				a.Hook = b[6]
+			}
+		case unixext.NFQA_MARK:
+			a.Mark = ad.Uint32()
+		case unixext.NFQA_TIMESTAMP:
+			if b := ad.Bytes(); len(b) >= 16 {
+				sec := binary.BigEndian.Uint64(b)
+				usec := binary.BigEndian.Uint64(b[8:])
+				a.Timestamp = time.Unix(int64(sec), int64(usec)*1000)
+			}
+		case unixext.NFQA_IFINDEX_INDEV:
+			a.InDev = ad.Uint32()
+		case unixext.NFQA_IFINDEX_OUTDEV:
+			a.OutDev = ad.Uint32()
+		case unixext.NFQA_IFINDEX_PHYSINDEV:
+			a.PhysInDev = ad.Uint32()
+		case unixext.NFQA_IFINDEX_PHYSOUTDEV:
+			a.PhysOutDev = ad.Uint32()
+		case unixext.NFQA_HWADDR:
+			// struct nfqnl_msg_packet_hw: __be16 hw_addrlen, __u16 _pad,
+			// __u8 hw_addr[8]
+			if b := ad.Bytes(); len(b) >= 4 {
+				n := int(binary.BigEndian.Uint16(b))
+				if n > len(b)-4 {
+					n = len(b) - 4
+				}
+				a.HwAddr = b[4 : 4+n]
+			}
+		case unixext.NFQA_PAYLOAD:
+			a.Payload = ad.Bytes()
+		case unixext.NFQA_CAP_LEN:
+			a.CapLen = ad.Uint32()
+		case unixext.NFQA_SKB_INFO:
+			a.SkbInfo = ad.Uint32()
+		case unixext.NFQA_UID:
+			uid := ad.Uint32()
+			a.UID = &uid
+		case unixext.NFQA_GID:
+			gid := ad.Uint32()
+			a.GID = &gid
+		case unixext.NFQA_L2HDR:
+			a.L2Hdr = ad.Bytes()
+		case unixext.NFQA_PRIORITY:
+			a.Priority = ad.Uint32()
+		}
+	}
+
+	return nil
+}
+
+// https://github.com/torvalds/linux/blob/8b789f2b7602a818e7c7488c74414fae21392b63/include/uapi/linux/netfilter/nfnetlink_queue.h
+type NfqueueVerdictAttrs struct {
+	Verdict  uint32
+	PacketID uint32
+	// Mark replaces the packet mark when set.
+	Mark *uint32
+	// Payload replaces the packet contents when set.
+	Payload []byte
+}
+
+func (a *NfqueueVerdictAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+
+	hdr := make([]byte, 8)
+	binary.BigEndian.PutUint32(hdr, a.Verdict)
+	binary.BigEndian.PutUint32(hdr[4:], a.PacketID)
+	ae.Bytes(unixext.NFQA_VERDICT_HDR, hdr)
+
+	if a.Mark != nil {
+		ae.Uint32(unixext.NFQA_MARK, *a.Mark)
+	}
+	if len(a.Payload) > 0 {
+		ae.Bytes(unixext.NFQA_PAYLOAD, a.Payload)
+	}
+
+	return ae.Encode()
+}
+
+func (a *NfqueueVerdictAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFQA_VERDICT_HDR:
+			if b := ad.Bytes(); len(b) >= 8 {
+				a.Verdict = binary.BigEndian.Uint32(b)
+				a.PacketID = binary.BigEndian.Uint32(b[4:])
+			}
+		case unixext.NFQA_MARK:
+			mark := ad.Uint32()
+			a.Mark = &mark
+		case unixext.NFQA_PAYLOAD:
+			a.Payload = ad.Bytes()
+		}
+	}
+
+	return nil
+}
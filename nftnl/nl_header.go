@@ -46,10 +46,12 @@ func (h *Header) MsgTypeString() string {
 
 func (h *Header) marshal() netlink.Header {
 	var headerType netlink.HeaderType
-	if h.SubsysID == unix.NFNL_SUBSYS_NFTABLES {
-		headerType = netlink.HeaderType((h.SubsysID << 8) | h.MsgType)
-	} else {
+	switch h.SubsysID {
+	case unix.NFNL_MSG_BATCH_BEGIN, unix.NFNL_MSG_BATCH_END:
+		// Batch delimiters are plain message types without a subsystem.
 		headerType = netlink.HeaderType(h.SubsysID)
+	default:
+		headerType = netlink.HeaderType((h.SubsysID << 8) | h.MsgType)
 	}
 	nlHeader := netlink.Header{
 		Type:  headerType,
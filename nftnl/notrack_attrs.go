@@ -0,0 +1,17 @@
+package nftnl
+
+// NotrackAttrs has no attributes, the expression only marks the packet as
+// untracked.
+type NotrackAttrs struct{}
+
+func (a NotrackAttrs) ExprName() string {
+	return "notrack"
+}
+
+func (a *NotrackAttrs) marshal() ([]byte, error) {
+	return nil, nil
+}
+
+func (a *NotrackAttrs) unmarshal(data []byte) error {
+	return nil
+}
@@ -0,0 +1,51 @@
+package nftnl
+
+import (
+	"github.com/nickgarlis/go-nft/unixext"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type NumgenAttrs struct {
+	DReg    uint32
+	Modulus uint32
+	Type    uint32
+	Offset  uint32
+}
+
+func (a NumgenAttrs) ExprName() string {
+	return "numgen"
+}
+
+func (a *NumgenAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.Uint32(unixext.NFTA_NUMGEN_DREG, a.DReg)
+	ae.Uint32(unixext.NFTA_NUMGEN_MODULUS, a.Modulus)
+	ae.Uint32(unixext.NFTA_NUMGEN_TYPE, a.Type)
+	if a.Offset > 0 {
+		ae.Uint32(unixext.NFTA_NUMGEN_OFFSET, a.Offset)
+	}
+
+	return ae.Encode()
+}
+
+func (a *NumgenAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFTA_NUMGEN_DREG:
+			a.DReg = ad.Uint32()
+		case unixext.NFTA_NUMGEN_MODULUS:
+			a.Modulus = ad.Uint32()
+		case unixext.NFTA_NUMGEN_TYPE:
+			a.Type = ad.Uint32()
+		case unixext.NFTA_NUMGEN_OFFSET:
+			a.Offset = ad.Uint32()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package nftnl
+
+import (
+	"fmt"
+
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type ObjAttrs struct {
+	Table string
+	Name  string
+	Type  uint32
+	// Data holds the object's state, encoded like the expression of the same
+	// kind (e.g. LimitAttrs for NFT_OBJECT_LIMIT).
+	Data     ExprDataAttrs
+	Use      uint32
+	Handle   uint64
+	UserData []byte
+}
+
+func (a *ObjAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	if a.Table != "" {
+		ae.String(unix.NFTA_OBJ_TABLE, a.Table)
+	}
+	if a.Name != "" {
+		ae.String(unix.NFTA_OBJ_NAME, a.Name)
+	}
+	if a.Type > 0 {
+		ae.Uint32(unix.NFTA_OBJ_TYPE, a.Type)
+	}
+	if a.Data != nil {
+		data, err := a.Data.marshal()
+		if err != nil {
+			return nil, err
+		}
+		ae.Bytes(unix.NLA_F_NESTED|unix.NFTA_OBJ_DATA, data)
+	}
+	if a.Handle > 0 {
+		ae.Uint64(unixext.NFTA_OBJ_HANDLE, a.Handle)
+	}
+	if len(a.UserData) > 0 {
+		ae.Bytes(unixext.NFTA_OBJ_USERDATA, a.UserData)
+	}
+
+	return ae.Encode()
+}
+
+func (a *ObjAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	// The object data can only be decoded once the type is known.
+	var objData []byte
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_OBJ_TABLE:
+			a.Table = ad.String()
+		case unix.NFTA_OBJ_NAME:
+			a.Name = ad.String()
+		case unix.NFTA_OBJ_TYPE:
+			a.Type = ad.Uint32()
+		case unix.NFTA_OBJ_DATA:
+			objData = ad.Bytes()
+		case unix.NFTA_OBJ_USE:
+			a.Use = ad.Uint32()
+		case unixext.NFTA_OBJ_HANDLE:
+			a.Handle = ad.Uint64()
+		case unixext.NFTA_OBJ_USERDATA:
+			a.UserData = ad.Bytes()
+		}
+	}
+	if err := ad.Err(); err != nil {
+		return err
+	}
+
+	if objData != nil {
+		objAttrs, err := objDataFactory(a.Type)
+		if err != nil {
+			return err
+		}
+		if err := objAttrs.unmarshal(objData); err != nil {
+			return err
+		}
+		a.Data = objAttrs
+	}
+
+	return nil
+}
+
+func objDataFactory(objType uint32) (ExprDataAttrs, error) {
+	switch objType {
+	case unixext.NFT_OBJECT_COUNTER:
+		return &CounterAttrs{}, nil
+	case unixext.NFT_OBJECT_QUOTA:
+		return &QuotaAttrs{}, nil
+	case unixext.NFT_OBJECT_LIMIT:
+		return &LimitAttrs{}, nil
+	case unixext.NFT_OBJECT_SYNPROXY:
+		return &SynproxyAttrs{}, nil
+	case unixext.NFT_OBJECT_CT_HELPER:
+		return &CtHelperAttrs{}, nil
+	case unixext.NFT_OBJECT_TUNNEL:
+		return &TunnelKeyAttrs{}, nil
+	case unixext.NFT_OBJECT_CT_TIMEOUT:
+		return &CtTimeoutAttrs{}, nil
+	case unixext.NFT_OBJECT_CT_EXPECT:
+		return &CtExpectAttrs{}, nil
+	default:
+		return nil, fmt.Errorf("unknown object type %d", objType)
+	}
+}
@@ -0,0 +1,62 @@
+package nftnl
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type ObjrefAttrs struct {
+	// ImmType and ImmName reference a single named object.
+	ImmType uint32
+	ImmName string
+	// SetSReg, SetName and SetID look the object up in a map instead.
+	SetSReg uint32
+	SetName string
+	SetID   uint32
+}
+
+func (a ObjrefAttrs) ExprName() string {
+	return "objref"
+}
+
+func (a *ObjrefAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	if a.ImmName != "" {
+		ae.Uint32(unix.NFTA_OBJREF_IMM_TYPE, a.ImmType)
+		ae.String(unix.NFTA_OBJREF_IMM_NAME, a.ImmName)
+	} else {
+		ae.Uint32(unix.NFTA_OBJREF_SET_SREG, a.SetSReg)
+		if a.SetName != "" {
+			ae.String(unix.NFTA_OBJREF_SET_NAME, a.SetName)
+		}
+		if a.SetID > 0 {
+			ae.Uint32(unix.NFTA_OBJREF_SET_ID, a.SetID)
+		}
+	}
+
+	return ae.Encode()
+}
+
+func (a *ObjrefAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_OBJREF_IMM_TYPE:
+			a.ImmType = ad.Uint32()
+		case unix.NFTA_OBJREF_IMM_NAME:
+			a.ImmName = ad.String()
+		case unix.NFTA_OBJREF_SET_SREG:
+			a.SetSReg = ad.Uint32()
+		case unix.NFTA_OBJREF_SET_NAME:
+			a.SetName = ad.String()
+		case unix.NFTA_OBJREF_SET_ID:
+			a.SetID = ad.Uint32()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package nftnl
+
+import (
+	"github.com/nickgarlis/go-nft/unixext"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type OsfAttrs struct {
+	DReg  uint32
+	TTL   uint8
+	Flags uint32
+}
+
+func (a OsfAttrs) ExprName() string {
+	return "osf"
+}
+
+func (a *OsfAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.Uint32(unixext.NFTA_OSF_DREG, a.DReg)
+	if a.TTL > 0 {
+		ae.Uint8(unixext.NFTA_OSF_TTL, a.TTL)
+	}
+	if a.Flags > 0 {
+		ae.Uint32(unixext.NFTA_OSF_FLAGS, a.Flags)
+	}
+
+	return ae.Encode()
+}
+
+func (a *OsfAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFTA_OSF_DREG:
+			a.DReg = ad.Uint32()
+		case unixext.NFTA_OSF_TTL:
+			a.TTL = ad.Uint8()
+		case unixext.NFTA_OSF_FLAGS:
+			a.Flags = ad.Uint32()
+		}
+	}
+
+	return nil
+}
@@ -33,7 +33,7 @@ func (a *PayloadAttrs) marshal() ([]byte, error) {
 	ae.Uint32(unix.NFTA_PAYLOAD_OFFSET, a.Offset)
 	ae.Uint32(unix.NFTA_PAYLOAD_LEN, a.Len)
 
-	if a.CSumType > 0 {
+	if a.CSumType > 0 || a.CSumFlags > 0 {
 		ae.Uint32(unix.NFTA_PAYLOAD_CSUM_TYPE, a.CSumType)
 		ae.Uint32(unix.NFTA_PAYLOAD_CSUM_OFFSET, a.CSumOffset)
 		ae.Uint32(unix.NFTA_PAYLOAD_CSUM_FLAGS, a.CSumFlags)
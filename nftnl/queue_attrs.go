@@ -0,0 +1,58 @@
+package nftnl
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type QueueAttrs struct {
+	Num   uint16
+	Total uint16
+	Flags uint16
+	// SRegQNum takes the queue number from a register instead of Num and
+	// Total.
+	SRegQNum uint32
+}
+
+func (a QueueAttrs) ExprName() string {
+	return "queue"
+}
+
+func (a *QueueAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	if a.SRegQNum > 0 {
+		ae.Uint32(unix.NFTA_QUEUE_SREG_QNUM, a.SRegQNum)
+	} else {
+		ae.Uint16(unix.NFTA_QUEUE_NUM, a.Num)
+		if a.Total > 0 {
+			ae.Uint16(unix.NFTA_QUEUE_TOTAL, a.Total)
+		}
+	}
+	if a.Flags > 0 {
+		ae.Uint16(unix.NFTA_QUEUE_FLAGS, a.Flags)
+	}
+
+	return ae.Encode()
+}
+
+func (a *QueueAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_QUEUE_NUM:
+			a.Num = ad.Uint16()
+		case unix.NFTA_QUEUE_TOTAL:
+			a.Total = ad.Uint16()
+		case unix.NFTA_QUEUE_FLAGS:
+			a.Flags = ad.Uint16()
+		case unix.NFTA_QUEUE_SREG_QNUM:
+			a.SRegQNum = ad.Uint32()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package nftnl
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type RangeAttrs struct {
+	SReg     uint32
+	Op       uint32
+	FromData *DataAttrs
+	ToData   *DataAttrs
+}
+
+func (a RangeAttrs) ExprName() string {
+	return "range"
+}
+
+func (a *RangeAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+
+	ae.Uint32(unix.NFTA_RANGE_SREG, a.SReg)
+	ae.Uint32(unix.NFTA_RANGE_OP, a.Op)
+
+	if a.FromData != nil {
+		b, err := a.FromData.marshal()
+		if err != nil {
+			return nil, err
+		}
+		ae.Bytes(unix.NLA_F_NESTED|unix.NFTA_RANGE_FROM_DATA, b)
+	}
+	if a.ToData != nil {
+		b, err := a.ToData.marshal()
+		if err != nil {
+			return nil, err
+		}
+		ae.Bytes(unix.NLA_F_NESTED|unix.NFTA_RANGE_TO_DATA, b)
+	}
+
+	return ae.Encode()
+}
+
+func (a *RangeAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_RANGE_SREG:
+			a.SReg = ad.Uint32()
+		case unix.NFTA_RANGE_OP:
+			a.Op = ad.Uint32()
+		case unix.NFTA_RANGE_FROM_DATA:
+			a.FromData = &DataAttrs{}
+			if err := a.FromData.unmarshal(ad.Bytes()); err != nil {
+				return err
+			}
+		case unix.NFTA_RANGE_TO_DATA:
+			a.ToData = &DataAttrs{}
+			if err := a.ToData.unmarshal(ad.Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
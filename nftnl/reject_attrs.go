@@ -0,0 +1,44 @@
+package nftnl
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type RejectAttrs struct {
+	Type     uint32
+	ICMPCode uint8
+}
+
+func (a RejectAttrs) ExprName() string {
+	return "reject"
+}
+
+func (a *RejectAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.Uint32(unix.NFTA_REJECT_TYPE, a.Type)
+	// The kernel ignores the code for TCP resets and does not dump it back.
+	if a.Type != unix.NFT_REJECT_TCP_RST {
+		ae.Uint8(unix.NFTA_REJECT_ICMP_CODE, a.ICMPCode)
+	}
+
+	return ae.Encode()
+}
+
+func (a *RejectAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_REJECT_TYPE:
+			a.Type = ad.Uint32()
+		case unix.NFTA_REJECT_ICMP_CODE:
+			a.ICMPCode = ad.Uint8()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package nftnl
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type RtAttrs struct {
+	DReg uint32
+	Key  uint32
+}
+
+func (a RtAttrs) ExprName() string {
+	return "rt"
+}
+
+func (a *RtAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.Uint32(unix.NFTA_RT_DREG, a.DReg)
+	ae.Uint32(unix.NFTA_RT_KEY, a.Key)
+
+	return ae.Encode()
+}
+
+func (a *RtAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_RT_DREG:
+			a.DReg = ad.Uint32()
+		case unix.NFTA_RT_KEY:
+			a.Key = ad.Uint32()
+		}
+	}
+
+	return nil
+}
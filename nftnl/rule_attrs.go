@@ -15,6 +15,14 @@ type RuleAttrs struct {
 	ID          uint32
 	PositionID  uint32
 	ChainID     uint32
+	// Compat holds the protocol of the rule for the checks of xt match and
+	// target expressions.
+	Compat *RuleCompatAttrs
+}
+
+type RuleCompatAttrs struct {
+	Proto uint32
+	Flags uint32
 }
 
 func (a *RuleAttrs) marshal() ([]byte, error) {
@@ -47,6 +55,13 @@ func (a *RuleAttrs) marshal() ([]byte, error) {
 	if a.ID > 0 {
 		ae.Uint32(unix.NFTA_RULE_ID, a.ID)
 	}
+	if a.Compat != nil {
+		ae.Nested(unix.NFTA_RULE_COMPAT, func(nae *netlink.AttributeEncoder) error {
+			nae.Uint32(unix.NFTA_RULE_COMPAT_PROTO, a.Compat.Proto)
+			nae.Uint32(unix.NFTA_RULE_COMPAT_FLAGS, a.Compat.Flags)
+			return nil
+		})
+	}
 
 	return ae.Encode()
 }
@@ -85,6 +100,19 @@ func (a *RuleAttrs) unmarshal(data []byte) error {
 			})
 		case unix.NFTA_RULE_ID:
 			a.ID = ad.Uint32()
+		case unix.NFTA_RULE_COMPAT:
+			a.Compat = &RuleCompatAttrs{}
+			ad.Nested(func(nad *netlink.AttributeDecoder) error {
+				for nad.Next() {
+					switch nad.Type() {
+					case unix.NFTA_RULE_COMPAT_PROTO:
+						a.Compat.Proto = nad.Uint32()
+					case unix.NFTA_RULE_COMPAT_FLAGS:
+						a.Compat.Flags = nad.Uint32()
+					}
+				}
+				return nil
+			})
 		}
 	}
 
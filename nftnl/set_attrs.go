@@ -71,7 +71,75 @@ func (a *SetAttrs) marshal() ([]byte, error) {
 	if a.Handle != 0 {
 		ae.Uint64(unixext.NFTA_SET_HANDLE, a.Handle)
 	}
+	if a.Expr != nil {
+		expr, err := marshalExprData(a.Expr)
+		if err != nil {
+			return nil, err
+		}
+		ae.Bytes(unix.NLA_F_NESTED|unixext.NFTA_SET_EXPR, expr)
+	}
+	if len(a.Expressions) > 0 {
+		exprs, err := marshalExprDataList(a.Expressions)
+		if err != nil {
+			return nil, err
+		}
+		ae.Bytes(unix.NLA_F_NESTED|unixext.NFTA_SET_EXPRESSIONS, exprs)
+	}
 	// TODO: Rest of attributes
 
 	return ae.Encode()
 }
+
+func (a *SetAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_SET_TABLE:
+			a.Table = ad.String()
+		case unix.NFTA_SET_NAME:
+			a.Name = ad.String()
+		case unix.NFTA_SET_FLAGS:
+			a.Flags = ad.Uint32()
+		case unix.NFTA_SET_ID:
+			a.ID = ad.Uint32()
+		case unix.NFTA_SET_KEY_TYPE:
+			a.KeyType = ad.Uint32()
+		case unix.NFTA_SET_KEY_LEN:
+			a.KeyLen = ad.Uint32()
+		case unix.NFTA_SET_DATA_TYPE:
+			a.DataType = ad.Uint32()
+		case unix.NFTA_SET_DATA_LEN:
+			a.DataLen = ad.Uint32()
+		case unix.NFTA_SET_POLICY:
+			a.Policy = ad.Uint32()
+		case unix.NFTA_SET_TIMEOUT:
+			a.Timeout = ad.Uint64()
+		case unix.NFTA_SET_GC_INTERVAL:
+			a.GCInterval = uint64(ad.Uint32())
+		case unix.NFTA_SET_USERDATA:
+			a.UserData = ad.Bytes()
+		case unix.NFTA_SET_OBJ_TYPE:
+			a.ObjType = ad.Uint32()
+		case unixext.NFTA_SET_HANDLE:
+			a.Handle = ad.Uint64()
+		case unixext.NFTA_SET_EXPR:
+			expr, err := unmarshalExprData(ad.Bytes())
+			if err != nil {
+				return err
+			}
+			a.Expr = expr
+		case unixext.NFTA_SET_EXPRESSIONS:
+			exprs, err := unmarshalExprDataList(ad.Bytes())
+			if err != nil {
+				return err
+			}
+			a.Expressions = exprs
+		}
+	}
+
+	return nil
+}
@@ -1,7 +1,6 @@
 package nftnl
 
 import (
-	"github.com/mdlayher/netlink"
 	"github.com/nickgarlis/go-nft/unixext"
 	"golang.org/x/sys/unix"
 )
@@ -49,7 +48,7 @@ func (a *SetElemAttrs) marshal() ([]byte, error) {
 		ae.Bytes(unix.NFTA_SET_ELEM_USERDATA, a.UserData)
 	}
 	if a.Expr != nil {
-		exprData, err := a.Expr.marshal()
+		exprData, err := marshalExprData(a.Expr)
 		if err != nil {
 			return nil, err
 		}
@@ -65,13 +64,13 @@ func (a *SetElemAttrs) marshal() ([]byte, error) {
 		}
 		ae.Bytes(unix.NLA_F_NESTED|unixext.NFTA_SET_ELEM_KEY_END, keyEndData)
 	}
-	// if len(a.Expressions) > 0 {
-	// 	exprsData, err := marshalExprs(a.Expressions)
-	// 	if err != nil {
-	// 		return nil, err
-	// 	}
-	// 	ae.Bytes(unix.NLA_F_NESTED|unixext.NFTA_SET_ELEM_EXPRESSIONS, exprsData)
-	// }
+	if len(a.Expressions) > 0 {
+		exprsData, err := marshalExprDataList(a.Expressions)
+		if err != nil {
+			return nil, err
+		}
+		ae.Bytes(unix.NLA_F_NESTED|unixext.NFTA_SET_ELEM_EXPRESSIONS, exprsData)
+	}
 
 	return ae.Encode()
 }
@@ -85,15 +84,15 @@ func (a *SetElemAttrs) unmarshal(b []byte) error {
 	for ad.Next() {
 		switch ad.Type() {
 		case unix.NFTA_SET_ELEM_KEY:
-			ad.Nested(func(nad *netlink.AttributeDecoder) error {
-				a.Key = &DataAttrs{}
-				return a.Key.unmarshal(nad.Bytes())
-			})
+			a.Key = &DataAttrs{}
+			if err := a.Key.unmarshal(ad.Bytes()); err != nil {
+				return err
+			}
 		case unix.NFTA_SET_ELEM_DATA:
-			ad.Nested(func(nad *netlink.AttributeDecoder) error {
-				a.Data = &DataAttrs{}
-				return a.Data.unmarshal(nad.Bytes())
-			})
+			a.Data = &DataAttrs{}
+			if err := a.Data.unmarshal(ad.Bytes()); err != nil {
+				return err
+			}
 		case unix.NFTA_SET_ELEM_FLAGS:
 			a.Flags = ad.Uint32()
 		case unix.NFTA_SET_ELEM_TIMEOUT:
@@ -103,32 +102,24 @@ func (a *SetElemAttrs) unmarshal(b []byte) error {
 		case unix.NFTA_SET_ELEM_USERDATA:
 			a.UserData = ad.Bytes()
 		case unix.NFTA_SET_ELEM_EXPR:
-			// ad.Nested(func(nad *netlink.AttributeDecoder) error {
-			// 	a.Expr = &ExprAttrs{}
-			// 	return a.Expr.unmarshal(nad.Bytes())
-			// })
+			expr, err := unmarshalExprData(ad.Bytes())
+			if err != nil {
+				return err
+			}
+			a.Expr = expr
 		case unix.NFTA_SET_ELEM_OBJREF:
 			a.ObjRef = ad.String()
 		case unixext.NFTA_SET_ELEM_KEY_END:
-			ad.Nested(func(nad *netlink.AttributeDecoder) error {
-				a.KeyEnd = &DataAttrs{}
-				return a.KeyEnd.unmarshal(nad.Bytes())
-			})
-			// case unixext.NFTA_SET_ELEM_EXPRESSIONS:
-			// 	exprData := ad.Bytes()
-			// 	exprAd, err := NewAttributeDecoder(exprData)
-			// 	if err != nil {
-			// 		return err
-			// 	}
-
-			// 	for exprAd.Next() {
-			// 		expr := ExprAttrs{}
-			// 		if err := expr.unmarshal(exprAd.Bytes()); err != nil {
-			// 			return err
-			// 		}
-			// 		a.Expressions = append(a.Expressions, expr)
-			// 	}
-			// }
+			a.KeyEnd = &DataAttrs{}
+			if err := a.KeyEnd.unmarshal(ad.Bytes()); err != nil {
+				return err
+			}
+		case unixext.NFTA_SET_ELEM_EXPRESSIONS:
+			exprs, err := unmarshalExprDataList(ad.Bytes())
+			if err != nil {
+				return err
+			}
+			a.Expressions = exprs
 		}
 	}
 	return nil
@@ -9,7 +9,7 @@ type SetElemListAttrs struct {
 	Table    string
 	Set      string
 	Elements []SetElemAttrs
-	SetID    uint64
+	SetID    uint32
 }
 
 func (a *SetElemListAttrs) marshal() ([]byte, error) {
@@ -17,7 +17,7 @@ func (a *SetElemListAttrs) marshal() ([]byte, error) {
 	ae.String(unix.NFTA_SET_ELEM_LIST_TABLE, a.Table)
 	ae.String(unix.NFTA_SET_ELEM_LIST_SET, a.Set)
 	if a.SetID > 0 {
-		ae.Uint64(unix.NFTA_SET_ELEM_LIST_SET_ID, a.SetID)
+		ae.Uint32(unix.NFTA_SET_ELEM_LIST_SET_ID, a.SetID)
 	}
 	if len(a.Elements) > 0 {
 		ae.Nested(unix.NFTA_SET_ELEM_LIST_ELEMENTS, func(nae *netlink.AttributeEncoder) error {
@@ -48,7 +48,7 @@ func (a *SetElemListAttrs) unmarshal(b []byte) error {
 		case unix.NFTA_SET_ELEM_LIST_SET:
 			a.Set = ad.String()
 		case unix.NFTA_SET_ELEM_LIST_SET_ID:
-			a.SetID = ad.Uint64()
+			a.SetID = ad.Uint32()
 		case unix.NFTA_SET_ELEM_LIST_ELEMENTS:
 			ad.Nested(func(nad *netlink.AttributeDecoder) error {
 				for nad.Next() {
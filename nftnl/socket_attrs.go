@@ -0,0 +1,49 @@
+package nftnl
+
+import (
+	"github.com/nickgarlis/go-nft/unixext"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type SocketAttrs struct {
+	Key  uint32
+	DReg uint32
+	// Level is the cgroup ancestor level and is required by the cgroupv2
+	// key.
+	Level uint32
+}
+
+func (a SocketAttrs) ExprName() string {
+	return "socket"
+}
+
+func (a *SocketAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.Uint32(unixext.NFTA_SOCKET_KEY, a.Key)
+	ae.Uint32(unixext.NFTA_SOCKET_DREG, a.DReg)
+	if a.Key == unixext.NFT_SOCKET_CGROUPV2 || a.Level > 0 {
+		ae.Uint32(unixext.NFTA_SOCKET_LEVEL, a.Level)
+	}
+
+	return ae.Encode()
+}
+
+func (a *SocketAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFTA_SOCKET_KEY:
+			a.Key = ad.Uint32()
+		case unixext.NFTA_SOCKET_DREG:
+			a.DReg = ad.Uint32()
+		case unixext.NFTA_SOCKET_LEVEL:
+			a.Level = ad.Uint32()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package nftnl
+
+import (
+	"github.com/nickgarlis/go-nft/unixext"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type SynproxyAttrs struct {
+	MSS    uint16
+	Wscale uint8
+	Flags  uint32
+}
+
+func (a SynproxyAttrs) ExprName() string {
+	return "synproxy"
+}
+
+func (a *SynproxyAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	if a.Flags&unixext.NF_SYNPROXY_OPT_MSS != 0 {
+		ae.Uint16(unixext.NFTA_SYNPROXY_MSS, a.MSS)
+	}
+	if a.Flags&unixext.NF_SYNPROXY_OPT_WSCALE != 0 {
+		ae.Uint8(unixext.NFTA_SYNPROXY_WSCALE, a.Wscale)
+	}
+	if a.Flags > 0 {
+		ae.Uint32(unixext.NFTA_SYNPROXY_FLAGS, a.Flags)
+	}
+
+	return ae.Encode()
+}
+
+func (a *SynproxyAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFTA_SYNPROXY_MSS:
+			a.MSS = ad.Uint16()
+		case unixext.NFTA_SYNPROXY_WSCALE:
+			a.Wscale = ad.Uint8()
+		case unixext.NFTA_SYNPROXY_FLAGS:
+			a.Flags = ad.Uint32()
+		}
+	}
+
+	return nil
+}
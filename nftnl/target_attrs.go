@@ -0,0 +1,46 @@
+package nftnl
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables_compat.h
+type TargetAttrs struct {
+	Name string
+	Rev  uint32
+	// Info is the xt_*_info structure of the target, as laid out by the
+	// kernel.
+	Info []byte
+}
+
+func (a TargetAttrs) ExprName() string {
+	return "target"
+}
+
+func (a *TargetAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.String(unix.NFTA_TARGET_NAME, a.Name)
+	ae.Uint32(unix.NFTA_TARGET_REV, a.Rev)
+	ae.Bytes(unix.NFTA_TARGET_INFO, a.Info)
+	return ae.Encode()
+}
+
+func (a *TargetAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_TARGET_NAME:
+			a.Name = ad.String()
+		case unix.NFTA_TARGET_REV:
+			a.Rev = ad.Uint32()
+		case unix.NFTA_TARGET_INFO:
+			a.Info = ad.Bytes()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package nftnl
+
+import (
+	"github.com/nickgarlis/go-nft/unixext"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type TproxyAttrs struct {
+	Family  uint32
+	RegAddr uint32
+	RegPort uint32
+}
+
+func (a TproxyAttrs) ExprName() string {
+	return "tproxy"
+}
+
+func (a *TproxyAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.Uint32(unixext.NFTA_TPROXY_FAMILY, a.Family)
+	if a.RegAddr > 0 {
+		ae.Uint32(unixext.NFTA_TPROXY_REG_ADDR, a.RegAddr)
+	}
+	if a.RegPort > 0 {
+		ae.Uint32(unixext.NFTA_TPROXY_REG_PORT, a.RegPort)
+	}
+
+	return ae.Encode()
+}
+
+func (a *TproxyAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFTA_TPROXY_FAMILY:
+			a.Family = ad.Uint32()
+		case unixext.NFTA_TPROXY_REG_ADDR:
+			a.RegAddr = ad.Uint32()
+		case unixext.NFTA_TPROXY_REG_PORT:
+			a.RegPort = ad.Uint32()
+		}
+	}
+
+	return nil
+}
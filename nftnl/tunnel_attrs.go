@@ -0,0 +1,46 @@
+package nftnl
+
+import (
+	"github.com/nickgarlis/go-nft/unixext"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type TunnelAttrs struct {
+	Key  uint32
+	DReg uint32
+	Mode uint32
+}
+
+func (a TunnelAttrs) ExprName() string {
+	return "tunnel"
+}
+
+func (a *TunnelAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.Uint32(unixext.NFTA_TUNNEL_KEY, a.Key)
+	ae.Uint32(unixext.NFTA_TUNNEL_DREG, a.DReg)
+	if a.Mode > 0 {
+		ae.Uint32(unixext.NFTA_TUNNEL_MODE, a.Mode)
+	}
+	return ae.Encode()
+}
+
+func (a *TunnelAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFTA_TUNNEL_KEY:
+			a.Key = ad.Uint32()
+		case unixext.NFTA_TUNNEL_DREG:
+			a.DReg = ad.Uint32()
+		case unixext.NFTA_TUNNEL_MODE:
+			a.Mode = ad.Uint32()
+		}
+	}
+
+	return nil
+}
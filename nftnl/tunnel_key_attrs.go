@@ -0,0 +1,238 @@
+package nftnl
+
+import (
+	"github.com/mdlayher/netlink"
+	"github.com/nickgarlis/go-nft/unixext"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type TunnelKeyAttrs struct {
+	ID uint32
+	// IP holds the IPv4 endpoints and IP6 the IPv6 ones. The kernel requires
+	// one of them.
+	IP    *TunnelKeyIPAttrs
+	IP6   *TunnelKeyIPAttrs
+	Flags uint32
+	TOS   uint8
+	TTL   uint8
+	SPort uint16
+	DPort uint16
+	Opts  *TunnelKeyOptsAttrs
+}
+
+type TunnelKeyIPAttrs struct {
+	Src []byte
+	Dst []byte
+	// FlowLabel is only used with IPv6.
+	FlowLabel uint32
+}
+
+// TunnelKeyOptsAttrs holds the options of one of the tunnel protocols.
+type TunnelKeyOptsAttrs struct {
+	Vxlan  *TunnelKeyVxlanAttrs
+	Erspan *TunnelKeyErspanAttrs
+	Geneve []TunnelKeyGeneveAttrs
+}
+
+type TunnelKeyVxlanAttrs struct {
+	GBP uint32
+}
+
+type TunnelKeyErspanAttrs struct {
+	Version uint32
+	V1Index uint32
+	V2HWID  uint8
+	V2Dir   uint8
+}
+
+type TunnelKeyGeneveAttrs struct {
+	Class uint16
+	Type  uint8
+	Data  []byte
+}
+
+func (a TunnelKeyAttrs) ExprName() string {
+	return "tunnel"
+}
+
+func (a *TunnelKeyAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.Uint32(unixext.NFTA_TUNNEL_KEY_ID, a.ID)
+	if a.IP != nil {
+		ae.Nested(unixext.NFTA_TUNNEL_KEY_IP, func(nae *netlink.AttributeEncoder) error {
+			if len(a.IP.Src) > 0 {
+				nae.Bytes(unixext.NFTA_TUNNEL_KEY_IP_SRC, a.IP.Src)
+			}
+			nae.Bytes(unixext.NFTA_TUNNEL_KEY_IP_DST, a.IP.Dst)
+			return nil
+		})
+	}
+	if a.IP6 != nil {
+		ae.Nested(unixext.NFTA_TUNNEL_KEY_IP6, func(nae *netlink.AttributeEncoder) error {
+			if len(a.IP6.Src) > 0 {
+				nae.Bytes(unixext.NFTA_TUNNEL_KEY_IP6_SRC, a.IP6.Src)
+			}
+			nae.Bytes(unixext.NFTA_TUNNEL_KEY_IP6_DST, a.IP6.Dst)
+			if a.IP6.FlowLabel > 0 {
+				nae.Uint32(unixext.NFTA_TUNNEL_KEY_IP6_FLOWLABEL, a.IP6.FlowLabel)
+			}
+			return nil
+		})
+	}
+	if a.Flags > 0 {
+		ae.Uint32(unixext.NFTA_TUNNEL_KEY_FLAGS, a.Flags)
+	}
+	if a.TOS > 0 {
+		ae.Uint8(unixext.NFTA_TUNNEL_KEY_TOS, a.TOS)
+	}
+	if a.TTL > 0 {
+		ae.Uint8(unixext.NFTA_TUNNEL_KEY_TTL, a.TTL)
+	}
+	if a.SPort > 0 {
+		ae.Uint16(unixext.NFTA_TUNNEL_KEY_SPORT, a.SPort)
+	}
+	if a.DPort > 0 {
+		ae.Uint16(unixext.NFTA_TUNNEL_KEY_DPORT, a.DPort)
+	}
+	if a.Opts != nil {
+		ae.Nested(unixext.NFTA_TUNNEL_KEY_OPTS, a.Opts.encode)
+	}
+	return ae.Encode()
+}
+
+func (a *TunnelKeyOptsAttrs) encode(ae *netlink.AttributeEncoder) error {
+	if a.Vxlan != nil {
+		ae.Nested(unixext.NFTA_TUNNEL_KEY_OPTS_VXLAN, func(nae *netlink.AttributeEncoder) error {
+			nae.Uint32(unixext.NFTA_TUNNEL_KEY_VXLAN_GBP, a.Vxlan.GBP)
+			return nil
+		})
+	}
+	if a.Erspan != nil {
+		ae.Nested(unixext.NFTA_TUNNEL_KEY_OPTS_ERSPAN, func(nae *netlink.AttributeEncoder) error {
+			nae.Uint32(unixext.NFTA_TUNNEL_KEY_ERSPAN_VERSION, a.Erspan.Version)
+			if a.Erspan.Version == 1 {
+				nae.Uint32(unixext.NFTA_TUNNEL_KEY_ERSPAN_V1_INDEX, a.Erspan.V1Index)
+			} else {
+				nae.Uint8(unixext.NFTA_TUNNEL_KEY_ERSPAN_V2_HWID, a.Erspan.V2HWID)
+				nae.Uint8(unixext.NFTA_TUNNEL_KEY_ERSPAN_V2_DIR, a.Erspan.V2Dir)
+			}
+			return nil
+		})
+	}
+	// Every Geneve option is sent in its own attribute.
+	for _, opt := range a.Geneve {
+		ae.Nested(unixext.NFTA_TUNNEL_KEY_OPTS_GENEVE, func(nae *netlink.AttributeEncoder) error {
+			nae.Uint16(unixext.NFTA_TUNNEL_KEY_GENEVE_CLASS, opt.Class)
+			nae.Uint8(unixext.NFTA_TUNNEL_KEY_GENEVE_TYPE, opt.Type)
+			nae.Bytes(unixext.NFTA_TUNNEL_KEY_GENEVE_DATA, opt.Data)
+			return nil
+		})
+	}
+	return nil
+}
+
+func (a *TunnelKeyAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFTA_TUNNEL_KEY_ID:
+			a.ID = ad.Uint32()
+		case unixext.NFTA_TUNNEL_KEY_IP:
+			a.IP = &TunnelKeyIPAttrs{}
+			ad.Nested(a.IP.decode)
+		case unixext.NFTA_TUNNEL_KEY_IP6:
+			a.IP6 = &TunnelKeyIPAttrs{}
+			ad.Nested(a.IP6.decode)
+		case unixext.NFTA_TUNNEL_KEY_FLAGS:
+			a.Flags = ad.Uint32()
+		case unixext.NFTA_TUNNEL_KEY_TOS:
+			a.TOS = ad.Uint8()
+		case unixext.NFTA_TUNNEL_KEY_TTL:
+			a.TTL = ad.Uint8()
+		case unixext.NFTA_TUNNEL_KEY_SPORT:
+			a.SPort = ad.Uint16()
+		case unixext.NFTA_TUNNEL_KEY_DPORT:
+			a.DPort = ad.Uint16()
+		case unixext.NFTA_TUNNEL_KEY_OPTS:
+			a.Opts = &TunnelKeyOptsAttrs{}
+			ad.Nested(a.Opts.decode)
+		}
+	}
+
+	return ad.Err()
+}
+
+// decode reads the IPv4 or IPv6 endpoints, whose attributes share the same
+// types.
+func (a *TunnelKeyIPAttrs) decode(ad *netlink.AttributeDecoder) error {
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFTA_TUNNEL_KEY_IP6_SRC:
+			a.Src = ad.Bytes()
+		case unixext.NFTA_TUNNEL_KEY_IP6_DST:
+			a.Dst = ad.Bytes()
+		case unixext.NFTA_TUNNEL_KEY_IP6_FLOWLABEL:
+			a.FlowLabel = ad.Uint32()
+		}
+	}
+	return nil
+}
+
+func (a *TunnelKeyOptsAttrs) decode(ad *netlink.AttributeDecoder) error {
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFTA_TUNNEL_KEY_OPTS_VXLAN:
+			a.Vxlan = &TunnelKeyVxlanAttrs{}
+			ad.Nested(func(nad *netlink.AttributeDecoder) error {
+				for nad.Next() {
+					if nad.Type() == unixext.NFTA_TUNNEL_KEY_VXLAN_GBP {
+						a.Vxlan.GBP = nad.Uint32()
+					}
+				}
+				return nil
+			})
+		case unixext.NFTA_TUNNEL_KEY_OPTS_ERSPAN:
+			a.Erspan = &TunnelKeyErspanAttrs{}
+			ad.Nested(func(nad *netlink.AttributeDecoder) error {
+				for nad.Next() {
+					switch nad.Type() {
+					case unixext.NFTA_TUNNEL_KEY_ERSPAN_VERSION:
+						a.Erspan.Version = nad.Uint32()
+					case unixext.NFTA_TUNNEL_KEY_ERSPAN_V1_INDEX:
+						a.Erspan.V1Index = nad.Uint32()
+					case unixext.NFTA_TUNNEL_KEY_ERSPAN_V2_HWID:
+						a.Erspan.V2HWID = nad.Uint8()
+					case unixext.NFTA_TUNNEL_KEY_ERSPAN_V2_DIR:
+						a.Erspan.V2Dir = nad.Uint8()
+					}
+				}
+				return nil
+			})
+		case unixext.NFTA_TUNNEL_KEY_OPTS_GENEVE:
+			// The kernel dumps all Geneve options in a single attribute,
+			// each one starting with its class.
+			ad.Nested(func(nad *netlink.AttributeDecoder) error {
+				for nad.Next() {
+					switch nad.Type() {
+					case unixext.NFTA_TUNNEL_KEY_GENEVE_CLASS:
+						a.Geneve = append(a.Geneve, TunnelKeyGeneveAttrs{Class: nad.Uint16()})
+					case unixext.NFTA_TUNNEL_KEY_GENEVE_TYPE:
+						if n := len(a.Geneve); n > 0 {
+							a.Geneve[n-1].Type = nad.Uint8()
+						}
+					case unixext.NFTA_TUNNEL_KEY_GENEVE_DATA:
+						if n := len(a.Geneve); n > 0 {
+							a.Geneve[n-1].Data = nad.Bytes()
+						}
+					}
+				}
+				return nil
+			})
+		}
+	}
+	return nil
+}
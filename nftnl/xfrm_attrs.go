@@ -0,0 +1,53 @@
+package nftnl
+
+import (
+	"github.com/nickgarlis/go-nft/unixext"
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+type XfrmAttrs struct {
+	DReg uint32
+	Key  uint32
+	Dir  uint8
+	// SPNum is the index of the state in the security path, 0 being the
+	// outermost.
+	SPNum uint32
+}
+
+func (a XfrmAttrs) ExprName() string {
+	return "xfrm"
+}
+
+func (a *XfrmAttrs) marshal() ([]byte, error) {
+	ae := NewAttributeEncoder()
+	ae.Uint32(unixext.NFTA_XFRM_DREG, a.DReg)
+	ae.Uint32(unixext.NFTA_XFRM_KEY, a.Key)
+	ae.Uint8(unixext.NFTA_XFRM_DIR, a.Dir)
+	if a.SPNum > 0 {
+		ae.Uint32(unixext.NFTA_XFRM_SPNUM, a.SPNum)
+	}
+
+	return ae.Encode()
+}
+
+func (a *XfrmAttrs) unmarshal(data []byte) error {
+	ad, err := NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unixext.NFTA_XFRM_DREG:
+			a.DReg = ad.Uint32()
+		case unixext.NFTA_XFRM_KEY:
+			a.Key = ad.Uint32()
+		case unixext.NFTA_XFRM_DIR:
+			a.Dir = ad.Uint8()
+		case unixext.NFTA_XFRM_SPNUM:
+			a.SPNum = ad.Uint32()
+		}
+	}
+
+	return nil
+}
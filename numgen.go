@@ -0,0 +1,62 @@
+package nft
+
+import (
+	"fmt"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+type NumgenType uint8
+
+const (
+	// NumgenTypeInc counts up for each packet, wrapping around at the
+	// modulus.
+	NumgenTypeInc    NumgenType = 0x1
+	NumgenTypeRandom NumgenType = 0x2
+)
+
+// Numgen generates a number in [Offset, Offset+Modulus) for each packet.
+type Numgen struct {
+	Type    NumgenType
+	Modulus uint32
+	Offset  uint32
+}
+
+func (n *Numgen) validate() error {
+	if n.Type != NumgenTypeInc && n.Type != NumgenTypeRandom {
+		return fmt.Errorf("unknown numgen type %d", n.Type)
+	}
+	if n.Modulus == 0 {
+		return fmt.Errorf("numgen modulus must be greater than 0")
+	}
+	if uint64(n.Offset)+uint64(n.Modulus) > 1<<32 {
+		return fmt.Errorf("numgen offset and modulus exceed 32 bits")
+	}
+	return nil
+}
+
+func (n *Numgen) marshal(dreg uint32) *nftnl.NumgenAttrs {
+	attrs := &nftnl.NumgenAttrs{
+		DReg:    dreg,
+		Modulus: n.Modulus,
+		Type:    unix.NFT_NG_INCREMENTAL,
+		Offset:  n.Offset,
+	}
+	if n.Type == NumgenTypeRandom {
+		attrs.Type = unix.NFT_NG_RANDOM
+	}
+	return attrs
+}
+
+func numgenFromAttrs(attr *nftnl.NumgenAttrs) *Numgen {
+	n := &Numgen{
+		Type:    NumgenTypeInc,
+		Modulus: attr.Modulus,
+		Offset:  attr.Offset,
+	}
+	if attr.Type == unix.NFT_NG_RANDOM {
+		n.Type = NumgenTypeRandom
+	}
+	return n
+}
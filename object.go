@@ -0,0 +1,397 @@
+package nft
+
+import (
+	"fmt"
+
+	"github.com/mdlayher/netlink"
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+type ObjectType uint32
+
+const (
+	ObjectTypeCounter ObjectType = unixext.NFT_OBJECT_COUNTER
+	ObjectTypeQuota   ObjectType = unixext.NFT_OBJECT_QUOTA
+	ObjectTypeLimit   ObjectType = unixext.NFT_OBJECT_LIMIT
+	// ObjectTypeSynproxy objects hold synproxy settings that can be
+	// selected per packet with a map.
+	ObjectTypeSynproxy  ObjectType = unixext.NFT_OBJECT_SYNPROXY
+	ObjectTypeCtHelper  ObjectType = unixext.NFT_OBJECT_CT_HELPER
+	ObjectTypeCtTimeout ObjectType = unixext.NFT_OBJECT_CT_TIMEOUT
+	ObjectTypeCtExpect  ObjectType = unixext.NFT_OBJECT_CT_EXPECT
+	ObjectTypeTunnel    ObjectType = unixext.NFT_OBJECT_TUNNEL
+)
+
+// Object is a named stateful object that rules can share by referencing it
+// with an ObjectRef. Exactly one of Counter, Quota, Limit, Synproxy,
+// CtHelper, CtTimeout, CtExpect or Tunnel must be set. Referencing a conntrack
+// object from a rule assigns it to the connection of the packet.
+type Object struct {
+	Family uint8
+	Table  string
+	Name   string
+	Handle uint64
+	// Type is inferred from the populated field when unset.
+	Type      ObjectType
+	Counter   *Counter
+	Quota     *Quota
+	Limit     *Limit
+	Synproxy  *Synproxy
+	CtHelper  *CtHelper
+	CtTimeout *CtTimeout
+	CtExpect  *CtExpect
+	Tunnel    *Tunnel
+}
+
+// ObjectRef applies the named object of the given type to the packets
+// matched by a rule. When Map is set instead of Name, the object is looked
+// up in the named map with the Key of the packet, and packets without an
+// element in the map do not match. The map must have been created with the
+// NFT_SET_OBJECT flag and the object type.
+type ObjectRef struct {
+	Type ObjectType
+	Name string
+	Map  string
+	Key  SetKey
+}
+
+func (ref *ObjectRef) validate(r *Rule) error {
+	if ref.Map != "" {
+		if ref.Name != "" {
+			return fmt.Errorf("object reference cannot have both a name and a map")
+		}
+		return ref.Key.validate(r)
+	}
+	if ref.Name == "" {
+		return fmt.Errorf("object reference name must be specified")
+	}
+	switch ref.Type {
+	case ObjectTypeCounter, ObjectTypeQuota, ObjectTypeLimit, ObjectTypeSynproxy,
+		ObjectTypeCtHelper, ObjectTypeCtTimeout, ObjectTypeCtExpect:
+	case ObjectTypeTunnel:
+		if r.Family != unix.NFPROTO_NETDEV {
+			return fmt.Errorf("tunnel objects are only supported in the netdev family")
+		}
+	default:
+		return fmt.Errorf("unknown object type %d", ref.Type)
+	}
+	return nil
+}
+
+func objectRefExpr(ref *ObjectRef, l3proto uint8) []nftnl.ExprAttrs {
+	if ref.Map == "" {
+		return appendExpr(nil,
+			&nftnl.ObjrefAttrs{
+				ImmType: uint32(ref.Type),
+				ImmName: ref.Name,
+			},
+		)
+	}
+	return appendExpr(nil,
+		ref.Key.load(l3proto, 1),
+		&nftnl.ObjrefAttrs{
+			SetSReg: 1,
+			SetName: ref.Map,
+		},
+	)
+}
+
+// objectRefFromExprs decodes the objref at exprs[i] and, for maps, the load
+// of the key preceding it.
+func objectRefFromExprs(exprs []nftnl.ExprAttrs, i int) *ObjectRef {
+	attr := exprs[i].Data.(*nftnl.ObjrefAttrs)
+	if attr.ImmName != "" {
+		return &ObjectRef{
+			Type: ObjectType(attr.ImmType),
+			Name: attr.ImmName,
+		}
+	}
+	if attr.SetName == "" || i == 0 {
+		return nil
+	}
+	key, reg := setKeyFromExpr(exprs[i-1].Data)
+	if key == 0 || reg != attr.SetSReg {
+		return nil
+	}
+	return &ObjectRef{Map: attr.SetName, Key: key}
+}
+
+func (o *Object) objectType() ObjectType {
+	if o.Type != 0 {
+		return o.Type
+	}
+	switch {
+	case o.Counter != nil:
+		return ObjectTypeCounter
+	case o.Quota != nil:
+		return ObjectTypeQuota
+	case o.Limit != nil:
+		return ObjectTypeLimit
+	case o.Synproxy != nil:
+		return ObjectTypeSynproxy
+	case o.CtHelper != nil:
+		return ObjectTypeCtHelper
+	case o.CtTimeout != nil:
+		return ObjectTypeCtTimeout
+	case o.CtExpect != nil:
+		return ObjectTypeCtExpect
+	case o.Tunnel != nil:
+		return ObjectTypeTunnel
+	}
+	return 0
+}
+
+func (o *Object) validateCreate() error {
+	if o.Table == "" || o.Name == "" {
+		return fmt.Errorf("table and object names must be specified")
+	}
+	n := 0
+	for _, set := range []bool{
+		o.Counter != nil, o.Quota != nil, o.Limit != nil, o.Synproxy != nil,
+		o.CtHelper != nil, o.CtTimeout != nil, o.CtExpect != nil, o.Tunnel != nil,
+	} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return fmt.Errorf("exactly one of counter, quota, limit, synproxy, ct helper, ct timeout, ct expectation or tunnel must be specified")
+	}
+	switch o.objectType() {
+	case ObjectTypeCounter:
+		if o.Counter == nil {
+			return fmt.Errorf("counter must be specified for a counter object")
+		}
+	case ObjectTypeQuota:
+		if o.Quota == nil {
+			return fmt.Errorf("quota must be specified for a quota object")
+		}
+	case ObjectTypeLimit:
+		if o.Limit == nil {
+			return fmt.Errorf("limit must be specified for a limit object")
+		}
+		if err := o.Limit.validate(); err != nil {
+			return err
+		}
+	case ObjectTypeSynproxy:
+		if o.Synproxy == nil {
+			return fmt.Errorf("synproxy must be specified for a synproxy object")
+		}
+		if err := o.Synproxy.validate(); err != nil {
+			return err
+		}
+	case ObjectTypeCtHelper:
+		if o.CtHelper == nil {
+			return fmt.Errorf("ct helper must be specified for a ct helper object")
+		}
+		if err := o.CtHelper.validate(); err != nil {
+			return err
+		}
+	case ObjectTypeCtTimeout:
+		if o.CtTimeout == nil {
+			return fmt.Errorf("ct timeout must be specified for a ct timeout object")
+		}
+		if err := o.CtTimeout.validate(); err != nil {
+			return err
+		}
+	case ObjectTypeCtExpect:
+		if o.CtExpect == nil {
+			return fmt.Errorf("ct expectation must be specified for a ct expectation object")
+		}
+		if err := o.CtExpect.validate(o.Family); err != nil {
+			return err
+		}
+	case ObjectTypeTunnel:
+		if o.Tunnel == nil {
+			return fmt.Errorf("tunnel must be specified for a tunnel object")
+		}
+		if err := o.Tunnel.validate(o.Family); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown object type %d", o.Type)
+	}
+	return nil
+}
+
+func (o *Object) marshal() *nftnl.ObjAttrs {
+	attrs := &nftnl.ObjAttrs{
+		Table:  o.Table,
+		Name:   o.Name,
+		Type:   uint32(o.objectType()),
+		Handle: o.Handle,
+	}
+	switch {
+	case o.Counter != nil:
+		attrs.Data = &nftnl.CounterAttrs{
+			Bytes:   o.Counter.Bytes,
+			Packets: o.Counter.Packets,
+		}
+	case o.Quota != nil:
+		attrs.Data = &nftnl.QuotaAttrs{
+			Bytes: o.Quota.Bytes,
+		}
+	case o.Limit != nil:
+		attrs.Data = o.Limit.marshal()
+	case o.Synproxy != nil:
+		attrs.Data = o.Synproxy.marshal()
+	case o.CtHelper != nil:
+		attrs.Data = o.CtHelper.marshal()
+	case o.CtTimeout != nil:
+		attrs.Data = o.CtTimeout.marshal()
+	case o.CtExpect != nil:
+		attrs.Data = o.CtExpect.marshal()
+	case o.Tunnel != nil:
+		attrs.Data = o.Tunnel.marshal()
+	}
+	return attrs
+}
+
+func (o *Object) unmarshal(family uint8, attrs *nftnl.ObjAttrs) {
+	o.Family = family
+	o.Table = attrs.Table
+	o.Name = attrs.Name
+	o.Handle = attrs.Handle
+	o.Type = ObjectType(attrs.Type)
+
+	switch data := attrs.Data.(type) {
+	case *nftnl.CounterAttrs:
+		o.Counter = &Counter{
+			Bytes:   data.Bytes,
+			Packets: data.Packets,
+		}
+	case *nftnl.QuotaAttrs:
+		o.Quota = &Quota{
+			Bytes: data.Bytes,
+		}
+	case *nftnl.LimitAttrs:
+		o.Limit = limitFromAttrs(data)
+	case *nftnl.SynproxyAttrs:
+		o.Synproxy = synproxyFromAttrs(data)
+	case *nftnl.CtHelperAttrs:
+		o.CtHelper = ctHelperFromAttrs(family, data)
+	case *nftnl.CtTimeoutAttrs:
+		o.CtTimeout = ctTimeoutFromAttrs(family, data)
+	case *nftnl.CtExpectAttrs:
+		o.CtExpect = ctExpectFromAttrs(family, data)
+	case *nftnl.TunnelKeyAttrs:
+		o.Tunnel = tunnelFromAttrs(data)
+	}
+}
+
+func (c *Conn) getObjects(family uint8, table string, objType ObjectType) ([]*Object, error) {
+	msg := nftnl.Msg{
+		Header: nftnl.Header{
+			SubsysID: unix.NFNL_SUBSYS_NFTABLES,
+			MsgType:  unix.NFT_MSG_GETOBJ,
+			Flags:    netlink.Request | netlink.Dump,
+		},
+		NfGenMsg: nftnl.NfGenMsg{
+			Family: family,
+		},
+		Attrs: &nftnl.ObjAttrs{
+			Table: table,
+			Type:  uint32(objType),
+		},
+	}
+
+	res, err := c.nftnlConn.Send(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := extractAttrs[*nftnl.ObjAttrs](res)
+	if err != nil {
+		return nil, err
+	}
+
+	objs := make([]*Object, len(attrs))
+	for i, a := range attrs {
+		o := &Object{}
+		o.unmarshal(family, a)
+		objs[i] = o
+	}
+	return objs, nil
+}
+
+func (c *Conn) GetObjects(table *Table) ([]*Object, error) {
+	if table.Name == "" {
+		return nil, fmt.Errorf("table name must be specified")
+	}
+	return c.getObjects(table.Family, table.Name, 0)
+}
+
+func (c *Conn) GetObject(obj *Object) (*Object, error) {
+	if obj.Table == "" || obj.Name == "" {
+		return nil, fmt.Errorf("table and object names must be specified")
+	}
+	objs, err := c.getObjects(obj.Family, obj.Table, obj.objectType())
+	if err != nil {
+		return nil, err
+	}
+
+	var found []*Object
+	for _, o := range objs {
+		if o.Name == obj.Name {
+			found = append(found, o)
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("object %q not found in table %q", obj.Name, obj.Table)
+	}
+	if len(found) > 1 {
+		return nil, fmt.Errorf("multiple objects found with name %q in table %q", obj.Name, obj.Table)
+	}
+
+	return found[0], nil
+}
+
+func (b *Batch) NewObject(obj *Object) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := obj.validateCreate(); err != nil {
+		return err
+	}
+	b.nftnlBatch.Add(nftnl.Msg{
+		Header: nftnl.Header{
+			SubsysID: unix.NFNL_SUBSYS_NFTABLES,
+			MsgType:  unix.NFT_MSG_NEWOBJ,
+			Flags:    netlink.Request | netlink.Acknowledge | netlink.Create,
+		},
+		NfGenMsg: nftnl.NfGenMsg{
+			Family: obj.Family,
+		},
+		Attrs: obj.marshal(),
+	})
+	return nil
+}
+
+func (b *Batch) DelObject(obj *Object) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if obj.Table == "" || (obj.Name == "" && obj.Handle == 0) {
+		return fmt.Errorf("table and object name or handle must be specified")
+	}
+	if obj.objectType() == 0 {
+		return fmt.Errorf("object type must be specified")
+	}
+	b.nftnlBatch.Add(nftnl.Msg{
+		Header: nftnl.Header{
+			SubsysID: unix.NFNL_SUBSYS_NFTABLES,
+			MsgType:  unix.NFT_MSG_DELOBJ,
+			Flags:    netlink.Request | netlink.Acknowledge,
+		},
+		NfGenMsg: nftnl.NfGenMsg{
+			Family: obj.Family,
+		},
+		Attrs: &nftnl.ObjAttrs{
+			Table:  obj.Table,
+			Name:   obj.Name,
+			Type:   uint32(obj.objectType()),
+			Handle: obj.Handle,
+		},
+	})
+	return nil
+}
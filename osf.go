@@ -0,0 +1,96 @@
+package nft
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+// OsfTTL controls how the TTL of the packet is compared with the TTL of the
+// fingerprint. The zero value requires the TTLs to be equal.
+type OsfTTL uint8
+
+const (
+	// OsfTTLLoose accepts a packet TTL lower than the fingerprint TTL, as
+	// when the host is a few hops away.
+	OsfTTLLoose OsfTTL = 0x1
+	// OsfTTLNoCheck ignores the TTL.
+	OsfTTLNoCheck OsfTTL = 0x2
+)
+
+// OsfMatch compares the operating system detected by passive fingerprinting
+// of TCP SYN packets. The fingerprints must have been loaded into the kernel,
+// otherwise every packet is detected as "unknown". The kernel only allows
+// the lookup in the prerouting, input and forward hooks.
+type OsfMatch struct {
+	// Name is the genre of the operating system, such as "Linux". When
+	// Version is set it also includes the version, such as "Linux:3.11".
+	Name    string
+	Version bool
+	TTL     OsfTTL
+}
+
+func (m *OsfMatch) validate(r *Rule) error {
+	switch r.Family {
+	case unix.NFPROTO_IPV4, unix.NFPROTO_IPV6, unix.NFPROTO_INET:
+	default:
+		return fmt.Errorf("osf is only supported in the ipv4, ipv6 and inet families")
+	}
+	if m.Name == "" {
+		return fmt.Errorf("osf requires a name to compare")
+	}
+	if len(m.Name) >= unix.NFT_OSF_MAXGENRELEN {
+		return fmt.Errorf("osf name must be shorter than %d characters", unix.NFT_OSF_MAXGENRELEN)
+	}
+	switch m.TTL {
+	case 0, OsfTTLLoose, OsfTTLNoCheck:
+	default:
+		return fmt.Errorf("unknown osf ttl mode %d", m.TTL)
+	}
+	return nil
+}
+
+func osfExpr(m *OsfMatch) []nftnl.ExprAttrs {
+	attrs := &nftnl.OsfAttrs{
+		DReg: 1,
+		TTL:  uint8(m.TTL),
+	}
+	if m.Version {
+		attrs.Flags = unixext.NFT_OSF_F_VERSION
+	}
+
+	return appendExpr(nil,
+		attrs,
+		&nftnl.CmpAttrs{
+			SReg: 1,
+			Op:   unix.NFT_CMP_EQ,
+			Data: &nftnl.DataAttrs{
+				Value: append([]byte(m.Name), 0),
+			},
+		},
+	)
+}
+
+func (r *Rule) unmarshalOsfExprs(attrs *nftnl.RuleAttrs) {
+	for i := 0; i+1 < len(attrs.Expressions); i++ {
+		osf, ok := attrs.Expressions[i].Data.(*nftnl.OsfAttrs)
+		if !ok {
+			continue
+		}
+		cmp, ok := attrs.Expressions[i+1].Data.(*nftnl.CmpAttrs)
+		if !ok || cmp.Op != unix.NFT_CMP_EQ || cmp.Data == nil || len(cmp.Data.Value) == 0 {
+			continue
+		}
+
+		name, _, _ := bytes.Cut(cmp.Data.Value, []byte{0})
+		r.Osf = &OsfMatch{
+			Name:    string(name),
+			Version: osf.Flags&unixext.NFT_OSF_F_VERSION != 0,
+			TTL:     OsfTTL(osf.TTL),
+		}
+		i++
+	}
+}
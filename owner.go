@@ -0,0 +1,189 @@
+package nft
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os/user"
+	"strconv"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+// OwnerMatch matches the user or group ID owning the local socket of the
+// packet. Packets without a socket, such as forwarded ones, never match.
+type OwnerMatch struct {
+	ID uint32
+	// MaxID makes the match a range from ID to MaxID, inclusive.
+	MaxID uint32
+	// Negate matches the IDs outside of ID or the range.
+	Negate bool
+}
+
+func (m *OwnerMatch) validate() error {
+	if m.MaxID != 0 && m.MaxID <= m.ID {
+		return fmt.Errorf("owner range maximum %d must be greater than the minimum %d", m.MaxID, m.ID)
+	}
+	return nil
+}
+
+// LookupUser returns the ID of the user with the given name, to be matched
+// by Rule.SkUID.
+func LookupUser(name string) (uint32, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("user %q has a non-numeric ID %q", name, u.Uid)
+	}
+	return uint32(id), nil
+}
+
+// LookupGroup returns the ID of the group with the given name, to be
+// matched by Rule.SkGID.
+func LookupGroup(name string) (uint32, error) {
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseUint(g.Gid, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("group %q has a non-numeric ID %q", name, g.Gid)
+	}
+	return uint32(id), nil
+}
+
+// ownerExpr matches the owner loaded by the meta key with m. Ranges are
+// compared in network byte order.
+func ownerExpr(m *OwnerMatch, key uint32) []nftnl.ExprAttrs {
+	exprs := appendExpr(nil,
+		&nftnl.MetaAttrs{
+			DReg: 1,
+			Key:  key,
+		},
+	)
+	if m.MaxID == 0 {
+		value := make([]byte, 4)
+		binary.NativeEndian.PutUint32(value, m.ID)
+		return appendExpr(exprs,
+			&nftnl.CmpAttrs{
+				SReg: 1,
+				Op:   cmpOp(m.Negate),
+				Data: &nftnl.DataAttrs{
+					Value: value,
+				},
+			},
+		)
+	}
+
+	min := binary.BigEndian.AppendUint32(nil, m.ID)
+	max := binary.BigEndian.AppendUint32(nil, m.MaxID)
+	exprs = appendExpr(exprs,
+		&nftnl.ByteorderAttrs{
+			SReg: 1,
+			DReg: 1,
+			Op:   unix.NFT_BYTEORDER_HTON,
+			Len:  4,
+			Size: 4,
+		},
+	)
+	if m.Negate {
+		return appendExpr(exprs,
+			&nftnl.RangeAttrs{
+				SReg:     1,
+				Op:       unix.NFT_RANGE_NEQ,
+				FromData: &nftnl.DataAttrs{Value: min},
+				ToData:   &nftnl.DataAttrs{Value: max},
+			},
+		)
+	}
+	return appendExpr(exprs,
+		&nftnl.CmpAttrs{
+			SReg: 1,
+			Op:   unix.NFT_CMP_GTE,
+			Data: &nftnl.DataAttrs{Value: min},
+		},
+		&nftnl.CmpAttrs{
+			SReg: 1,
+			Op:   unix.NFT_CMP_LTE,
+			Data: &nftnl.DataAttrs{Value: max},
+		},
+	)
+}
+
+// ownerFromExprs decodes the comparison following the owner load at
+// exprs[i], returning the match and the number of expressions consumed.
+func ownerFromExprs(exprs []nftnl.ExprAttrs, i int) (*OwnerMatch, int) {
+	if i+1 >= len(exprs) {
+		return nil, 0
+	}
+	switch e := exprs[i+1].Data.(type) {
+	case *nftnl.CmpAttrs:
+		if e.Data == nil || len(e.Data.Value) != 4 {
+			return nil, 0
+		}
+		if e.Op != unix.NFT_CMP_EQ && e.Op != unix.NFT_CMP_NEQ {
+			return nil, 0
+		}
+		return &OwnerMatch{
+			ID:     binary.NativeEndian.Uint32(e.Data.Value),
+			Negate: e.Op == unix.NFT_CMP_NEQ,
+		}, 1
+	case *nftnl.ByteorderAttrs:
+		if e.Op != unix.NFT_BYTEORDER_HTON || e.Size != 4 || i+2 >= len(exprs) {
+			return nil, 0
+		}
+	default:
+		return nil, 0
+	}
+
+	switch e := exprs[i+2].Data.(type) {
+	case *nftnl.RangeAttrs:
+		if e.Op != unix.NFT_RANGE_NEQ || e.FromData == nil || len(e.FromData.Value) != 4 ||
+			e.ToData == nil || len(e.ToData.Value) != 4 {
+			return nil, 0
+		}
+		return &OwnerMatch{
+			ID:     binary.BigEndian.Uint32(e.FromData.Value),
+			MaxID:  binary.BigEndian.Uint32(e.ToData.Value),
+			Negate: true,
+		}, 2
+	case *nftnl.CmpAttrs:
+		if e.Op != unix.NFT_CMP_GTE || e.Data == nil || len(e.Data.Value) != 4 || i+3 >= len(exprs) {
+			return nil, 0
+		}
+		max, ok := exprs[i+3].Data.(*nftnl.CmpAttrs)
+		if !ok || max.Op != unix.NFT_CMP_LTE || max.Data == nil || len(max.Data.Value) != 4 {
+			return nil, 0
+		}
+		return &OwnerMatch{
+			ID:    binary.BigEndian.Uint32(e.Data.Value),
+			MaxID: binary.BigEndian.Uint32(max.Data.Value),
+		}, 3
+	}
+	return nil, 0
+}
+
+func (r *Rule) unmarshalOwnerExprs(attrs *nftnl.RuleAttrs) {
+	for i := 0; i < len(attrs.Expressions); i++ {
+		meta, ok := attrs.Expressions[i].Data.(*nftnl.MetaAttrs)
+		if !ok || meta.DReg == 0 {
+			continue
+		}
+		if meta.Key != unix.NFT_META_SKUID && meta.Key != unix.NFT_META_SKGID {
+			continue
+		}
+		m, n := ownerFromExprs(attrs.Expressions, i)
+		if m == nil {
+			continue
+		}
+		if meta.Key == unix.NFT_META_SKUID {
+			r.SkUID = m
+		} else {
+			r.SkGID = m
+		}
+		i += n
+	}
+}
@@ -0,0 +1,346 @@
+package nft
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+// PayloadField is a packet header field that can be rewritten by a
+// PayloadSet.
+type PayloadField uint8
+
+const (
+	// PayloadFieldDSCP and PayloadFieldECN are the two parts of the IPv4 TOS
+	// or IPv6 traffic class field.
+	PayloadFieldDSCP     PayloadField = 0x1
+	PayloadFieldECN      PayloadField = 0x2
+	PayloadFieldTTL      PayloadField = 0x3
+	PayloadFieldHopLimit PayloadField = 0x4
+	PayloadFieldSrcPort  PayloadField = 0x5
+	PayloadFieldDstPort  PayloadField = 0x6
+	// PayloadFieldRaw writes Data at Offset bytes into the header selected
+	// by Base.
+	PayloadFieldRaw PayloadField = 0x7
+)
+
+type PayloadBase uint8
+
+const (
+	PayloadBaseNetwork   PayloadBase = 0x1
+	PayloadBaseTransport PayloadBase = 0x2
+)
+
+// PayloadSet rewrites a header field of matching packets. The IPv4 header
+// checksum and the transport checksum are updated as needed, so the rule's
+// family, or L3Proto for inet rules, must select the network protocol and
+// L4Proto must be set for transport header writes.
+type PayloadSet struct {
+	Field PayloadField
+	// Value is the new value of a named field.
+	Value uint16
+	// Base, Offset and Data are only used with PayloadFieldRaw.
+	Base   PayloadBase
+	Offset uint32
+	Data   []byte
+}
+
+func (s *PayloadSet) validate(r *Rule) error {
+	l3proto := r.l3proto()
+	switch s.Field {
+	case PayloadFieldDSCP, PayloadFieldECN:
+		if l3proto != unix.NFPROTO_IPV4 && l3proto != unix.NFPROTO_IPV6 {
+			return fmt.Errorf("setting dscp or ecn requires the ipv4 or ipv6 family or L3 protocol")
+		}
+		if s.Field == PayloadFieldDSCP && s.Value > 0x3f {
+			return fmt.Errorf("invalid dscp %d", s.Value)
+		}
+		if s.Field == PayloadFieldECN && s.Value > 0x3 {
+			return fmt.Errorf("invalid ecn %d", s.Value)
+		}
+	case PayloadFieldTTL:
+		if l3proto != unix.NFPROTO_IPV4 {
+			return fmt.Errorf("setting ttl requires the ipv4 family or L3 protocol IPv4")
+		}
+		if s.Value > 0xff {
+			return fmt.Errorf("invalid ttl %d", s.Value)
+		}
+	case PayloadFieldHopLimit:
+		if l3proto != unix.NFPROTO_IPV6 {
+			return fmt.Errorf("setting hop limit requires the ipv6 family or L3 protocol IPv6")
+		}
+		if s.Value > 0xff {
+			return fmt.Errorf("invalid hop limit %d", s.Value)
+		}
+	case PayloadFieldSrcPort, PayloadFieldDstPort:
+		switch r.l4proto() {
+		case unix.IPPROTO_TCP, unix.IPPROTO_UDP, unix.IPPROTO_UDPLITE, unix.IPPROTO_SCTP:
+		default:
+			return fmt.Errorf("setting ports requires L4 protocol TCP, UDP, UDP-Lite or SCTP")
+		}
+	case PayloadFieldRaw:
+		if len(s.Data) == 0 || len(s.Data) > 16 {
+			return fmt.Errorf("raw payload data must be between 1 and 16 bytes")
+		}
+		switch s.Base {
+		case PayloadBaseNetwork:
+			if l3proto != unix.NFPROTO_IPV4 && l3proto != unix.NFPROTO_IPV6 {
+				return fmt.Errorf("setting the network header requires the ipv4 or ipv6 family or L3 protocol")
+			}
+		case PayloadBaseTransport:
+			if r.l4proto() == 0 {
+				return fmt.Errorf("setting the transport header requires an L4 protocol")
+			}
+		default:
+			return fmt.Errorf("unknown payload base %d", s.Base)
+		}
+	default:
+		return fmt.Errorf("unknown payload field %d", s.Field)
+	}
+	return nil
+}
+
+// l4CSum returns the checksum type and offset of the transport protocol.
+func l4CSum(l4proto uint8) (uint32, uint32) {
+	switch l4proto {
+	case unix.IPPROTO_TCP:
+		return unix.NFT_PAYLOAD_CSUM_INET, 16
+	case unix.IPPROTO_UDP, unix.IPPROTO_UDPLITE:
+		return unix.NFT_PAYLOAD_CSUM_INET, 6
+	case unix.IPPROTO_ICMP, unix.IPPROTO_ICMPV6:
+		return unix.NFT_PAYLOAD_CSUM_INET, 2
+	case unix.IPPROTO_SCTP:
+		return unix.NFT_PAYLOAD_CSUM_SCTP, 8
+	}
+	return unix.NFT_PAYLOAD_CSUM_NONE, 0
+}
+
+// payloadWrite returns the expression writing register 1 to the header,
+// with the checksum updates the write requires.
+func payloadWrite(base uint32, offset uint32, length uint32, l3proto uint8, l4proto uint8) *nftnl.PayloadAttrs {
+	attrs := &nftnl.PayloadAttrs{
+		SReg:   1,
+		Base:   base,
+		Offset: offset,
+		Len:    length,
+	}
+	end := offset + length
+	switch base {
+	case unix.NFT_PAYLOAD_NETWORK_HEADER:
+		if l3proto == unix.NFPROTO_IPV4 {
+			attrs.CSumType = unix.NFT_PAYLOAD_CSUM_INET
+			attrs.CSumOffset = 10
+			// The addresses are part of the transport pseudo header.
+			if offset < 20 && end > 12 {
+				attrs.CSumFlags = unix.NFT_PAYLOAD_L4CSUM_PSEUDOHDR
+			}
+		} else if offset < 40 && end > 8 {
+			attrs.CSumFlags = unix.NFT_PAYLOAD_L4CSUM_PSEUDOHDR
+		}
+	case unix.NFT_PAYLOAD_TRANSPORT_HEADER:
+		attrs.CSumType, attrs.CSumOffset = l4CSum(l4proto)
+	}
+	return attrs
+}
+
+func payloadSetExpr(s *PayloadSet, l3proto uint8, l4proto uint8) []nftnl.ExprAttrs {
+	var base, offset uint32
+	var value []byte
+	// keep is the mask of the bits preserved when only part of the loaded
+	// bytes is rewritten.
+	var keep []byte
+
+	switch s.Field {
+	case PayloadFieldDSCP, PayloadFieldECN:
+		base = unix.NFT_PAYLOAD_NETWORK_HEADER
+		if l3proto == unix.NFPROTO_IPV4 {
+			offset = 1
+			if s.Field == PayloadFieldDSCP {
+				keep, value = []byte{0x03}, []byte{byte(s.Value << 2)}
+			} else {
+				keep, value = []byte{0xfc}, []byte{byte(s.Value)}
+			}
+		} else {
+			// The traffic class sits between the version and the flow label.
+			keep, value = make([]byte, 2), make([]byte, 2)
+			if s.Field == PayloadFieldDSCP {
+				binary.BigEndian.PutUint16(keep, 0xf03f)
+				binary.BigEndian.PutUint16(value, s.Value<<6)
+			} else {
+				binary.BigEndian.PutUint16(keep, 0xffcf)
+				binary.BigEndian.PutUint16(value, s.Value<<4)
+			}
+		}
+	case PayloadFieldTTL:
+		base, offset, value = unix.NFT_PAYLOAD_NETWORK_HEADER, 8, []byte{byte(s.Value)}
+	case PayloadFieldHopLimit:
+		base, offset, value = unix.NFT_PAYLOAD_NETWORK_HEADER, 7, []byte{byte(s.Value)}
+	case PayloadFieldSrcPort, PayloadFieldDstPort:
+		base, value = unix.NFT_PAYLOAD_TRANSPORT_HEADER, make([]byte, 2)
+		if s.Field == PayloadFieldDstPort {
+			offset = 2
+		}
+		binary.BigEndian.PutUint16(value, s.Value)
+	case PayloadFieldRaw:
+		base, offset, value = unix.NFT_PAYLOAD_NETWORK_HEADER, s.Offset, s.Data
+		if s.Base == PayloadBaseTransport {
+			base = unix.NFT_PAYLOAD_TRANSPORT_HEADER
+		}
+	}
+
+	write := payloadWrite(base, offset, uint32(len(value)), l3proto, l4proto)
+
+	// Internet checksums are updated 16 bits at a time, so writes covered by
+	// one must start on an even offset. Widen them to include the preceding
+	// byte and preserve it.
+	if offset%2 != 0 && (write.CSumType == unix.NFT_PAYLOAD_CSUM_INET || write.CSumFlags != 0) {
+		if keep == nil {
+			keep = make([]byte, len(value))
+		}
+		offset--
+		keep = append([]byte{0xff}, keep...)
+		value = append([]byte{0x00}, value...)
+		write = payloadWrite(base, offset, uint32(len(value)), l3proto, l4proto)
+	}
+
+	length := uint32(len(value))
+
+	if keep == nil {
+		return appendExpr(nil,
+			&nftnl.ImmediateAttrs{
+				DReg: 1,
+				Data: &nftnl.DataAttrs{
+					Value: value,
+				},
+			},
+			write,
+		)
+	}
+
+	return appendExpr(nil,
+		&nftnl.PayloadAttrs{
+			DReg:   1,
+			Base:   base,
+			Offset: offset,
+			Len:    length,
+		},
+		&nftnl.BitwiseAttrs{
+			SReg: 1,
+			DReg: 1,
+			Len:  length,
+			Mask: &nftnl.DataAttrs{
+				Value: keep,
+			},
+			Xor: &nftnl.DataAttrs{
+				Value: value,
+			},
+		},
+		write,
+	)
+}
+
+// unmarshalPayloadSetExprs decodes payload set statements.
+func (r *Rule) unmarshalPayloadSetExprs(attrs *nftnl.RuleAttrs) {
+	l3proto := r.l3proto()
+	exprs := attrs.Expressions
+	for i := 1; i < len(exprs); i++ {
+		write, ok := exprs[i].Data.(*nftnl.PayloadAttrs)
+		if !ok || write.SReg == 0 {
+			continue
+		}
+
+		var s *PayloadSet
+		switch prev := exprs[i-1].Data.(type) {
+		case *nftnl.ImmediateAttrs:
+			data, ok := prev.Data.(*nftnl.DataAttrs)
+			if !ok {
+				continue
+			}
+			s = payloadSetFromWrite(write, data.Value, l3proto)
+		case *nftnl.BitwiseAttrs:
+			if prev.Mask == nil || prev.Xor == nil {
+				continue
+			}
+			keep, value := prev.Mask.Value, prev.Xor.Value
+			if len(keep) != len(value) {
+				continue
+			}
+			// Undo the widening of writes to an even offset.
+			widened := write.CSumType == unix.NFT_PAYLOAD_CSUM_INET || write.CSumFlags != 0
+			if widened && len(keep) > 1 && keep[0] == 0xff && value[0] == 0 {
+				w := *write
+				w.Offset++
+				w.Len--
+				keep, value, write = keep[1:], value[1:], &w
+			}
+			if isZero(keep) {
+				s = payloadSetFromWrite(write, value, l3proto)
+			} else {
+				s = payloadSetFromBitwise(write, keep, value, l3proto)
+			}
+		}
+		if s != nil {
+			r.PayloadSets = append(r.PayloadSets, s)
+		}
+	}
+}
+
+func payloadSetFromWrite(write *nftnl.PayloadAttrs, value []byte, l3proto uint8) *PayloadSet {
+	if len(value) < int(write.Len) {
+		return nil
+	}
+	switch write.Base {
+	case unix.NFT_PAYLOAD_NETWORK_HEADER:
+		switch {
+		case l3proto == unix.NFPROTO_IPV4 && write.Offset == 8 && write.Len == 1:
+			return &PayloadSet{Field: PayloadFieldTTL, Value: uint16(value[0])}
+		case l3proto == unix.NFPROTO_IPV6 && write.Offset == 7 && write.Len == 1:
+			return &PayloadSet{Field: PayloadFieldHopLimit, Value: uint16(value[0])}
+		}
+		return &PayloadSet{Field: PayloadFieldRaw, Base: PayloadBaseNetwork, Offset: write.Offset, Data: value}
+	case unix.NFT_PAYLOAD_TRANSPORT_HEADER:
+		if write.Len == 2 && (write.Offset == 0 || write.Offset == 2) {
+			field := PayloadFieldSrcPort
+			if write.Offset == 2 {
+				field = PayloadFieldDstPort
+			}
+			return &PayloadSet{Field: field, Value: binary.BigEndian.Uint16(value)}
+		}
+		return &PayloadSet{Field: PayloadFieldRaw, Base: PayloadBaseTransport, Offset: write.Offset, Data: value}
+	}
+	return nil
+}
+
+func payloadSetFromBitwise(write *nftnl.PayloadAttrs, keep []byte, value []byte, l3proto uint8) *PayloadSet {
+	if write.Base != unix.NFT_PAYLOAD_NETWORK_HEADER {
+		return nil
+	}
+	switch {
+	case l3proto == unix.NFPROTO_IPV4 && write.Offset == 1 && len(keep) == 1 && len(value) == 1:
+		switch keep[0] {
+		case 0x03:
+			return &PayloadSet{Field: PayloadFieldDSCP, Value: uint16(value[0] >> 2)}
+		case 0xfc:
+			return &PayloadSet{Field: PayloadFieldECN, Value: uint16(value[0])}
+		}
+	case l3proto == unix.NFPROTO_IPV6 && write.Offset == 0 && len(keep) == 2 && len(value) == 2:
+		switch binary.BigEndian.Uint16(keep) {
+		case 0xf03f:
+			return &PayloadSet{Field: PayloadFieldDSCP, Value: binary.BigEndian.Uint16(value) >> 6}
+		case 0xffcf:
+			return &PayloadSet{Field: PayloadFieldECN, Value: binary.BigEndian.Uint16(value) >> 4}
+		}
+	}
+	return nil
+}
+
+func isZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,319 @@
+package nft
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+// portProtos are the transport protocols whose headers start with the
+// source and destination ports.
+var portProtos = []uint8{
+	unix.IPPROTO_TCP,
+	unix.IPPROTO_UDP,
+	unix.IPPROTO_UDPLITE,
+	unix.IPPROTO_SCTP,
+	unix.IPPROTO_DCCP,
+}
+
+func isPortProto(proto uint8) bool {
+	for _, p := range portProtos {
+		if p == proto {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *PortMatch) validate() error {
+	n := 0
+	if m.Port != 0 || m.MaxPort != 0 {
+		n++
+	}
+	if len(m.Ports) > 0 {
+		n++
+	}
+	if m.Set != "" || m.SetID != 0 {
+		n++
+	}
+	if n != 1 {
+		return fmt.Errorf("port match requires exactly one of a port, a range, a list or a set")
+	}
+	if m.MaxPort != 0 && m.MaxPort <= m.Port {
+		return fmt.Errorf("port range maximum %d must be greater than the minimum %d", m.MaxPort, m.Port)
+	}
+	if m.SetID != 0 && m.Set == "" {
+		return fmt.Errorf("port set name must be specified")
+	}
+	return nil
+}
+
+func (r *Rule) validatePorts() error {
+	if r.SrcPort != nil || r.DstPort != nil {
+		if l4proto := r.l4proto(); l4proto != 0 && !isPortProto(l4proto) {
+			return fmt.Errorf("port matching requires the tcp, udp, udplite, sctp or dccp L4 protocol")
+		}
+	}
+	matches := []*PortMatch{r.SrcPort, r.DstPort}
+	if r.Ct != nil {
+		matches = append(matches, r.Ct.SrcPort, r.Ct.DstPort)
+	}
+	for _, m := range matches {
+		if m == nil {
+			continue
+		}
+		if err := m.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// portAnonSets returns the anonymous sets of the port matches of a rule.
+func (r *Rule) portAnonSets() []anonSet {
+	var sets []anonSet
+	if r.l4proto() == 0 && (r.SrcPort != nil || r.DstPort != nil) {
+		set := anonSet{keyType: nftTypeInetProto, keyLen: 1}
+		for _, p := range portProtos {
+			set.keys = append(set.keys, []byte{p})
+		}
+		sets = append(sets, set)
+	}
+
+	matches := []*PortMatch{r.SrcPort, r.DstPort}
+	if r.Ct != nil {
+		matches = append(matches, r.Ct.SrcPort, r.Ct.DstPort)
+	}
+	for i, m := range matches {
+		if m == nil || len(m.Ports) == 0 || slices.Contains(matches[:i], m) {
+			continue
+		}
+		set := anonSet{match: m, keyType: nftTypeInetService, keyLen: 2}
+		for _, p := range m.Ports {
+			key := make([]byte, 2)
+			binary.BigEndian.PutUint16(key, p)
+			set.keys = append(set.keys, key)
+		}
+		sets = append(sets, set)
+	}
+	return sets
+}
+
+// portProtoExpr checks that the packet has a transport header starting
+// with the ports. It is inserted when the rule does not match on L4Proto.
+func portProtoExpr(setID uint32) []nftnl.ExprAttrs {
+	return appendExpr(nil,
+		&nftnl.MetaAttrs{
+			DReg: 1,
+			Key:  unix.NFT_META_L4PROTO,
+		},
+		anonSetLookup(setID, false),
+	)
+}
+
+// portLoad loads the source or destination port of the transport header.
+func portLoad(src bool) nftnl.ExprDataAttrs {
+	offset := uint32(2)
+	if src {
+		offset = 0
+	}
+	return &nftnl.PayloadAttrs{
+		DReg:   1,
+		Base:   unix.NFT_PAYLOAD_TRANSPORT_HEADER,
+		Offset: offset,
+		Len:    2,
+	}
+}
+
+// ctPortLoad loads the source or destination port of the original
+// direction of the connection.
+func ctPortLoad(src bool) nftnl.ExprDataAttrs {
+	key := uint32(unix.NFT_CT_PROTO_DST)
+	if src {
+		key = unix.NFT_CT_PROTO_SRC
+	}
+	return &nftnl.CtAttrs{
+		DReg:      1,
+		Key:       key,
+		Direction: unixext.IP_CT_DIR_ORIGINAL,
+	}
+}
+
+func portValue(port uint16) *nftnl.DataAttrs {
+	value := make([]byte, 2)
+	binary.BigEndian.PutUint16(value, port)
+	return &nftnl.DataAttrs{Value: value}
+}
+
+// portExpr compares the port loaded by load with m. setID is the ID of the
+// anonymous set of m.Ports.
+func portExpr(m *PortMatch, load nftnl.ExprDataAttrs, setID uint32) []nftnl.ExprAttrs {
+	exprs := appendExpr(nil, load)
+	switch {
+	case len(m.Ports) > 0:
+		return appendExpr(exprs, anonSetLookup(setID, m.Negate))
+	case m.Set != "":
+		return appendExpr(exprs,
+			&nftnl.LookupAttrs{
+				Set:   m.Set,
+				SetID: m.SetID,
+				SReg:  1,
+				Flags: lookupFlags(m.Negate),
+			},
+		)
+	case m.MaxPort != 0 && m.Negate:
+		return appendExpr(exprs,
+			&nftnl.RangeAttrs{
+				SReg:     1,
+				Op:       unix.NFT_RANGE_NEQ,
+				FromData: portValue(m.Port),
+				ToData:   portValue(m.MaxPort),
+			},
+		)
+	case m.MaxPort != 0:
+		return appendExpr(exprs,
+			&nftnl.CmpAttrs{
+				SReg: 1,
+				Op:   unix.NFT_CMP_GTE,
+				Data: portValue(m.Port),
+			},
+			&nftnl.CmpAttrs{
+				SReg: 1,
+				Op:   unix.NFT_CMP_LTE,
+				Data: portValue(m.MaxPort),
+			},
+		)
+	}
+	return appendExpr(exprs,
+		&nftnl.CmpAttrs{
+			SReg: 1,
+			Op:   cmpOp(m.Negate),
+			Data: portValue(m.Port),
+		},
+	)
+}
+
+// portFromExprs decodes the comparison following the port load at
+// exprs[i]. The ports of an anonymous set are filled in by the caller.
+func portFromExprs(exprs []nftnl.ExprAttrs, i int) *PortMatch {
+	if i+1 >= len(exprs) {
+		return nil
+	}
+	switch e := exprs[i+1].Data.(type) {
+	case *nftnl.LookupAttrs:
+		if e.DReg != 0 {
+			return nil
+		}
+		return &PortMatch{Set: e.Set, Negate: e.Flags&unix.NFT_LOOKUP_F_INV != 0}
+	case *nftnl.RangeAttrs:
+		if e.FromData == nil || len(e.FromData.Value) != 2 || e.ToData == nil || len(e.ToData.Value) != 2 {
+			return nil
+		}
+		return &PortMatch{
+			Port:    binary.BigEndian.Uint16(e.FromData.Value),
+			MaxPort: binary.BigEndian.Uint16(e.ToData.Value),
+			Negate:  e.Op == unix.NFT_RANGE_NEQ,
+		}
+	case *nftnl.CmpAttrs:
+		if e.Data == nil || len(e.Data.Value) != 2 {
+			return nil
+		}
+		port := binary.BigEndian.Uint16(e.Data.Value)
+		switch e.Op {
+		case unix.NFT_CMP_EQ, unix.NFT_CMP_NEQ:
+			return &PortMatch{Port: port, Negate: e.Op == unix.NFT_CMP_NEQ}
+		case unix.NFT_CMP_GTE:
+			if i+2 >= len(exprs) {
+				return nil
+			}
+			max, ok := exprs[i+2].Data.(*nftnl.CmpAttrs)
+			if !ok || max.Op != unix.NFT_CMP_LTE || max.Data == nil || len(max.Data.Value) != 2 {
+				return nil
+			}
+			return &PortMatch{Port: port, MaxPort: binary.BigEndian.Uint16(max.Data.Value)}
+		}
+	}
+	return nil
+}
+
+func (r *Rule) unmarshalPortExprs(attrs *nftnl.RuleAttrs) {
+	for i, expr := range attrs.Expressions {
+		var src bool
+		switch e := expr.Data.(type) {
+		case *nftnl.PayloadAttrs:
+			if e.DReg == 0 || e.Base != unix.NFT_PAYLOAD_TRANSPORT_HEADER || e.Len != 2 {
+				continue
+			}
+			switch e.Offset {
+			case 0:
+				src = true
+			case 2:
+			default:
+				continue
+			}
+			m := portFromExprs(attrs.Expressions, i)
+			if m == nil {
+				continue
+			}
+			if src {
+				r.SrcPort = m
+			} else {
+				r.DstPort = m
+			}
+		case *nftnl.CtAttrs:
+			if e.DReg == 0 || e.Direction != unixext.IP_CT_DIR_ORIGINAL {
+				continue
+			}
+			switch e.Key {
+			case unix.NFT_CT_PROTO_SRC:
+				src = true
+			case unix.NFT_CT_PROTO_DST:
+			default:
+				continue
+			}
+			m := portFromExprs(attrs.Expressions, i)
+			if m == nil {
+				continue
+			}
+			if r.Ct == nil {
+				r.Ct = &CtMatch{}
+			}
+			if src {
+				r.Ct.SrcPort = m
+			} else {
+				r.Ct.DstPort = m
+			}
+		}
+	}
+}
+
+// getPortSetElems fills in the ports of the anonymous sets looked up by the
+// port matches of a dumped rule, in ascending order.
+func (c *Conn) getPortSetElems(r *Rule) error {
+	matches := []*PortMatch{r.SrcPort, r.DstPort}
+	if r.Ct != nil {
+		matches = append(matches, r.Ct.SrcPort, r.Ct.DstPort)
+	}
+	for _, m := range matches {
+		if m == nil || !isAnonSet(m.Set) {
+			continue
+		}
+
+		keys, err := c.getSetKeys(r.Family, r.Table, m.Set)
+		if err != nil {
+			return err
+		}
+		m.Set = ""
+		for _, key := range keys {
+			if len(key) >= 2 {
+				m.Ports = append(m.Ports, binary.BigEndian.Uint16(key))
+			}
+		}
+		slices.Sort(m.Ports)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package nft
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+// Queue hands matching packets to a userspace program listening on an
+// NFQUEUE, such as one built with the nfqueue package.
+type Queue struct {
+	// Num is the queue packets are sent to.
+	Num uint16
+	// Total spreads packets over the queues Num to Num+Total-1. If unset,
+	// only queue Num is used.
+	Total uint16
+	// Bypass accepts packets instead of dropping them when no program is
+	// listening on the queue.
+	Bypass bool
+	// Fanout picks the queue by CPU instead of by flow hash when Total is
+	// set.
+	Fanout bool
+	// NumFrom takes the queue number from the packet instead of Num and
+	// Total.
+	NumFrom *QueueNumSource
+}
+
+// QueueNumSource computes the queue number of a packet. Exactly one of its
+// fields must be set.
+type QueueNumSource struct {
+	// Mark uses the packet mark.
+	Mark   bool
+	Numgen *Numgen
+	Hash   *Hash
+}
+
+func (s *QueueNumSource) validate(r *Rule) error {
+	switch {
+	case s.Mark && s.Numgen == nil && s.Hash == nil:
+		return nil
+	case !s.Mark && s.Numgen != nil && s.Hash == nil:
+		return s.Numgen.validate()
+	case !s.Mark && s.Numgen == nil && s.Hash != nil:
+		return s.Hash.validate(r)
+	}
+	return fmt.Errorf("queue number source requires exactly one of mark, numgen or hash")
+}
+
+func (s *QueueNumSource) marshal(l3proto uint8, dreg uint32) []nftnl.ExprAttrs {
+	switch {
+	case s.Numgen != nil:
+		return appendExpr(nil, s.Numgen.marshal(dreg))
+	case s.Hash != nil:
+		return s.Hash.marshal(l3proto, dreg)
+	}
+	return appendExpr(nil,
+		&nftnl.MetaAttrs{
+			DReg: dreg,
+			Key:  unix.NFT_META_MARK,
+		},
+	)
+}
+
+// queueNumSourceFromExprs decodes the queue number source computed into
+// sreg at exprs[i].
+func queueNumSourceFromExprs(exprs []nftnl.ExprAttrs, i int, sreg uint32) *QueueNumSource {
+	switch e := exprs[i].Data.(type) {
+	case *nftnl.MetaAttrs:
+		if e.Key == unix.NFT_META_MARK && reg32(e.DReg) == reg32(sreg) {
+			return &QueueNumSource{Mark: true}
+		}
+	case *nftnl.NumgenAttrs:
+		if reg32(e.DReg) == reg32(sreg) {
+			return &QueueNumSource{Numgen: numgenFromAttrs(e)}
+		}
+	case *nftnl.HashAttrs:
+		if reg32(e.DReg) == reg32(sreg) {
+			return &QueueNumSource{Hash: hashFromExprs(exprs, i)}
+		}
+	}
+	return nil
+}
+
+func (q *Queue) validate(r *Rule) error {
+	if q.NumFrom != nil {
+		if q.Num != 0 || q.Total != 0 {
+			return fmt.Errorf("queue number and total cannot be used when the queue number is taken from the packet")
+		}
+		if q.Fanout {
+			return fmt.Errorf("queue fanout cannot be used when the queue number is taken from the packet")
+		}
+		return q.NumFrom.validate(r)
+	}
+	if q.Total > 0 && uint32(q.Num)+uint32(q.Total)-1 > math.MaxUint16 {
+		return fmt.Errorf("queue range %d-%d exceeds the maximum queue number", q.Num, uint32(q.Num)+uint32(q.Total)-1)
+	}
+	if q.Fanout && q.Total < 2 {
+		return fmt.Errorf("queue fanout requires a range of queues")
+	}
+	return nil
+}
+
+func queueExpr(q *Queue, l3proto uint8) []nftnl.ExprAttrs {
+	attrs := &nftnl.QueueAttrs{
+		Num:   q.Num,
+		Total: q.Total,
+	}
+	if q.Bypass {
+		attrs.Flags |= unix.NFT_QUEUE_FLAG_BYPASS
+	}
+	if q.Fanout {
+		attrs.Flags |= unix.NFT_QUEUE_FLAG_CPU_FANOUT
+	}
+
+	if q.NumFrom == nil {
+		return appendExpr(nil, attrs)
+	}
+
+	attrs.Num = 0
+	attrs.Total = 0
+	attrs.SRegQNum = 1
+	return appendExpr(q.NumFrom.marshal(l3proto, attrs.SRegQNum), attrs)
+}
+
+// queueFromExprs decodes the queue at exprs[i] and the source of its queue
+// number. A source that is not recognised is left unset.
+func queueFromExprs(exprs []nftnl.ExprAttrs, i int) *Queue {
+	attr := exprs[i].Data.(*nftnl.QueueAttrs)
+	q := &Queue{
+		Num:    attr.Num,
+		Total:  attr.Total,
+		Bypass: attr.Flags&unix.NFT_QUEUE_FLAG_BYPASS != 0,
+		Fanout: attr.Flags&unix.NFT_QUEUE_FLAG_CPU_FANOUT != 0,
+	}
+	if attr.SRegQNum != 0 && i > 0 {
+		q.NumFrom = queueNumSourceFromExprs(exprs, i-1, attr.SRegQNum)
+	}
+	// The kernel always reports the total, report a single queue as unset.
+	if q.Total == 1 {
+		q.Total = 0
+	}
+	return q
+}
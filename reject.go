@@ -0,0 +1,146 @@
+package nft
+
+import (
+	"fmt"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+type RejectType uint8
+
+const (
+	RejectTypeICMP     RejectType = 0x1
+	RejectTypeICMPv6   RejectType = 0x2
+	RejectTypeICMPx    RejectType = 0x3
+	RejectTypeTCPReset RejectType = 0x4
+)
+
+// ICMP destination unreachable codes.
+// https://www.iana.org/assignments/icmp-parameters/icmp-parameters.xhtml#icmp-parameters-codes-3
+const (
+	ICMPCodeNetUnreachable  = 0
+	ICMPCodeHostUnreachable = 1
+	ICMPCodeProtUnreachable = 2
+	ICMPCodePortUnreachable = 3
+	ICMPCodeNetProhibited   = 9
+	ICMPCodeHostProhibited  = 10
+	ICMPCodeAdminProhibited = 13
+)
+
+// ICMPv6 destination unreachable codes.
+// https://www.iana.org/assignments/icmpv6-parameters/icmpv6-parameters.xhtml#icmpv6-parameters-codes-2
+const (
+	ICMPv6CodeNoRoute         = 0
+	ICMPv6CodeAdminProhibited = 1
+	ICMPv6CodeAddrUnreachable = 3
+	ICMPv6CodePortUnreachable = 4
+	ICMPv6CodePolicyFail      = 5
+	ICMPv6CodeRejectRoute     = 6
+)
+
+// ICMPx codes are translated by the kernel to the matching ICMP or ICMPv6
+// code depending on the packet.
+const (
+	ICMPxCodeNoRoute         = unix.NFT_REJECT_ICMPX_NO_ROUTE
+	ICMPxCodePortUnreachable = unix.NFT_REJECT_ICMPX_PORT_UNREACH
+	ICMPxCodeHostUnreachable = unix.NFT_REJECT_ICMPX_HOST_UNREACH
+	ICMPxCodeAdminProhibited = unix.NFT_REJECT_ICMPX_ADMIN_PROHIBITED
+)
+
+type Reject struct {
+	// Type is the kind of reply sent back. If unset, a port unreachable
+	// reply appropriate for the rule's family is sent: ICMP for ipv4, ICMPv6
+	// for ipv6 and ICMPx otherwise, unless an inet rule pins its L3Proto.
+	// Dumped rules have the type and code the kernel reports.
+	Type RejectType
+	// Code is the ICMP, ICMPv6 or ICMPx code of the reply. It is ignored when
+	// Type is unset or RejectTypeTCPReset.
+	Code uint8
+}
+
+func (rj *Reject) validate(r *Rule) error {
+	typ, code := rj.resolve(r.Family, r.l3proto())
+	switch typ {
+	case RejectTypeICMP:
+		if r.Family != unix.NFPROTO_IPV4 && !(r.Family == unix.NFPROTO_INET && r.l3proto() == unix.NFPROTO_IPV4) {
+			return fmt.Errorf("reject with icmp requires the ipv4 family or L3 protocol IPv4")
+		}
+	case RejectTypeICMPv6:
+		if r.Family != unix.NFPROTO_IPV6 && !(r.Family == unix.NFPROTO_INET && r.l3proto() == unix.NFPROTO_IPV6) {
+			return fmt.Errorf("reject with icmpv6 requires the ipv6 family or L3 protocol IPv6")
+		}
+	case RejectTypeICMPx:
+		switch r.Family {
+		case unix.NFPROTO_INET, unix.NFPROTO_BRIDGE, unix.NFPROTO_NETDEV:
+		default:
+			return fmt.Errorf("reject with icmpx is only supported in the inet, bridge and netdev families")
+		}
+		if code > unix.NFT_REJECT_ICMPX_MAX {
+			return fmt.Errorf("invalid icmpx code %d", code)
+		}
+	case RejectTypeTCPReset:
+		if r.l4proto() != unix.IPPROTO_TCP {
+			return fmt.Errorf("reject with tcp reset requires L4 protocol TCP")
+		}
+	default:
+		return fmt.Errorf("unknown reject type %d", typ)
+	}
+	return nil
+}
+
+// resolve returns the reject type and code that are sent to the kernel,
+// filling in the family defaults when Type is unset.
+func (rj *Reject) resolve(family uint8, l3proto uint8) (RejectType, uint8) {
+	if rj.Type != 0 {
+		return rj.Type, rj.Code
+	}
+	if family == unix.NFPROTO_INET {
+		family = l3proto
+	}
+	switch family {
+	case unix.NFPROTO_IPV4:
+		return RejectTypeICMP, ICMPCodePortUnreachable
+	case unix.NFPROTO_IPV6:
+		return RejectTypeICMPv6, ICMPv6CodePortUnreachable
+	default:
+		return RejectTypeICMPx, ICMPxCodePortUnreachable
+	}
+}
+
+func rejectExpr(reject *Reject, family uint8, l3proto uint8) []nftnl.ExprAttrs {
+	typ, code := reject.resolve(family, l3proto)
+
+	attrs := &nftnl.RejectAttrs{
+		ICMPCode: code,
+	}
+	switch typ {
+	case RejectTypeICMP, RejectTypeICMPv6:
+		attrs.Type = unix.NFT_REJECT_ICMP_UNREACH
+	case RejectTypeICMPx:
+		attrs.Type = unix.NFT_REJECT_ICMPX_UNREACH
+	case RejectTypeTCPReset:
+		attrs.Type = unix.NFT_REJECT_TCP_RST
+		attrs.ICMPCode = 0
+	}
+
+	return appendExpr(nil, attrs)
+}
+
+func rejectFromExpr(attr *nftnl.RejectAttrs, family uint8, l3proto uint8) *Reject {
+	reject := &Reject{Code: attr.ICMPCode}
+	switch attr.Type {
+	case unix.NFT_REJECT_ICMP_UNREACH:
+		if family == unix.NFPROTO_IPV6 || (family == unix.NFPROTO_INET && l3proto == unix.NFPROTO_IPV6) {
+			reject.Type = RejectTypeICMPv6
+		} else {
+			reject.Type = RejectTypeICMP
+		}
+	case unix.NFT_REJECT_ICMPX_UNREACH:
+		reject.Type = RejectTypeICMPx
+	case unix.NFT_REJECT_TCP_RST:
+		reject.Type = RejectTypeTCPReset
+		reject.Code = 0
+	}
+	return reject
+}
@@ -0,0 +1,143 @@
+package nft
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+type RtKey uint8
+
+const (
+	RtKeyClassID RtKey = 0x1
+	// RtKeyNexthop is the gateway of the route, or the destination address
+	// for directly connected networks.
+	RtKeyNexthop RtKey = 0x2
+	// RtKeyTCPMSS is the TCP maximum segment size derived from the route
+	// MTU.
+	RtKeyTCPMSS RtKey = 0x3
+	// RtKeyIPsec reports whether the route uses an IPsec transformation.
+	RtKeyIPsec RtKey = 0x4
+)
+
+// RtMatch compares routing information of the packet. The routing decision
+// is only available after routing, so the kernel rejects most keys in the
+// prerouting and input hooks.
+type RtMatch struct {
+	Key     RtKey
+	ClassID uint32
+	Nexthop *netip.Addr
+	TCPMSS  uint16
+	IPsec   bool
+}
+
+func (m *RtMatch) validate(r *Rule) error {
+	switch r.Family {
+	case unix.NFPROTO_IPV4, unix.NFPROTO_IPV6, unix.NFPROTO_INET:
+	default:
+		return fmt.Errorf("rt is only supported in the ipv4, ipv6 and inet families")
+	}
+	switch m.Key {
+	case RtKeyClassID, RtKeyTCPMSS, RtKeyIPsec:
+	case RtKeyNexthop:
+		if m.Nexthop == nil || !m.Nexthop.IsValid() {
+			return fmt.Errorf("rt nexthop requires an address")
+		}
+		l3proto := r.l3proto()
+		if m.Nexthop.Is4() && l3proto == unix.NFPROTO_IPV6 ||
+			!m.Nexthop.Is4() && l3proto == unix.NFPROTO_IPV4 {
+			return fmt.Errorf("rt nexthop address family does not match the rule")
+		}
+	default:
+		return fmt.Errorf("unknown rt key %d", m.Key)
+	}
+	return nil
+}
+
+func rtExpr(m *RtMatch) []nftnl.ExprAttrs {
+	attrs := &nftnl.RtAttrs{DReg: 1}
+
+	var value []byte
+	switch m.Key {
+	case RtKeyClassID:
+		attrs.Key = unix.NFT_RT_CLASSID
+		value = make([]byte, 4)
+		binary.NativeEndian.PutUint32(value, m.ClassID)
+	case RtKeyNexthop:
+		if m.Nexthop.Is4() {
+			attrs.Key = unix.NFT_RT_NEXTHOP4
+		} else {
+			attrs.Key = unix.NFT_RT_NEXTHOP6
+		}
+		value = m.Nexthop.AsSlice()
+	case RtKeyTCPMSS:
+		attrs.Key = unix.NFT_RT_TCPMSS
+		value = make([]byte, 2)
+		binary.NativeEndian.PutUint16(value, m.TCPMSS)
+	case RtKeyIPsec:
+		attrs.Key = unixext.NFT_RT_XFRM
+		value = []byte{0}
+		if m.IPsec {
+			value[0] = 1
+		}
+	}
+
+	return appendExpr(nil,
+		attrs,
+		&nftnl.CmpAttrs{
+			SReg: 1,
+			Op:   unix.NFT_CMP_EQ,
+			Data: &nftnl.DataAttrs{
+				Value: value,
+			},
+		},
+	)
+}
+
+func (r *Rule) unmarshalRtExprs(attrs *nftnl.RuleAttrs) {
+	for i := 0; i+1 < len(attrs.Expressions); i++ {
+		rt, ok := attrs.Expressions[i].Data.(*nftnl.RtAttrs)
+		if !ok {
+			continue
+		}
+		cmp, ok := attrs.Expressions[i+1].Data.(*nftnl.CmpAttrs)
+		if !ok || cmp.Op != unix.NFT_CMP_EQ || cmp.Data == nil || len(cmp.Data.Value) == 0 {
+			continue
+		}
+
+		m := &RtMatch{}
+		value := cmp.Data.Value
+		switch rt.Key {
+		case unix.NFT_RT_CLASSID:
+			if len(value) < 4 {
+				continue
+			}
+			m.Key = RtKeyClassID
+			m.ClassID = binary.NativeEndian.Uint32(value)
+		case unix.NFT_RT_NEXTHOP4, unix.NFT_RT_NEXTHOP6:
+			addr, ok := netip.AddrFromSlice(value)
+			if !ok {
+				continue
+			}
+			m.Key = RtKeyNexthop
+			m.Nexthop = &addr
+		case unix.NFT_RT_TCPMSS:
+			if len(value) < 2 {
+				continue
+			}
+			m.Key = RtKeyTCPMSS
+			m.TCPMSS = binary.NativeEndian.Uint16(value)
+		case unixext.NFT_RT_XFRM:
+			m.Key = RtKeyIPsec
+			m.IPsec = value[0] != 0
+		default:
+			continue
+		}
+		r.Rt = m
+		i++
+	}
+}
@@ -3,6 +3,7 @@ package nft
 import (
 	"fmt"
 	"net/netip"
+	"time"
 
 	"github.com/mdlayher/netlink"
 	"github.com/nickgarlis/go-nft/nftnl"
@@ -14,12 +15,22 @@ type IPMatch struct {
 	Prefix *netip.Prefix
 	Set    string
 	SetID  uint32
+	// Negate matches the addresses outside of Addr, Prefix or Set.
+	Negate bool
 }
 
+// PortMatch matches a transport port against exactly one of a single
+// port, a range, a list or a named set.
 type PortMatch struct {
-	Port  uint16
+	Port uint16
+	// MaxPort makes the match a range from Port to MaxPort, inclusive.
+	MaxPort uint16
+	// Ports are stored in an anonymous set created with the rule.
+	Ports []uint16
 	Set   string
 	SetID uint32
+	// Negate matches the ports that are not matched otherwise.
+	Negate bool
 }
 
 type CtMatch struct {
@@ -30,6 +41,7 @@ type CtMatch struct {
 	SrcPort *PortMatch
 	DstPort *PortMatch
 	States  []CtState
+	Mark    *MarkMatch
 }
 
 type Counter struct {
@@ -48,7 +60,12 @@ type Verdict struct {
 }
 
 type Action struct {
+	Log     *Log
 	Verdict *Verdict
+	Reject  *Reject
+	Queue   *Queue
+	Nat     *Nat
+	Fwd     *Fwd
 }
 
 type Rule struct {
@@ -60,18 +77,79 @@ type Rule struct {
 	Handle  uint64
 	L3Proto uint8
 	L4Proto uint8
-	IIface  string
-	OIface  string
-	SrcIPv4 *IPMatch
-	DstIPv4 *IPMatch
-	SrcIPv6 *IPMatch
-	DstIPv6 *IPMatch
-	SrcPort *PortMatch
-	DstPort *PortMatch
+	// IIface and OIface match the interface names. A trailing '*' matches
+	// all the names starting with the rest, such as "veth*".
+	IIface string
+	OIface string
+	// NegateL3Proto, NegateL4Proto, NegateIIface and NegateOIface invert
+	// the comparisons of the fields above.
+	NegateL3Proto bool
+	NegateL4Proto bool
+	NegateIIface  bool
+	NegateOIface  bool
+	IIfaceMatch   *IfaceMatch
+	OIfaceMatch   *IfaceMatch
+	Ether         *EtherMatch
+	VLAN          *VLANMatch
+	ServiceVLAN   *VLANMatch
+	ARP           *ARPMatch
+	PktType       *PacketType
+	SrcIPv4       *IPMatch
+	DstIPv4       *IPMatch
+	SrcIPv6       *IPMatch
+	DstIPv6       *IPMatch
+	SrcPort       *PortMatch
+	DstPort       *PortMatch
+	ICMP          *ICMPMatch
+	ICMPv6        *ICMPv6Match
+	TCPFlags      *TCPFlagsMatch
+	Mark          *MarkMatch
+	SkUID         *OwnerMatch
+	SkGID         *OwnerMatch
+	Time          *TimeMatch
+	Fib           *FibMatch
+	Rt            *RtMatch
+	Socket        *SocketMatch
+	// Xfrm match properties of the IPsec states of the packet.
+	Xfrm []*XfrmMatch
+	Osf  *OsfMatch
+	// Inner matches the headers encapsulated in a VXLAN, Geneve or GRE
+	// tunnel.
+	Inner  *InnerMatch
+	Tunnel *TunnelMatch
+	// XtMatches are iptables match extensions, as added by iptables-nft.
+	XtMatches []*XtMatch
+	// Exthdrs match IPv6 extension headers and TCP options.
+	Exthdrs []*ExthdrMatch
 	Ct      *CtMatch
-	Counter *Counter
-	Quota   *Quota
-	Action  *Action
+	// Connlimit matches on the number of connections tracked by the rule.
+	Connlimit *Connlimit
+	Counter   *Counter
+	// Last records when the rule last matched. It is filled in on dump.
+	Last  *Last
+	Quota *Quota
+	Limit *Limit
+	// ObjectRefs applies named objects, such as a shared limit, to the
+	// matched packets.
+	ObjectRefs []*ObjectRef
+	// SetUpdates add elements to dynamic sets.
+	SetUpdates []*SetUpdate
+	// MetaSets write packet and connection metadata, such as the mark.
+	MetaSets []*MetaSet
+	// PayloadSets rewrite packet headers.
+	PayloadSets []*PayloadSet
+	// ExthdrSets rewrite TCP options, such as clamping the MSS.
+	ExthdrSets []*ExthdrSet
+	Tproxy     *Tproxy
+	// Dup sends a copy of the matched packets elsewhere.
+	Dup      *Dup
+	Synproxy *Synproxy
+	// Notrack exempts the matched packets from connection tracking. The
+	// kernel only allows it in the prerouting and output hooks.
+	Notrack bool
+	// XtTarget is an iptables target extension, evaluated before Action.
+	XtTarget *XtTarget
+	Action   *Action
 }
 
 func (r *Rule) validateCreate() error {
@@ -85,25 +163,272 @@ func (r *Rule) validateCreate() error {
 		return fmt.Errorf("family must be specified")
 	}
 	if r.Family == unix.NFPROTO_INET {
-		if r.L3Proto == 0 &&
+		if r.l3proto() == 0 &&
 			(r.SrcIPv4 != nil || r.DstIPv4 != nil || r.SrcIPv6 != nil || r.DstIPv6 != nil) {
 			return fmt.Errorf("L3 protocol must be specified for inet family when matching on IP addresses")
 		}
 
-		if r.L3Proto == unix.NFPROTO_IPV4 && (r.SrcIPv6 != nil || r.DstIPv6 != nil) {
+		if r.l3proto() == unix.NFPROTO_IPV4 && (r.SrcIPv6 != nil || r.DstIPv6 != nil) {
 			return fmt.Errorf("cannot match on IPv6 addresses when L3 protocol is IPv4")
 		}
 
-		if r.L3Proto == unix.NFPROTO_IPV6 && (r.SrcIPv4 != nil || r.DstIPv4 != nil) {
+		if r.l3proto() == unix.NFPROTO_IPV6 && (r.SrcIPv4 != nil || r.DstIPv4 != nil) {
 			return fmt.Errorf("cannot match on IPv4 addresses when L3 protocol is IPv6")
 		}
 	}
 
+	if err := r.validateNegations(); err != nil {
+		return err
+	}
+
+	if err := r.validateIfaces(); err != nil {
+		return err
+	}
+
+	if err := r.validateEther(); err != nil {
+		return err
+	}
+
+	if r.ARP != nil {
+		if err := r.ARP.validate(r); err != nil {
+			return err
+		}
+	}
+
+	if err := r.validatePorts(); err != nil {
+		return err
+	}
+
+	if err := r.validateICMP(); err != nil {
+		return err
+	}
+
+	for _, m := range []*OwnerMatch{r.SkUID, r.SkGID} {
+		if m == nil {
+			continue
+		}
+		if err := m.validate(); err != nil {
+			return err
+		}
+	}
+
+	if r.Time != nil {
+		if err := r.Time.validate(); err != nil {
+			return err
+		}
+	}
+
+	if r.TCPFlags != nil && r.l4proto() != unix.IPPROTO_TCP {
+		return fmt.Errorf("tcp flags matching requires the tcp L4 protocol")
+	}
+
+	if r.Fib != nil {
+		if err := r.Fib.validate(r); err != nil {
+			return err
+		}
+	}
+
+	if r.Rt != nil {
+		if err := r.Rt.validate(r); err != nil {
+			return err
+		}
+	}
+
+	if r.Socket != nil {
+		if err := r.Socket.validate(r); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range r.Xfrm {
+		if err := m.validate(r); err != nil {
+			return err
+		}
+	}
+
+	if r.Osf != nil {
+		if err := r.Osf.validate(r); err != nil {
+			return err
+		}
+	}
+
+	if r.Inner != nil {
+		if err := r.Inner.validate(r); err != nil {
+			return err
+		}
+	}
+
+	if r.Tunnel != nil {
+		if err := r.Tunnel.validate(r); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range r.Exthdrs {
+		if err := m.validate(r); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range r.XtMatches {
+		if err := validateXtName(m.Name); err != nil {
+			return err
+		}
+	}
+
+	if r.XtTarget != nil {
+		if err := validateXtName(r.XtTarget.Name); err != nil {
+			return err
+		}
+	}
+
+	if r.Limit != nil {
+		if err := r.Limit.validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, ref := range r.ObjectRefs {
+		if err := ref.validate(r); err != nil {
+			return err
+		}
+	}
+
+	for _, u := range r.SetUpdates {
+		if err := u.validate(r); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range r.MetaSets {
+		if err := s.validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range r.PayloadSets {
+		if err := s.validate(r); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range r.ExthdrSets {
+		if err := s.validate(r); err != nil {
+			return err
+		}
+	}
+
+	if r.Tproxy != nil {
+		if err := r.Tproxy.validate(r); err != nil {
+			return err
+		}
+	}
+
+	if r.Dup != nil {
+		if err := r.Dup.validate(r); err != nil {
+			return err
+		}
+	}
+
+	if r.Synproxy != nil {
+		if err := r.Synproxy.validateStatement(r); err != nil {
+			return err
+		}
+	}
+
+	if r.Action != nil {
+		if r.Action.Log != nil {
+			if err := r.Action.Log.validate(); err != nil {
+				return err
+			}
+		}
+		if r.Action.Reject != nil {
+			if r.Action.Verdict != nil {
+				return fmt.Errorf("reject and verdict cannot be combined in the same rule")
+			}
+			if err := r.Action.Reject.validate(r); err != nil {
+				return err
+			}
+		}
+		if r.Action.Queue != nil {
+			if r.Action.Verdict != nil || r.Action.Reject != nil {
+				return fmt.Errorf("queue cannot be combined with a verdict or reject in the same rule")
+			}
+			if err := r.Action.Queue.validate(r); err != nil {
+				return err
+			}
+		}
+		if r.Action.Nat != nil {
+			if r.Action.Verdict != nil || r.Action.Reject != nil || r.Action.Queue != nil {
+				return fmt.Errorf("nat cannot be combined with a verdict, reject or queue in the same rule")
+			}
+			if err := r.Action.Nat.validate(r); err != nil {
+				return err
+			}
+		}
+		if r.Action.Fwd != nil {
+			if r.Action.Verdict != nil || r.Action.Reject != nil || r.Action.Queue != nil || r.Action.Nat != nil {
+				return fmt.Errorf("fwd cannot be combined with a verdict, reject, queue or nat in the same rule")
+			}
+			if err := r.Action.Fwd.validate(r); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
+func (r *Rule) validateNegations() error {
+	switch {
+	case r.NegateL3Proto && r.L3Proto == 0:
+		return fmt.Errorf("cannot negate an unset L3 protocol")
+	case r.NegateL4Proto && r.L4Proto == 0:
+		return fmt.Errorf("cannot negate an unset L4 protocol")
+	case r.NegateIIface && r.IIface == "":
+		return fmt.Errorf("cannot negate an unset input interface")
+	case r.NegateOIface && r.OIface == "":
+		return fmt.Errorf("cannot negate an unset output interface")
+	case r.NegateL4Proto && (r.SrcPort != nil || r.DstPort != nil || r.ICMP != nil || r.ICMPv6 != nil || r.TCPFlags != nil):
+		return fmt.Errorf("port, icmp and tcp flags matching require a non-negated L4 protocol")
+	}
+	return nil
+}
+
+// l3proto returns the network protocol of the packets the rule applies to,
+// or 0 if it is not known.
+func (r *Rule) l3proto() uint8 {
+	switch r.Family {
+	case unix.NFPROTO_IPV4, unix.NFPROTO_IPV6:
+		return r.Family
+	}
+	if r.NegateL3Proto {
+		return 0
+	}
+	return r.L3Proto
+}
+
+// l4proto returns the transport protocol of the packets the rule applies
+// to, or 0 if it is not known. ICMP and TCP flag matches imply their
+// protocol when L4Proto is unset.
+func (r *Rule) l4proto() uint8 {
+	switch {
+	case r.NegateL4Proto:
+		return 0
+	case r.L4Proto != 0:
+		return r.L4Proto
+	case r.ICMP != nil:
+		return unix.IPPROTO_ICMP
+	case r.ICMPv6 != nil:
+		return unix.IPPROTO_ICMPV6
+	case r.TCPFlags != nil:
+		return unix.IPPROTO_TCP
+	}
+	return 0
+}
+
 func (r *Rule) marshal() *nftnl.RuleAttrs {
-	return &nftnl.RuleAttrs{
+	attrs := &nftnl.RuleAttrs{
 		Table:       r.Table,
 		Chain:       r.Chain,
 		ID:          r.ID,
@@ -111,6 +436,11 @@ func (r *Rule) marshal() *nftnl.RuleAttrs {
 		ChainID:     r.ChainID,
 		Expressions: r.marshalExprs(),
 	}
+	// Extensions such as multiport check the protocol of the rule.
+	if l4proto := r.l4proto(); (len(r.XtMatches) > 0 || r.XtTarget != nil) && l4proto != 0 {
+		attrs.Compat = &nftnl.RuleCompatAttrs{Proto: uint32(l4proto)}
+	}
+	return attrs
 }
 
 func (r *Rule) unmarshal(family uint8, attrs *nftnl.RuleAttrs) {
@@ -121,7 +451,29 @@ func (r *Rule) unmarshal(family uint8, attrs *nftnl.RuleAttrs) {
 	r.Handle = attrs.Handle
 	r.ChainID = attrs.ChainID
 
+	r.unmarshalMetaExprs(attrs)
+	r.unmarshalIfaceExprs(attrs)
+	r.unmarshalEtherExprs(attrs)
+	r.unmarshalARPExprs(attrs)
+	r.unmarshalOwnerExprs(attrs)
+	r.unmarshalTimeExprs(attrs)
 	r.unmarshalPrefixExprs(attrs)
+	r.unmarshalPortExprs(attrs)
+	r.unmarshalICMPExprs(attrs)
+	r.unmarshalTCPFlagsExprs(attrs)
+	r.unmarshalFibExprs(attrs)
+	r.unmarshalRtExprs(attrs)
+	r.unmarshalSocketExprs(attrs)
+	r.unmarshalXfrmExprs(attrs)
+	r.unmarshalOsfExprs(attrs)
+	r.unmarshalInnerExprs(attrs)
+	r.unmarshalTunnelExprs(attrs)
+	r.unmarshalExthdrExprs(attrs)
+	r.unmarshalStatementExprs(attrs)
+	r.unmarshalMetaSetExprs(attrs)
+	r.unmarshalPayloadSetExprs(attrs)
+	r.unmarshalXtExprs(attrs)
+	r.unmarshalActionExprs(attrs)
 }
 
 func (c *Conn) getRules(family uint8, table string, chain string, handle uint64) ([]*Rule, error) {
@@ -158,6 +510,18 @@ func (c *Conn) getRules(family uint8, table string, chain string, handle uint64)
 	for i, a := range attrs {
 		r := &Rule{}
 		r.unmarshal(family, a)
+		if err := c.getNatMapElems(r, a); err != nil {
+			return nil, err
+		}
+		if err := c.getPortSetElems(r); err != nil {
+			return nil, err
+		}
+		if err := c.getICMPSetElems(r, a); err != nil {
+			return nil, err
+		}
+		if err := c.getTimeSetElems(r, a); err != nil {
+			return nil, err
+		}
 		rules[i] = r
 	}
 	return rules, nil
@@ -193,7 +557,27 @@ func (b *Batch) NewRule(rule *Rule) error {
 	if err := rule.validateCreate(); err != nil {
 		return err
 	}
+	// The time match is converted to UTC once, with the offset of its
+	// location at the time the rule is added.
+	r := rule
+	if rule.Time != nil {
+		m, err := rule.Time.inUTC(time.Now())
+		if err != nil {
+			return err
+		}
+		utc := *rule
+		utc.Time = m
+		r = &utc
+	}
 	rule.ID = b.newID()
+	r.ID = rule.ID
+	// The IDs of the anonymous sets follow the rule ID.
+	for range r.anonSets() {
+		b.newID()
+	}
+	for _, msg := range anonSetMsgs(r) {
+		b.nftnlBatch.Add(msg)
+	}
 	b.nftnlBatch.Add(nftnl.Msg{
 		Header: nftnl.Header{
 			SubsysID: unix.NFNL_SUBSYS_NFTABLES,
@@ -203,7 +587,7 @@ func (b *Batch) NewRule(rule *Rule) error {
 		NfGenMsg: nftnl.NfGenMsg{
 			Family: rule.Family,
 		},
-		Attrs: rule.marshal(),
+		Attrs: r.marshal(),
 	})
 	return nil
 }
@@ -0,0 +1,213 @@
+package nft
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+type SocketKey uint8
+
+const (
+	// SocketKeyTransparent matches whether the socket found for the packet
+	// has the IP_TRANSPARENT option set.
+	SocketKeyTransparent SocketKey = 0x1
+	SocketKeyMark        SocketKey = 0x2
+	// SocketKeyWildcard matches whether the socket is bound to the wildcard
+	// address.
+	SocketKeyWildcard SocketKey = 0x3
+	// SocketKeyCgroupV2 compares the ancestor at Level of the socket's
+	// cgroup with CgroupID.
+	SocketKeyCgroupV2 SocketKey = 0x4
+)
+
+// SocketMatch looks up the local socket the packet belongs to and compares
+// one of its properties. Packets without a socket never match. The kernel
+// only allows the lookup in the prerouting, input and output hooks.
+type SocketMatch struct {
+	Key         SocketKey
+	Transparent bool
+	Mark        *MarkMatch
+	Wildcard    bool
+	Level       uint32
+	CgroupID    uint64
+}
+
+func (m *SocketMatch) validate(r *Rule) error {
+	switch r.Family {
+	case unix.NFPROTO_IPV4, unix.NFPROTO_IPV6, unix.NFPROTO_INET:
+	default:
+		return fmt.Errorf("socket is only supported in the ipv4, ipv6 and inet families")
+	}
+	switch m.Key {
+	case SocketKeyTransparent, SocketKeyWildcard:
+	case SocketKeyMark:
+		if m.Mark == nil {
+			return fmt.Errorf("socket mark requires a mark to compare")
+		}
+	case SocketKeyCgroupV2:
+		if m.Level > 255 {
+			return fmt.Errorf("socket cgroupv2 level must be at most 255")
+		}
+	default:
+		return fmt.Errorf("unknown socket key %d", m.Key)
+	}
+	return nil
+}
+
+// NewSocketCgroupV2Match returns a match of the sockets created in the
+// cgroup v2 at cgroupPath, such as "/system.slice/foo.service", or in any
+// of its descendants. cgroupPath is relative to the cgroup2 mount point.
+//
+// The kernel compares the ID of the cgroup, so the match has to be
+// recreated when the cgroup is removed and created again.
+func NewSocketCgroupV2Match(cgroupPath string) (*SocketMatch, error) {
+	cgroupPath = path.Clean("/" + cgroupPath)
+	id, err := CgroupV2ID(cgroupPath)
+	if err != nil {
+		return nil, err
+	}
+	var level uint32
+	if cgroupPath != "/" {
+		level = uint32(strings.Count(cgroupPath, "/"))
+	}
+	return &SocketMatch{
+		Key:      SocketKeyCgroupV2,
+		Level:    level,
+		CgroupID: id,
+	}, nil
+}
+
+// CgroupV2ID returns the ID of the cgroup v2 at cgroupPath, relative to the
+// cgroup2 mount point. The ID is the inode number of the cgroup directory.
+func CgroupV2ID(cgroupPath string) (uint64, error) {
+	mount, err := cgroupV2Mount()
+	if err != nil {
+		return 0, err
+	}
+	var st unix.Stat_t
+	if err := unix.Stat(filepath.Join(mount, cgroupPath), &st); err != nil {
+		return 0, fmt.Errorf("failed to stat cgroup %q: %w", cgroupPath, err)
+	}
+	return st.Ino, nil
+}
+
+// cgroupV2Mount returns the mount point of the cgroup2 file system, which
+// is /sys/fs/cgroup/unified on hosts using the hybrid hierarchy.
+func cgroupV2Mount() (string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// The file system type follows the "-" separator.
+		fields := strings.Fields(scanner.Text())
+		for i, field := range fields {
+			if field == "-" && i+1 < len(fields) && i >= 4 {
+				if fields[i+1] == "cgroup2" {
+					return fields[4], nil
+				}
+				break
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("cgroup2 file system is not mounted")
+}
+
+func socketExpr(m *SocketMatch) []nftnl.ExprAttrs {
+	attrs := &nftnl.SocketAttrs{DReg: 1}
+
+	var value []byte
+	switch m.Key {
+	case SocketKeyTransparent:
+		attrs.Key = unixext.NFT_SOCKET_TRANSPARENT
+		value = []byte{0}
+		if m.Transparent {
+			value[0] = 1
+		}
+	case SocketKeyMark:
+		attrs.Key = unixext.NFT_SOCKET_MARK
+		return markMatchExpr(attrs, m.Mark)
+	case SocketKeyWildcard:
+		attrs.Key = unixext.NFT_SOCKET_WILDCARD
+		value = []byte{0}
+		if m.Wildcard {
+			value[0] = 1
+		}
+	case SocketKeyCgroupV2:
+		attrs.Key = unixext.NFT_SOCKET_CGROUPV2
+		attrs.Level = m.Level
+		value = make([]byte, 8)
+		binary.NativeEndian.PutUint64(value, m.CgroupID)
+	}
+
+	return appendExpr(nil,
+		attrs,
+		&nftnl.CmpAttrs{
+			SReg: 1,
+			Op:   unix.NFT_CMP_EQ,
+			Data: &nftnl.DataAttrs{
+				Value: value,
+			},
+		},
+	)
+}
+
+func (r *Rule) unmarshalSocketExprs(attrs *nftnl.RuleAttrs) {
+	exprs := attrs.Expressions
+	for i := 0; i+1 < len(exprs); i++ {
+		socket, ok := exprs[i].Data.(*nftnl.SocketAttrs)
+		if !ok {
+			continue
+		}
+
+		if socket.Key == unixext.NFT_SOCKET_MARK {
+			if mark, n := markMatchFromExprs(exprs[i+1:]); mark != nil {
+				r.Socket = &SocketMatch{Key: SocketKeyMark, Mark: mark}
+				i += n
+			}
+			continue
+		}
+
+		cmp, ok := exprs[i+1].Data.(*nftnl.CmpAttrs)
+		if !ok || cmp.Op != unix.NFT_CMP_EQ || cmp.Data == nil || len(cmp.Data.Value) == 0 {
+			continue
+		}
+
+		m := &SocketMatch{}
+		value := cmp.Data.Value
+		switch socket.Key {
+		case unixext.NFT_SOCKET_TRANSPARENT:
+			m.Key = SocketKeyTransparent
+			m.Transparent = value[0] != 0
+		case unixext.NFT_SOCKET_WILDCARD:
+			m.Key = SocketKeyWildcard
+			m.Wildcard = value[0] != 0
+		case unixext.NFT_SOCKET_CGROUPV2:
+			if len(value) < 8 {
+				continue
+			}
+			m.Key = SocketKeyCgroupV2
+			m.Level = socket.Level
+			m.CgroupID = binary.NativeEndian.Uint64(value)
+		default:
+			continue
+		}
+		r.Socket = m
+		i++
+	}
+}
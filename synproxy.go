@@ -0,0 +1,228 @@
+package nft
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/mdlayher/netlink"
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+// synproxyMaxWscale mirrors TCP_MAX_WSCALE.
+const synproxyMaxWscale = 14
+
+// Synproxy answers TCP SYN packets with a SYN cookie on behalf of the
+// server, and only opens the connection to the server once the client has
+// completed the handshake. The packets must not be tracked by conntrack
+// until then, see NewSynproxy. The kernel only allows synproxy in the input
+// and forward hooks.
+type Synproxy struct {
+	// MSS advertised to the client when non-zero. It should match the MSS
+	// of the server.
+	MSS uint16
+	// Wscale is the window scale advertised to the client when non-zero.
+	Wscale    uint8
+	Timestamp bool
+	SackPerm  bool
+}
+
+func (s *Synproxy) validate() error {
+	if s.Wscale > synproxyMaxWscale {
+		return fmt.Errorf("synproxy window scale must be at most %d", synproxyMaxWscale)
+	}
+	return nil
+}
+
+func (s *Synproxy) validateStatement(r *Rule) error {
+	switch r.Family {
+	case unix.NFPROTO_IPV4, unix.NFPROTO_IPV6, unix.NFPROTO_INET:
+	default:
+		return fmt.Errorf("synproxy is only supported in the ipv4, ipv6 and inet families")
+	}
+	return s.validate()
+}
+
+func (s *Synproxy) marshal() *nftnl.SynproxyAttrs {
+	attrs := &nftnl.SynproxyAttrs{
+		MSS:    s.MSS,
+		Wscale: s.Wscale,
+	}
+	if s.MSS != 0 {
+		attrs.Flags |= unixext.NF_SYNPROXY_OPT_MSS
+	}
+	if s.Wscale != 0 {
+		attrs.Flags |= unixext.NF_SYNPROXY_OPT_WSCALE
+	}
+	if s.Timestamp {
+		attrs.Flags |= unixext.NF_SYNPROXY_OPT_TIMESTAMP
+	}
+	if s.SackPerm {
+		attrs.Flags |= unixext.NF_SYNPROXY_OPT_SACK_PERM
+	}
+	return attrs
+}
+
+func synproxyFromAttrs(attr *nftnl.SynproxyAttrs) *Synproxy {
+	s := &Synproxy{
+		Timestamp: attr.Flags&unixext.NF_SYNPROXY_OPT_TIMESTAMP != 0,
+		SackPerm:  attr.Flags&unixext.NF_SYNPROXY_OPT_SACK_PERM != 0,
+	}
+	if attr.Flags&unixext.NF_SYNPROXY_OPT_MSS != 0 {
+		s.MSS = attr.MSS
+	}
+	if attr.Flags&unixext.NF_SYNPROXY_OPT_WSCALE != 0 {
+		s.Wscale = attr.Wscale
+	}
+	return s
+}
+
+// SynproxySetup describes a TCP port protected by a SYN proxy.
+type SynproxySetup struct {
+	Family uint8
+	Table  string
+	// RawChain must be hooked at prerouting with a priority lower than
+	// conntrack, such as -300.
+	RawChain string
+	// FilterChain must be hooked at input or forward.
+	FilterChain string
+	Port        uint16
+	Synproxy    Synproxy
+}
+
+// NewSynproxy adds the rules protecting setup.Port with a SYN proxy:
+//
+//   - SYN packets to the port are not tracked, in RawChain,
+//   - untracked and invalid packets to the port go through the synproxy
+//     statement, in FilterChain,
+//   - the remaining invalid packets to the port, such as ACKs with a bad
+//     cookie, are dropped, in FilterChain.
+//
+// The chains must already exist. The net.netfilter.nf_conntrack_tcp_loose
+// sysctl must be 0, otherwise conntrack accepts the final ACK of the
+// handshake as a new connection instead of handing it to the synproxy.
+func (b *Batch) NewSynproxy(setup *SynproxySetup) error {
+	switch setup.Family {
+	case unix.NFPROTO_IPV4, unix.NFPROTO_IPV6, unix.NFPROTO_INET:
+	default:
+		return fmt.Errorf("synproxy is only supported in the ipv4, ipv6 and inet families")
+	}
+	if setup.Table == "" || setup.RawChain == "" || setup.FilterChain == "" {
+		return fmt.Errorf("table, raw chain and filter chain names must be specified")
+	}
+	if setup.Port == 0 {
+		return fmt.Errorf("synproxy port must be specified")
+	}
+	if err := setup.Synproxy.validate(); err != nil {
+		return err
+	}
+
+	port := func() []nftnl.ExprAttrs { return synproxyPortExpr(setup.Port) }
+	rules := []*nftnl.RuleAttrs{
+		{
+			Chain: setup.RawChain,
+			Expressions: appendExpr(append(port(), tcpSynExpr()...),
+				&nftnl.NotrackAttrs{},
+			),
+		},
+		{
+			Chain: setup.FilterChain,
+			Expressions: appendExpr(append(port(), ctStateExpr([]CtState{CtStateInvalid, CtStateUntracked})...),
+				setup.Synproxy.marshal(),
+			),
+		},
+		{
+			Chain: setup.FilterChain,
+			Expressions: appendExpr(append(port(), ctStateExpr([]CtState{CtStateInvalid})...),
+				&nftnl.ImmediateAttrs{
+					DReg: unix.NFT_REG_VERDICT,
+					Data: &nftnl.VerdictAttrs{
+						Code: uint32(VerdictCodeDrop),
+					},
+				},
+			),
+		},
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, rule := range rules {
+		rule.Table = setup.Table
+		rule.ID = b.newID()
+		b.nftnlBatch.Add(nftnl.Msg{
+			Header: nftnl.Header{
+				SubsysID: unix.NFNL_SUBSYS_NFTABLES,
+				MsgType:  unix.NFT_MSG_NEWRULE,
+				Flags:    netlink.Request | netlink.Acknowledge | netlink.Create | netlink.Append,
+			},
+			NfGenMsg: nftnl.NfGenMsg{
+				Family: setup.Family,
+			},
+			Attrs: rule,
+		})
+	}
+	return nil
+}
+
+// synproxyPortExpr matches TCP packets to port.
+func synproxyPortExpr(port uint16) []nftnl.ExprAttrs {
+	value := make([]byte, 2)
+	binary.BigEndian.PutUint16(value, port)
+	return appendExpr(nil,
+		&nftnl.MetaAttrs{
+			DReg: 1,
+			Key:  unix.NFT_META_L4PROTO,
+		},
+		&nftnl.CmpAttrs{
+			SReg: 1,
+			Op:   unix.NFT_CMP_EQ,
+			Data: &nftnl.DataAttrs{
+				Value: []byte{unix.IPPROTO_TCP},
+			},
+		},
+		&nftnl.PayloadAttrs{
+			DReg:   1,
+			Base:   unix.NFT_PAYLOAD_TRANSPORT_HEADER,
+			Offset: 2,
+			Len:    2,
+		},
+		&nftnl.CmpAttrs{
+			SReg: 1,
+			Op:   unix.NFT_CMP_EQ,
+			Data: &nftnl.DataAttrs{
+				Value: value,
+			},
+		},
+	)
+}
+
+// tcpSynExpr matches TCP packets with SYN set among FIN, SYN, RST and ACK.
+func tcpSynExpr() []nftnl.ExprAttrs {
+	return appendExpr(nil,
+		&nftnl.PayloadAttrs{
+			DReg:   1,
+			Base:   unix.NFT_PAYLOAD_TRANSPORT_HEADER,
+			Offset: 13,
+			Len:    1,
+		},
+		&nftnl.BitwiseAttrs{
+			SReg: 1,
+			DReg: 1,
+			Len:  1,
+			Mask: &nftnl.DataAttrs{
+				Value: []byte{0x17},
+			},
+			Xor: &nftnl.DataAttrs{
+				Value: []byte{0},
+			},
+		},
+		&nftnl.CmpAttrs{
+			SReg: 1,
+			Op:   unix.NFT_CMP_EQ,
+			Data: &nftnl.DataAttrs{
+				Value: []byte{0x02},
+			},
+		},
+	)
+}
@@ -0,0 +1,103 @@
+package nft
+
+import (
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+// TCPFlags are the control bits of the TCP header.
+type TCPFlags uint8
+
+const (
+	TCPFlagFin TCPFlags = 0x01
+	TCPFlagSyn TCPFlags = 0x02
+	TCPFlagRst TCPFlags = 0x04
+	TCPFlagPsh TCPFlags = 0x08
+	TCPFlagAck TCPFlags = 0x10
+	TCPFlagUrg TCPFlags = 0x20
+	TCPFlagEce TCPFlags = 0x40
+	TCPFlagCwr TCPFlags = 0x80
+)
+
+// TCPFlagsMatch matches TCP packets whose flags, masked by Mask, equal
+// Flags. For example, new connections are Flags TCPFlagSyn with Mask
+// TCPFlagSyn|TCPFlagAck|TCPFlagRst|TCPFlagFin. The TCP protocol is implied,
+// so the L4Proto of the rule may be left unset and decodes to 0.
+type TCPFlagsMatch struct {
+	Flags TCPFlags
+	// Mask selects the compared flags. If unset, all the flags are
+	// compared, so a NULL scan is a match of zero Flags.
+	Mask TCPFlags
+}
+
+// tcpFlagsOffset is the offset of the flags in the TCP header.
+const tcpFlagsOffset = 13
+
+func tcpFlagsExpr(m *TCPFlagsMatch) []nftnl.ExprAttrs {
+	exprs := appendExpr(nil,
+		&nftnl.PayloadAttrs{
+			DReg:   1,
+			Base:   unix.NFT_PAYLOAD_TRANSPORT_HEADER,
+			Offset: tcpFlagsOffset,
+			Len:    1,
+		},
+	)
+	if m.Mask != 0 && m.Mask != 0xff {
+		exprs = appendExpr(exprs,
+			&nftnl.BitwiseAttrs{
+				SReg: 1,
+				DReg: 1,
+				Len:  1,
+				Mask: &nftnl.DataAttrs{
+					Value: []byte{uint8(m.Mask)},
+				},
+				Xor: &nftnl.DataAttrs{
+					Value: []byte{0},
+				},
+			},
+		)
+	}
+	return appendExpr(exprs,
+		&nftnl.CmpAttrs{
+			SReg: 1,
+			Op:   unix.NFT_CMP_EQ,
+			Data: &nftnl.DataAttrs{
+				Value: []byte{uint8(m.Flags)},
+			},
+		},
+	)
+}
+
+func (r *Rule) unmarshalTCPFlagsExprs(attrs *nftnl.RuleAttrs) {
+	if r.NegateL4Proto || r.L4Proto != unix.IPPROTO_TCP {
+		return
+	}
+
+	exprs := attrs.Expressions
+	for i := 0; i+1 < len(exprs); i++ {
+		load, ok := exprs[i].Data.(*nftnl.PayloadAttrs)
+		if !ok || load.DReg == 0 || load.Base != unix.NFT_PAYLOAD_TRANSPORT_HEADER ||
+			load.Offset != tcpFlagsOffset || load.Len != 1 {
+			continue
+		}
+
+		m := &TCPFlagsMatch{}
+		j := i + 1
+		if bitwise, ok := exprs[j].Data.(*nftnl.BitwiseAttrs); ok {
+			if bitwise.Mask == nil || len(bitwise.Mask.Value) != 1 || j+1 >= len(exprs) {
+				continue
+			}
+			m.Mask = TCPFlags(bitwise.Mask.Value[0])
+			j++
+		}
+		cmp, ok := exprs[j].Data.(*nftnl.CmpAttrs)
+		if !ok || cmp.Op != unix.NFT_CMP_EQ || cmp.Data == nil || len(cmp.Data.Value) != 1 {
+			continue
+		}
+		m.Flags = TCPFlags(cmp.Data.Value[0])
+
+		r.TCPFlags = m
+		r.L4Proto = 0
+		return
+	}
+}
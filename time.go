@@ -0,0 +1,376 @@
+package nft
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+const oneDay = 24 * time.Hour
+
+// TimeMatch matches the time at which the packet is processed. All the
+// fields that are set must match.
+type TimeMatch struct {
+	// After and Before match an absolute range of time, inclusive. A zero
+	// value leaves that end of the range open.
+	After  time.Time
+	Before time.Time
+	// Days matches any of the weekdays. The kernel computes the weekday in
+	// its own timezone, which is UTC unless set with settimeofday. Along
+	// with hours, the days are in Location as well and are converted with
+	// them, which fails if the hours span two weekdays in UTC.
+	Days []time.Weekday
+	// HourFrom and HourTo match the time of day as durations since
+	// midnight, inclusive and with a precision of a second. A range whose
+	// start is after its end wraps past midnight, such as 22:00 to 06:00.
+	// The hours are matched unless both are 0.
+	HourFrom time.Duration
+	HourTo   time.Duration
+	// Location is the timezone of the hours, or time.Local if nil. The
+	// kernel compares the time of day in UTC, so the hours are converted
+	// with the offset of Location when the rule is added and the rule has
+	// to be recreated when it changes, such as for daylight saving time.
+	// Dumped rules have their hours and days in UTC.
+	Location *time.Location
+}
+
+func (m *TimeMatch) hasHours() bool {
+	return m.HourFrom != 0 || m.HourTo != 0
+}
+
+func (m *TimeMatch) validate() error {
+	if m.After.IsZero() && m.Before.IsZero() && len(m.Days) == 0 && !m.hasHours() {
+		return fmt.Errorf("time match requires a range of time, days or hours")
+	}
+	for _, t := range []time.Time{m.After, m.Before} {
+		if !t.IsZero() && t.Before(time.Unix(0, 0)) {
+			return fmt.Errorf("time %s is before the Unix epoch", t)
+		}
+	}
+	if !m.After.IsZero() && !m.Before.IsZero() && m.Before.Before(m.After) {
+		return fmt.Errorf("time range ends at %s before it starts at %s", m.Before, m.After)
+	}
+	for i, d := range m.Days {
+		if d < time.Sunday || d > time.Saturday {
+			return fmt.Errorf("unknown weekday %d", d)
+		}
+		if slices.Contains(m.Days[:i], d) {
+			return fmt.Errorf("weekday %s is listed more than once", d)
+		}
+	}
+	for _, h := range []time.Duration{m.HourFrom, m.HourTo} {
+		if h < 0 || h >= oneDay {
+			return fmt.Errorf("hour %s is outside of a day", h)
+		}
+		if h%time.Second != 0 {
+			return fmt.Errorf("hour %s is not a whole number of seconds", h)
+		}
+	}
+	return nil
+}
+
+// timeAnonSets returns the anonymous set of the weekdays of a rule.
+func (r *Rule) timeAnonSets() []anonSet {
+	if r.Time == nil || len(r.Time.Days) < 2 {
+		return nil
+	}
+	set := anonSet{match: r.Time, keyType: nftTypeDay, keyLen: 1}
+	for _, d := range r.Time.Days {
+		set.keys = append(set.keys, []byte{uint8(d)})
+	}
+	return []anonSet{set}
+}
+
+// inUTC returns m with its hours, and the days matched along with them,
+// converted to UTC with the offset of Location at now. The days cannot be
+// converted if the hours span two weekdays in UTC.
+func (m *TimeMatch) inUTC(now time.Time) (*TimeMatch, error) {
+	if !m.hasHours() {
+		return m, nil
+	}
+	loc := m.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	_, offset := now.In(loc).Zone()
+	day := int64(oneDay / time.Second)
+
+	// shift returns the number of days between the weekday of a time of
+	// day and the weekday of the same time in UTC.
+	shift := func(h time.Duration) int64 {
+		secs := int64(h/time.Second) - int64(offset)
+		switch {
+		case secs < 0:
+			return -1
+		case secs >= day:
+			return 1
+		}
+		return 0
+	}
+	utc := func(h time.Duration) time.Duration {
+		secs := (int64(h/time.Second) - int64(offset)) % day
+		if secs < 0 {
+			secs += day
+		}
+		return time.Duration(secs) * time.Second
+	}
+
+	u := *m
+	u.HourFrom, u.HourTo, u.Location = utc(m.HourFrom), utc(m.HourTo), time.UTC
+	if len(m.Days) > 0 && offset != 0 {
+		n := shift(m.HourFrom)
+		if m.HourFrom > m.HourTo || shift(m.HourTo) != n {
+			return nil, fmt.Errorf("hours %s to %s span two weekdays in UTC and cannot be matched along with days", m.HourFrom, m.HourTo)
+		}
+		u.Days = make([]time.Weekday, len(m.Days))
+		for i, d := range m.Days {
+			u.Days[i] = time.Weekday((int64(d) + n + 7) % 7)
+		}
+	}
+	return &u, nil
+}
+
+// timeLoad loads the value of the meta key in network byte order, so that
+// it can be compared as a range.
+func timeLoad(key uint32, size uint32) []nftnl.ExprAttrs {
+	return appendExpr(nil,
+		&nftnl.MetaAttrs{
+			DReg: 1,
+			Key:  key,
+		},
+		&nftnl.ByteorderAttrs{
+			SReg: 1,
+			DReg: 1,
+			Op:   unix.NFT_BYTEORDER_HTON,
+			Len:  size,
+			Size: size,
+		},
+	)
+}
+
+// marshalTimeExprs matches the time match of a rule, whose hours are in UTC
+// once converted by inUTC.
+func (r *Rule) marshalTimeExprs() []nftnl.ExprAttrs {
+	m := r.Time
+	var exprs []nftnl.ExprAttrs
+
+	if !m.After.IsZero() || !m.Before.IsZero() {
+		exprs = append(exprs, timeLoad(unixext.NFT_META_TIME_NS, 8)...)
+		if !m.After.IsZero() {
+			exprs = appendExpr(exprs,
+				&nftnl.CmpAttrs{
+					SReg: 1,
+					Op:   unix.NFT_CMP_GTE,
+					Data: &nftnl.DataAttrs{
+						Value: binary.BigEndian.AppendUint64(nil, uint64(m.After.UnixNano())),
+					},
+				},
+			)
+		}
+		if !m.Before.IsZero() {
+			exprs = appendExpr(exprs,
+				&nftnl.CmpAttrs{
+					SReg: 1,
+					Op:   unix.NFT_CMP_LTE,
+					Data: &nftnl.DataAttrs{
+						Value: binary.BigEndian.AppendUint64(nil, uint64(m.Before.UnixNano())),
+					},
+				},
+			)
+		}
+	}
+
+	switch len(m.Days) {
+	case 0:
+	case 1:
+		exprs = appendExpr(exprs,
+			&nftnl.MetaAttrs{
+				DReg: 1,
+				Key:  unixext.NFT_META_TIME_DAY,
+			},
+			&nftnl.CmpAttrs{
+				SReg: 1,
+				Op:   unix.NFT_CMP_EQ,
+				Data: &nftnl.DataAttrs{
+					Value: []byte{uint8(m.Days[0])},
+				},
+			},
+		)
+	default:
+		exprs = appendExpr(exprs,
+			&nftnl.MetaAttrs{
+				DReg: 1,
+				Key:  unixext.NFT_META_TIME_DAY,
+			},
+			anonSetLookup(r.anonSetID(m), false),
+		)
+	}
+
+	if m.hasHours() {
+		from, to := uint32(m.HourFrom/time.Second), uint32(m.HourTo/time.Second)
+		switch {
+		case from <= to:
+			exprs = append(exprs, timeLoad(unixext.NFT_META_TIME_HOUR, 4)...)
+			exprs = appendExpr(exprs,
+				&nftnl.CmpAttrs{
+					SReg: 1,
+					Op:   unix.NFT_CMP_GTE,
+					Data: &nftnl.DataAttrs{Value: binary.BigEndian.AppendUint32(nil, from)},
+				},
+				&nftnl.CmpAttrs{
+					SReg: 1,
+					Op:   unix.NFT_CMP_LTE,
+					Data: &nftnl.DataAttrs{Value: binary.BigEndian.AppendUint32(nil, to)},
+				},
+			)
+		case from-to > 1:
+			// A range wrapping past midnight excludes the hours between
+			// its end and its start.
+			exprs = append(exprs, timeLoad(unixext.NFT_META_TIME_HOUR, 4)...)
+			exprs = appendExpr(exprs,
+				&nftnl.RangeAttrs{
+					SReg:     1,
+					Op:       unix.NFT_RANGE_NEQ,
+					FromData: &nftnl.DataAttrs{Value: binary.BigEndian.AppendUint32(nil, to+1)},
+					ToData:   &nftnl.DataAttrs{Value: binary.BigEndian.AppendUint32(nil, from-1)},
+				},
+			)
+		default:
+			// The range covers the whole day.
+		}
+	}
+	return exprs
+}
+
+// timeRangeFromExprs decodes the comparisons following the byteorder
+// conversion of a time load at exprs[i]. It returns the ends of the range
+// and the number of expressions consumed, with wrapped set if the range
+// excludes [min, max] instead.
+func timeRangeFromExprs(exprs []nftnl.ExprAttrs, i int, size int) (min, max []byte, wrapped bool, n int) {
+	if i+1 >= len(exprs) {
+		return nil, nil, false, 0
+	}
+	order, ok := exprs[i+1].Data.(*nftnl.ByteorderAttrs)
+	if !ok || order.Op != unix.NFT_BYTEORDER_HTON || order.Size != uint32(size) {
+		return nil, nil, false, 0
+	}
+	n = 1
+	for i+n+1 < len(exprs) {
+		switch e := exprs[i+n+1].Data.(type) {
+		case *nftnl.CmpAttrs:
+			if e.Data == nil || len(e.Data.Value) != size {
+				return min, max, false, n
+			}
+			switch {
+			case e.Op == unix.NFT_CMP_GTE && min == nil && max == nil:
+				min = e.Data.Value
+			case e.Op == unix.NFT_CMP_LTE && max == nil:
+				max = e.Data.Value
+			default:
+				return min, max, false, n
+			}
+		case *nftnl.RangeAttrs:
+			if e.Op != unix.NFT_RANGE_NEQ || min != nil || max != nil ||
+				e.FromData == nil || len(e.FromData.Value) != size ||
+				e.ToData == nil || len(e.ToData.Value) != size {
+				return min, max, false, n
+			}
+			return e.FromData.Value, e.ToData.Value, true, n + 1
+		default:
+			return min, max, false, n
+		}
+		n++
+	}
+	return min, max, false, n
+}
+
+func (r *Rule) unmarshalTimeExprs(attrs *nftnl.RuleAttrs) {
+	exprs := attrs.Expressions
+	match := func() *TimeMatch {
+		if r.Time == nil {
+			r.Time = &TimeMatch{}
+		}
+		return r.Time
+	}
+
+	for i := 0; i+1 < len(exprs); i++ {
+		meta, ok := exprs[i].Data.(*nftnl.MetaAttrs)
+		if !ok || meta.DReg == 0 {
+			continue
+		}
+		switch meta.Key {
+		case unixext.NFT_META_TIME_NS:
+			min, max, wrapped, n := timeRangeFromExprs(exprs, i, 8)
+			if wrapped || (min == nil && max == nil) {
+				continue
+			}
+			m := match()
+			if min != nil {
+				m.After = time.Unix(0, int64(binary.BigEndian.Uint64(min))).UTC()
+			}
+			if max != nil {
+				m.Before = time.Unix(0, int64(binary.BigEndian.Uint64(max))).UTC()
+			}
+			i += n
+		case unixext.NFT_META_TIME_DAY:
+			switch e := exprs[i+1].Data.(type) {
+			case *nftnl.CmpAttrs:
+				if e.Op != unix.NFT_CMP_EQ || e.Data == nil || len(e.Data.Value) != 1 {
+					continue
+				}
+				m := match()
+				m.Days = []time.Weekday{time.Weekday(e.Data.Value[0])}
+			case *nftnl.LookupAttrs:
+				// The days are filled in by getTimeSetElems.
+				if e.DReg != 0 || e.Flags != 0 || !isAnonSet(e.Set) {
+					continue
+				}
+				match()
+			default:
+				continue
+			}
+			i++
+		case unixext.NFT_META_TIME_HOUR:
+			min, max, wrapped, n := timeRangeFromExprs(exprs, i, 4)
+			if min == nil || max == nil {
+				continue
+			}
+			from, to := binary.BigEndian.Uint32(min), binary.BigEndian.Uint32(max)
+			if wrapped {
+				from, to = to+1, from-1
+			}
+			m := match()
+			m.HourFrom = time.Duration(from) * time.Second
+			m.HourTo = time.Duration(to) * time.Second
+			m.Location = time.UTC
+			i += n
+		}
+	}
+}
+
+// getTimeSetElems fills in the weekdays of the anonymous set looked up by
+// the time match of a dumped rule, in ascending order.
+func (c *Conn) getTimeSetElems(r *Rule, attrs *nftnl.RuleAttrs) error {
+	if r.Time == nil {
+		return nil
+	}
+	elems, err := c.getAnonSetElems(r, attrs, func(data nftnl.ExprDataAttrs) bool {
+		meta, ok := data.(*nftnl.MetaAttrs)
+		return ok && meta.Key == unixext.NFT_META_TIME_DAY
+	})
+	if err != nil {
+		return err
+	}
+	for _, elem := range elems {
+		if elem.Key != nil && len(elem.Key.Value) >= 1 {
+			r.Time.Days = append(r.Time.Days, time.Weekday(elem.Key.Value[0]))
+		}
+	}
+	slices.Sort(r.Time.Days)
+	return nil
+}
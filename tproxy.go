@@ -0,0 +1,111 @@
+package nft
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"golang.org/x/sys/unix"
+)
+
+// Tproxy redirects the packet to a local socket without changing its
+// headers. Addr and Port default to the destination of the packet, so at
+// least one of them must be set. The socket must have the IP_TRANSPARENT
+// option set. The kernel only allows tproxy in the prerouting hook and fails
+// the batch with EOPNOTSUPP when a rule with it is added to, or jumped to
+// from, a base chain with another hook.
+type Tproxy struct {
+	Addr *netip.Addr
+	Port uint16
+}
+
+func (t *Tproxy) validate(r *Rule) error {
+	switch r.Family {
+	case unix.NFPROTO_IPV4, unix.NFPROTO_IPV6, unix.NFPROTO_INET:
+	default:
+		return fmt.Errorf("tproxy is only supported in the ipv4, ipv6 and inet families")
+	}
+	if l4proto := r.l4proto(); l4proto != unix.IPPROTO_TCP && l4proto != unix.IPPROTO_UDP {
+		return fmt.Errorf("tproxy requires the L4 protocol to be tcp or udp")
+	}
+	if t.Addr == nil && t.Port == 0 {
+		return fmt.Errorf("tproxy requires an address or a port")
+	}
+	if t.Addr != nil {
+		if !t.Addr.IsValid() {
+			return fmt.Errorf("invalid tproxy address")
+		}
+		l3proto := r.l3proto()
+		if t.Addr.Is4() && l3proto == unix.NFPROTO_IPV6 ||
+			!t.Addr.Is4() && l3proto == unix.NFPROTO_IPV4 {
+			return fmt.Errorf("tproxy address family does not match the rule")
+		}
+	}
+	return nil
+}
+
+func tproxyExpr(t *Tproxy, l3proto uint8) []nftnl.ExprAttrs {
+	attrs := &nftnl.TproxyAttrs{Family: uint32(l3proto)}
+
+	var exprs []nftnl.ExprAttrs
+	if t.Addr != nil {
+		if t.Addr.Is4() {
+			attrs.Family = unix.NFPROTO_IPV4
+		} else {
+			attrs.Family = unix.NFPROTO_IPV6
+		}
+		attrs.RegAddr = 1
+		exprs = appendExpr(exprs,
+			&nftnl.ImmediateAttrs{
+				DReg: attrs.RegAddr,
+				Data: &nftnl.DataAttrs{
+					Value: t.Addr.AsSlice(),
+				},
+			},
+		)
+	}
+	if t.Port != 0 {
+		port := make([]byte, 2)
+		binary.BigEndian.PutUint16(port, t.Port)
+		attrs.RegPort = 2
+		exprs = appendExpr(exprs,
+			&nftnl.ImmediateAttrs{
+				DReg: attrs.RegPort,
+				Data: &nftnl.DataAttrs{
+					Value: port,
+				},
+			},
+		)
+	}
+
+	return appendExpr(exprs, attrs)
+}
+
+// tproxyFromExprs decodes the tproxy statement at exprs[i] from the
+// immediates loading its registers.
+func tproxyFromExprs(exprs []nftnl.ExprAttrs, i int) *Tproxy {
+	attr := exprs[i].Data.(*nftnl.TproxyAttrs)
+	t := &Tproxy{}
+	for j := i - 1; j >= 0; j-- {
+		imm, ok := exprs[j].Data.(*nftnl.ImmediateAttrs)
+		if !ok {
+			break
+		}
+		data, ok := imm.Data.(*nftnl.DataAttrs)
+		if !ok {
+			break
+		}
+		switch imm.DReg {
+		case attr.RegAddr:
+			if addr, ok := netip.AddrFromSlice(data.Value); ok {
+				t.Addr = &addr
+			}
+		case attr.RegPort:
+			if len(data.Value) >= 2 {
+				t.Port = binary.BigEndian.Uint16(data.Value)
+			}
+		}
+	}
+	return t
+}
@@ -0,0 +1,316 @@
+package nft
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+type TunnelKey uint8
+
+const (
+	// TunnelKeyPath matches whether the packet carries tunnel metadata.
+	TunnelKeyPath TunnelKey = 0x1
+	// TunnelKeyID compares the tunnel ID of the metadata, such as the VNI.
+	TunnelKeyID TunnelKey = 0x2
+)
+
+type TunnelMode uint8
+
+const (
+	// TunnelModeRx only considers metadata of received packets, and
+	// TunnelModeTx metadata set for transmission. Both are considered when
+	// the mode is unset.
+	TunnelModeRx TunnelMode = 0x1
+	TunnelModeTx TunnelMode = 0x2
+)
+
+// TunnelMatch matches the tunnel metadata of the packet, which is set by
+// tunnel devices in external (collect_md) mode or by a tunnel object. The
+// kernel only supports it in the netdev family. Packets without metadata
+// never match on the ID.
+type TunnelMatch struct {
+	Key  TunnelKey
+	Mode TunnelMode
+	Path bool
+	ID   uint32
+}
+
+func (m *TunnelMatch) validate(r *Rule) error {
+	if r.Family != unix.NFPROTO_NETDEV {
+		return fmt.Errorf("tunnel is only supported in the netdev family")
+	}
+	switch m.Key {
+	case TunnelKeyPath, TunnelKeyID:
+	default:
+		return fmt.Errorf("unknown tunnel key %d", m.Key)
+	}
+	switch m.Mode {
+	case 0, TunnelModeRx, TunnelModeTx:
+	default:
+		return fmt.Errorf("unknown tunnel mode %d", m.Mode)
+	}
+	return nil
+}
+
+func tunnelExpr(m *TunnelMatch) []nftnl.ExprAttrs {
+	attrs := &nftnl.TunnelAttrs{DReg: 1}
+	switch m.Mode {
+	case TunnelModeRx:
+		attrs.Mode = unixext.NFT_TUNNEL_MODE_RX
+	case TunnelModeTx:
+		attrs.Mode = unixext.NFT_TUNNEL_MODE_TX
+	}
+
+	var value []byte
+	switch m.Key {
+	case TunnelKeyPath:
+		attrs.Key = unixext.NFT_TUNNEL_PATH
+		value = []byte{0}
+		if m.Path {
+			value[0] = 1
+		}
+	case TunnelKeyID:
+		attrs.Key = unixext.NFT_TUNNEL_ID
+		value = make([]byte, 4)
+		binary.NativeEndian.PutUint32(value, m.ID)
+	}
+
+	return appendExpr(nil,
+		attrs,
+		&nftnl.CmpAttrs{
+			SReg: 1,
+			Op:   unix.NFT_CMP_EQ,
+			Data: &nftnl.DataAttrs{
+				Value: value,
+			},
+		},
+	)
+}
+
+func (r *Rule) unmarshalTunnelExprs(attrs *nftnl.RuleAttrs) {
+	exprs := attrs.Expressions
+	for i := 0; i+1 < len(exprs); i++ {
+		tunnel, ok := exprs[i].Data.(*nftnl.TunnelAttrs)
+		if !ok {
+			continue
+		}
+
+		cmp, ok := exprs[i+1].Data.(*nftnl.CmpAttrs)
+		if !ok || cmp.Op != unix.NFT_CMP_EQ || cmp.Data == nil || len(cmp.Data.Value) == 0 {
+			continue
+		}
+
+		m := &TunnelMatch{}
+		switch tunnel.Mode {
+		case unixext.NFT_TUNNEL_MODE_RX:
+			m.Mode = TunnelModeRx
+		case unixext.NFT_TUNNEL_MODE_TX:
+			m.Mode = TunnelModeTx
+		}
+		value := cmp.Data.Value
+		switch tunnel.Key {
+		case unixext.NFT_TUNNEL_PATH:
+			m.Key = TunnelKeyPath
+			m.Path = value[0] != 0
+		case unixext.NFT_TUNNEL_ID:
+			if len(value) < 4 {
+				continue
+			}
+			m.Key = TunnelKeyID
+			m.ID = binary.NativeEndian.Uint32(value)
+		default:
+			continue
+		}
+		r.Tunnel = m
+		i++
+	}
+}
+
+// Tunnel is the template of the metadata a tunnel object attaches to
+// packets. A tunnel device in external (collect_md) mode then encapsulates
+// the packets it transmits according to it. Tunnel objects are only
+// supported in the netdev family.
+type Tunnel struct {
+	// ID is the tunnel key, such as the VXLAN VNI or the GRE key.
+	ID uint32
+	// Dst is the remote endpoint and Src the optional local one. Both must
+	// be of the same family.
+	Src *netip.Addr
+	Dst *netip.Addr
+	// FlowLabel is only used with IPv6 endpoints.
+	FlowLabel uint32
+	SrcPort   uint16
+	DstPort   uint16
+	TOS       uint8
+	// TTL defaults to 255.
+	TTL          uint8
+	ZeroChecksum bool
+	DontFragment bool
+	SeqNumber    bool
+	// At most one of VxlanGBP, Erspan or Geneve may be set.
+	VxlanGBP uint32
+	Erspan   *TunnelErspan
+	Geneve   []TunnelGeneveOpt
+}
+
+// TunnelErspan holds the ERSPAN options. Index is only used with version 1,
+// HWID and Dir with version 2.
+type TunnelErspan struct {
+	Version uint32
+	Index   uint32
+	HWID    uint8
+	Dir     uint8
+}
+
+type TunnelGeneveOpt struct {
+	Class uint16
+	Type  uint8
+	// Data length must be a multiple of 4 bytes, up to 124.
+	Data []byte
+}
+
+func (t *Tunnel) validate(family uint8) error {
+	if family != unix.NFPROTO_NETDEV {
+		return fmt.Errorf("tunnel objects are only supported in the netdev family")
+	}
+	if t.Dst == nil {
+		return fmt.Errorf("tunnel destination address must be specified")
+	}
+	if t.Src != nil && t.Src.Is4() != t.Dst.Is4() {
+		return fmt.Errorf("tunnel source and destination addresses must be of the same family")
+	}
+	if t.FlowLabel != 0 && t.Dst.Is4() {
+		return fmt.Errorf("tunnel flow label requires IPv6 addresses")
+	}
+	n := 0
+	for _, set := range []bool{t.VxlanGBP != 0, t.Erspan != nil, len(t.Geneve) > 0} {
+		if set {
+			n++
+		}
+	}
+	if n > 1 {
+		return fmt.Errorf("at most one of vxlan, erspan or geneve options may be specified")
+	}
+	if t.Erspan != nil && t.Erspan.Version != 1 && t.Erspan.Version != 2 {
+		return fmt.Errorf("invalid erspan version %d", t.Erspan.Version)
+	}
+	for _, opt := range t.Geneve {
+		if len(opt.Data)%4 != 0 || len(opt.Data) > 124 {
+			return fmt.Errorf("geneve option data must be a multiple of 4 bytes, up to 124")
+		}
+	}
+	return nil
+}
+
+func (t *Tunnel) marshal() *nftnl.TunnelKeyAttrs {
+	attrs := &nftnl.TunnelKeyAttrs{
+		ID:    t.ID,
+		TOS:   t.TOS,
+		TTL:   t.TTL,
+		SPort: t.SrcPort,
+		DPort: t.DstPort,
+	}
+	ip := &nftnl.TunnelKeyIPAttrs{Dst: t.Dst.AsSlice()}
+	if t.Src != nil {
+		ip.Src = t.Src.AsSlice()
+	}
+	if t.Dst.Is4() {
+		attrs.IP = ip
+	} else {
+		ip.FlowLabel = t.FlowLabel
+		attrs.IP6 = ip
+	}
+	if t.ZeroChecksum {
+		attrs.Flags |= unixext.NFT_TUNNEL_F_ZERO_CSUM_TX
+	}
+	if t.DontFragment {
+		attrs.Flags |= unixext.NFT_TUNNEL_F_DONT_FRAGMENT
+	}
+	if t.SeqNumber {
+		attrs.Flags |= unixext.NFT_TUNNEL_F_SEQ_NUMBER
+	}
+	switch {
+	case t.VxlanGBP != 0:
+		attrs.Opts = &nftnl.TunnelKeyOptsAttrs{
+			Vxlan: &nftnl.TunnelKeyVxlanAttrs{GBP: t.VxlanGBP},
+		}
+	case t.Erspan != nil:
+		attrs.Opts = &nftnl.TunnelKeyOptsAttrs{
+			Erspan: &nftnl.TunnelKeyErspanAttrs{
+				Version: t.Erspan.Version,
+				V1Index: t.Erspan.Index,
+				V2HWID:  t.Erspan.HWID,
+				V2Dir:   t.Erspan.Dir,
+			},
+		}
+	case len(t.Geneve) > 0:
+		attrs.Opts = &nftnl.TunnelKeyOptsAttrs{}
+		for _, opt := range t.Geneve {
+			attrs.Opts.Geneve = append(attrs.Opts.Geneve, nftnl.TunnelKeyGeneveAttrs{
+				Class: opt.Class,
+				Type:  opt.Type,
+				Data:  opt.Data,
+			})
+		}
+	}
+	return attrs
+}
+
+func tunnelFromAttrs(attrs *nftnl.TunnelKeyAttrs) *Tunnel {
+	t := &Tunnel{
+		ID:           attrs.ID,
+		TOS:          attrs.TOS,
+		TTL:          attrs.TTL,
+		SrcPort:      attrs.SPort,
+		DstPort:      attrs.DPort,
+		ZeroChecksum: attrs.Flags&unixext.NFT_TUNNEL_F_ZERO_CSUM_TX != 0,
+		DontFragment: attrs.Flags&unixext.NFT_TUNNEL_F_DONT_FRAGMENT != 0,
+		SeqNumber:    attrs.Flags&unixext.NFT_TUNNEL_F_SEQ_NUMBER != 0,
+	}
+	if t.TTL == 255 {
+		t.TTL = 0
+	}
+
+	ip := attrs.IP
+	if ip == nil {
+		ip = attrs.IP6
+	}
+	if ip != nil {
+		t.FlowLabel = ip.FlowLabel
+		// The kernel reports an unspecified source as the zero address.
+		if src, ok := netip.AddrFromSlice(ip.Src); ok && !src.IsUnspecified() {
+			t.Src = &src
+		}
+		if dst, ok := netip.AddrFromSlice(ip.Dst); ok {
+			t.Dst = &dst
+		}
+	}
+
+	if opts := attrs.Opts; opts != nil {
+		if opts.Vxlan != nil {
+			t.VxlanGBP = opts.Vxlan.GBP
+		}
+		if opts.Erspan != nil {
+			t.Erspan = &TunnelErspan{Version: opts.Erspan.Version}
+			if opts.Erspan.Version == 1 {
+				t.Erspan.Index = opts.Erspan.V1Index
+			} else {
+				t.Erspan.HWID = opts.Erspan.V2HWID
+				t.Erspan.Dir = opts.Erspan.V2Dir
+			}
+		}
+		for _, opt := range opts.Geneve {
+			t.Geneve = append(t.Geneve, TunnelGeneveOpt{
+				Class: opt.Class,
+				Type:  opt.Type,
+				Data:  opt.Data,
+			})
+		}
+	}
+	return t
+}
@@ -78,3 +78,508 @@ const (
 	NFTA_SET_TYPE        = 0x13
 	NFTA_SET_COUNT       = 0x14
 )
+
+// https://github.com/torvalds/linux/blob/8b789f2b7602a818e7c7488c74414fae21392b63/include/uapi/linux/netfilter/nf_log.h
+const (
+	NF_LOG_TCPSEQ    = 0x01
+	NF_LOG_TCPOPT    = 0x02
+	NF_LOG_IPOPT     = 0x04
+	NF_LOG_UID       = 0x08
+	NF_LOG_NFLOG     = 0x10
+	NF_LOG_MACDECODE = 0x20
+	NF_LOG_MASK      = 0x2f
+)
+
+// https://github.com/torvalds/linux/blob/8b789f2b7602a818e7c7488c74414fae21392b63/include/uapi/linux/netfilter/nf_tables.h
+const (
+	NFT_LOGLEVEL_EMERG = iota
+	NFT_LOGLEVEL_ALERT
+	NFT_LOGLEVEL_CRIT
+	NFT_LOGLEVEL_ERR
+	NFT_LOGLEVEL_WARNING
+	NFT_LOGLEVEL_NOTICE
+	NFT_LOGLEVEL_INFO
+	NFT_LOGLEVEL_DEBUG
+	NFT_LOGLEVEL_AUDIT
+)
+
+// https://github.com/torvalds/linux/blob/8b789f2b7602a818e7c7488c74414fae21392b63/include/uapi/linux/netfilter/nfnetlink_log.h
+const (
+	NFULNL_MSG_PACKET = 0x0
+	NFULNL_MSG_CONFIG = 0x1
+)
+
+const (
+	NFULA_PACKET_HDR         = 0x01
+	NFULA_MARK               = 0x02
+	NFULA_TIMESTAMP          = 0x03
+	NFULA_IFINDEX_INDEV      = 0x04
+	NFULA_IFINDEX_OUTDEV     = 0x05
+	NFULA_IFINDEX_PHYSINDEV  = 0x06
+	NFULA_IFINDEX_PHYSOUTDEV = 0x07
+	NFULA_HWADDR             = 0x08
+	NFULA_PAYLOAD            = 0x09
+	NFULA_PREFIX             = 0x0a
+	NFULA_UID                = 0x0b
+	NFULA_SEQ                = 0x0c
+	NFULA_SEQ_GLOBAL         = 0x0d
+	NFULA_GID                = 0x0e
+	NFULA_HWTYPE             = 0x0f
+	NFULA_HWHEADER           = 0x10
+	NFULA_HWLEN              = 0x11
+	NFULA_CT                 = 0x12
+	NFULA_CT_INFO            = 0x13
+	NFULA_VLAN               = 0x14
+	NFULA_L2HDR              = 0x15
+)
+
+const (
+	NFULNL_CFG_CMD_NONE      = 0x0
+	NFULNL_CFG_CMD_BIND      = 0x1
+	NFULNL_CFG_CMD_UNBIND    = 0x2
+	NFULNL_CFG_CMD_PF_BIND   = 0x3
+	NFULNL_CFG_CMD_PF_UNBIND = 0x4
+)
+
+const (
+	NFULA_CFG_CMD      = 0x1
+	NFULA_CFG_MODE     = 0x2
+	NFULA_CFG_NLBUFSIZ = 0x3
+	NFULA_CFG_TIMEOUT  = 0x4
+	NFULA_CFG_QTHRESH  = 0x5
+	NFULA_CFG_FLAGS    = 0x6
+)
+
+const (
+	NFULNL_COPY_NONE   = 0x00
+	NFULNL_COPY_META   = 0x01
+	NFULNL_COPY_PACKET = 0x02
+)
+
+const (
+	NFULNL_CFG_F_SEQ        = 0x0001
+	NFULNL_CFG_F_SEQ_GLOBAL = 0x0002
+	NFULNL_CFG_F_CONNTRACK  = 0x0004
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+const (
+	NFTA_OBJ_HANDLE   = 0x6
+	NFTA_OBJ_USERDATA = 0x8
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+const (
+	NFT_OBJECT_UNSPEC     = 0x0
+	NFT_OBJECT_COUNTER    = 0x1
+	NFT_OBJECT_QUOTA      = 0x2
+	NFT_OBJECT_CT_HELPER  = 0x3
+	NFT_OBJECT_LIMIT      = 0x4
+	NFT_OBJECT_CONNLIMIT  = 0x5
+	NFT_OBJECT_TUNNEL     = 0x6
+	NFT_OBJECT_CT_TIMEOUT = 0x7
+	NFT_OBJECT_SECMARK    = 0x8
+	NFT_OBJECT_CT_EXPECT  = 0x9
+	NFT_OBJECT_SYNPROXY   = 0xa
+)
+
+// https://github.com/torvalds/linux/blob/8b789f2b7602a818e7c7488c74414fae21392b63/include/uapi/linux/netfilter/nfnetlink_queue.h
+const (
+	NFQNL_MSG_PACKET        = 0x0
+	NFQNL_MSG_VERDICT       = 0x1
+	NFQNL_MSG_CONFIG        = 0x2
+	NFQNL_MSG_VERDICT_BATCH = 0x3
+)
+
+const (
+	NFQA_PACKET_HDR         = 0x01
+	NFQA_VERDICT_HDR        = 0x02
+	NFQA_MARK               = 0x03
+	NFQA_TIMESTAMP          = 0x04
+	NFQA_IFINDEX_INDEV      = 0x05
+	NFQA_IFINDEX_OUTDEV     = 0x06
+	NFQA_IFINDEX_PHYSINDEV  = 0x07
+	NFQA_IFINDEX_PHYSOUTDEV = 0x08
+	NFQA_HWADDR             = 0x09
+	NFQA_PAYLOAD            = 0x0a
+	NFQA_CT                 = 0x0b
+	NFQA_CT_INFO            = 0x0c
+	NFQA_CAP_LEN            = 0x0d
+	NFQA_SKB_INFO           = 0x0e
+	NFQA_EXP                = 0x0f
+	NFQA_UID                = 0x10
+	NFQA_GID                = 0x11
+	NFQA_SECCTX             = 0x12
+	NFQA_VLAN               = 0x13
+	NFQA_L2HDR              = 0x14
+	NFQA_PRIORITY           = 0x15
+	NFQA_CGROUP_CLASSID     = 0x16
+)
+
+const (
+	NFQNL_CFG_CMD_NONE      = 0x0
+	NFQNL_CFG_CMD_BIND      = 0x1
+	NFQNL_CFG_CMD_UNBIND    = 0x2
+	NFQNL_CFG_CMD_PF_BIND   = 0x3
+	NFQNL_CFG_CMD_PF_UNBIND = 0x4
+)
+
+const (
+	NFQNL_COPY_NONE   = 0x0
+	NFQNL_COPY_META   = 0x1
+	NFQNL_COPY_PACKET = 0x2
+)
+
+const (
+	NFQA_CFG_CMD          = 0x1
+	NFQA_CFG_PARAMS       = 0x2
+	NFQA_CFG_QUEUE_MAXLEN = 0x3
+	NFQA_CFG_MASK         = 0x4
+	NFQA_CFG_FLAGS        = 0x5
+)
+
+const (
+	NFQA_CFG_F_FAIL_OPEN = 0x01
+	NFQA_CFG_F_CONNTRACK = 0x02
+	NFQA_CFG_F_GSO       = 0x04
+	NFQA_CFG_F_UID_GID   = 0x08
+	NFQA_CFG_F_SECCTX    = 0x10
+)
+
+const (
+	NFQA_SKB_CSUMNOTREADY     = 0x1
+	NFQA_SKB_GSO              = 0x2
+	NFQA_SKB_CSUM_NOTVERIFIED = 0x4
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+const (
+	NFT_RT_XFRM = 0x4
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+const (
+	NFT_META_IIFKIND   = 0x1a
+	NFT_META_OIFKIND   = 0x1b
+	NFT_META_TIME_NS   = 0x1e
+	NFT_META_TIME_DAY  = 0x1f
+	NFT_META_TIME_HOUR = 0x20
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+const (
+	NFTA_SOCKET_KEY   = 0x1
+	NFTA_SOCKET_DREG  = 0x2
+	NFTA_SOCKET_LEVEL = 0x3
+)
+
+const (
+	NFT_SOCKET_TRANSPARENT = 0x0
+	NFT_SOCKET_MARK        = 0x1
+	NFT_SOCKET_WILDCARD    = 0x2
+	NFT_SOCKET_CGROUPV2    = 0x3
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+const (
+	NFTA_TPROXY_FAMILY   = 0x1
+	NFTA_TPROXY_REG_ADDR = 0x2
+	NFTA_TPROXY_REG_PORT = 0x3
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+const (
+	NFTA_FWD_SREG_ADDR = 0x2
+	NFTA_FWD_NFPROTO   = 0x3
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+const (
+	NFTA_NUMGEN_DREG    = 0x1
+	NFTA_NUMGEN_MODULUS = 0x2
+	NFTA_NUMGEN_TYPE    = 0x3
+	NFTA_NUMGEN_OFFSET  = 0x4
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+const (
+	NFTA_XFRM_DREG  = 0x1
+	NFTA_XFRM_KEY   = 0x2
+	NFTA_XFRM_DIR   = 0x3
+	NFTA_XFRM_SPNUM = 0x4
+)
+
+const (
+	NFT_XFRM_KEY_DADDR_IP4 = 0x1
+	NFT_XFRM_KEY_DADDR_IP6 = 0x2
+	NFT_XFRM_KEY_SADDR_IP4 = 0x3
+	NFT_XFRM_KEY_SADDR_IP6 = 0x4
+	NFT_XFRM_KEY_REQID     = 0x5
+	NFT_XFRM_KEY_SPI       = 0x6
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/xfrm.h
+const (
+	XFRM_POLICY_IN  = 0x0
+	XFRM_POLICY_OUT = 0x1
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+const (
+	NFTA_OSF_DREG  = 0x1
+	NFTA_OSF_TTL   = 0x2
+	NFTA_OSF_FLAGS = 0x3
+)
+
+const (
+	NFT_OSF_F_VERSION = 0x1
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nfnetlink_osf.h
+const (
+	NF_OSF_TTL_TRUE    = 0x0
+	NF_OSF_TTL_LESS    = 0x1
+	NF_OSF_TTL_NOCHECK = 0x2
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+const (
+	NFTA_CONNLIMIT_COUNT = 0x1
+	NFTA_CONNLIMIT_FLAGS = 0x2
+)
+
+const (
+	NFT_CONNLIMIT_F_INV = 0x1
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+const (
+	NFTA_LAST_SET   = 0x1
+	NFTA_LAST_MSECS = 0x2
+	NFTA_LAST_PAD   = 0x3
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+const (
+	NFTA_DYNSET_EXPRESSIONS = 0xa
+)
+
+const (
+	NFT_DYNSET_OP_DELETE = 0x2
+)
+
+const (
+	NFT_DYNSET_F_EXPR = 0x2
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+const (
+	NFTA_SYNPROXY_MSS    = 0x1
+	NFTA_SYNPROXY_WSCALE = 0x2
+	NFTA_SYNPROXY_FLAGS  = 0x3
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_synproxy.h
+const (
+	NF_SYNPROXY_OPT_MSS       = 0x01
+	NF_SYNPROXY_OPT_WSCALE    = 0x02
+	NF_SYNPROXY_OPT_SACK_PERM = 0x04
+	NF_SYNPROXY_OPT_TIMESTAMP = 0x08
+	NF_SYNPROXY_OPT_ECN       = 0x10
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+const (
+	NFTA_CT_TIMEOUT_L3PROTO = 0x1
+	NFTA_CT_TIMEOUT_L4PROTO = 0x2
+	NFTA_CT_TIMEOUT_DATA    = 0x3
+)
+
+const (
+	NFTA_CT_EXPECT_L3PROTO = 0x1
+	NFTA_CT_EXPECT_L4PROTO = 0x2
+	NFTA_CT_EXPECT_DPORT   = 0x3
+	NFTA_CT_EXPECT_TIMEOUT = 0x4
+	NFTA_CT_EXPECT_SIZE    = 0x5
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nfnetlink_cttimeout.h
+const (
+	CTA_TIMEOUT_TCP_SYN_SENT    = 0x1
+	CTA_TIMEOUT_TCP_SYN_RECV    = 0x2
+	CTA_TIMEOUT_TCP_ESTABLISHED = 0x3
+	CTA_TIMEOUT_TCP_FIN_WAIT    = 0x4
+	CTA_TIMEOUT_TCP_CLOSE_WAIT  = 0x5
+	CTA_TIMEOUT_TCP_LAST_ACK    = 0x6
+	CTA_TIMEOUT_TCP_TIME_WAIT   = 0x7
+	CTA_TIMEOUT_TCP_CLOSE       = 0x8
+	CTA_TIMEOUT_TCP_SYN_SENT2   = 0x9
+	CTA_TIMEOUT_TCP_RETRANS     = 0xa
+	CTA_TIMEOUT_TCP_UNACK       = 0xb
+)
+
+const (
+	CTA_TIMEOUT_UDP_UNREPLIED = 0x1
+	CTA_TIMEOUT_UDP_REPLIED   = 0x2
+)
+
+const (
+	CTA_TIMEOUT_ICMP_TIMEOUT = 0x1
+)
+
+const (
+	CTA_TIMEOUT_ICMPV6_TIMEOUT = 0x1
+)
+
+const (
+	CTA_TIMEOUT_GENERIC_TIMEOUT = 0x1
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_tables.h
+const (
+	NFTA_INNER_NUM     = 0x1
+	NFTA_INNER_TYPE    = 0x2
+	NFTA_INNER_FLAGS   = 0x3
+	NFTA_INNER_HDRSIZE = 0x4
+	NFTA_INNER_EXPR    = 0x5
+)
+
+const (
+	NFT_INNER_UNSPEC = 0x0
+	NFT_INNER_VXLAN  = 0x1
+	NFT_INNER_GENEVE = 0x2
+)
+
+const (
+	NFT_INNER_HDRSIZE = 0x1
+	NFT_INNER_LL      = 0x2
+	NFT_INNER_NH      = 0x4
+	NFT_INNER_TH      = 0x8
+)
+
+const (
+	NFTA_TUNNEL_KEY_IP_SRC = 0x1
+	NFTA_TUNNEL_KEY_IP_DST = 0x2
+)
+
+const (
+	NFTA_TUNNEL_KEY_IP6_SRC       = 0x1
+	NFTA_TUNNEL_KEY_IP6_DST       = 0x2
+	NFTA_TUNNEL_KEY_IP6_FLOWLABEL = 0x3
+)
+
+const (
+	NFTA_TUNNEL_KEY_OPTS_VXLAN  = 0x1
+	NFTA_TUNNEL_KEY_OPTS_ERSPAN = 0x2
+	NFTA_TUNNEL_KEY_OPTS_GENEVE = 0x3
+)
+
+const (
+	NFTA_TUNNEL_KEY_VXLAN_GBP = 0x1
+)
+
+const (
+	NFTA_TUNNEL_KEY_ERSPAN_VERSION  = 0x1
+	NFTA_TUNNEL_KEY_ERSPAN_V1_INDEX = 0x2
+	NFTA_TUNNEL_KEY_ERSPAN_V2_HWID  = 0x3
+	NFTA_TUNNEL_KEY_ERSPAN_V2_DIR   = 0x4
+)
+
+const (
+	NFTA_TUNNEL_KEY_GENEVE_CLASS = 0x1
+	NFTA_TUNNEL_KEY_GENEVE_TYPE  = 0x2
+	NFTA_TUNNEL_KEY_GENEVE_DATA  = 0x3
+)
+
+const (
+	NFT_TUNNEL_F_ZERO_CSUM_TX  = 0x1
+	NFT_TUNNEL_F_DONT_FRAGMENT = 0x2
+	NFT_TUNNEL_F_SEQ_NUMBER    = 0x4
+)
+
+const (
+	NFTA_TUNNEL_KEY_ID    = 0x1
+	NFTA_TUNNEL_KEY_IP    = 0x2
+	NFTA_TUNNEL_KEY_IP6   = 0x3
+	NFTA_TUNNEL_KEY_FLAGS = 0x4
+	NFTA_TUNNEL_KEY_TOS   = 0x5
+	NFTA_TUNNEL_KEY_TTL   = 0x6
+	NFTA_TUNNEL_KEY_SPORT = 0x7
+	NFTA_TUNNEL_KEY_DPORT = 0x8
+	NFTA_TUNNEL_KEY_OPTS  = 0x9
+)
+
+const (
+	NFT_TUNNEL_PATH = 0x0
+	NFT_TUNNEL_ID   = 0x1
+)
+
+const (
+	NFT_TUNNEL_MODE_NONE = 0x0
+	NFT_TUNNEL_MODE_RX   = 0x1
+	NFT_TUNNEL_MODE_TX   = 0x2
+)
+
+const (
+	NFTA_TUNNEL_KEY  = 0x1
+	NFTA_TUNNEL_DREG = 0x2
+	NFTA_TUNNEL_MODE = 0x3
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_nat.h
+const (
+	NF_NAT_RANGE_MAP_IPS            = 0x1
+	NF_NAT_RANGE_PROTO_SPECIFIED    = 0x2
+	NF_NAT_RANGE_PROTO_RANDOM       = 0x4
+	NF_NAT_RANGE_PERSISTENT         = 0x8
+	NF_NAT_RANGE_PROTO_RANDOM_FULLY = 0x10
+	NF_NAT_RANGE_PROTO_OFFSET       = 0x20
+	NF_NAT_RANGE_NETMAP             = 0x40
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/xt_conntrack.h
+const (
+	XT_CONNTRACK_STATE        = 0x1
+	XT_CONNTRACK_PROTO        = 0x2
+	XT_CONNTRACK_ORIGSRC      = 0x4
+	XT_CONNTRACK_ORIGDST      = 0x8
+	XT_CONNTRACK_REPLSRC      = 0x10
+	XT_CONNTRACK_REPLDST      = 0x20
+	XT_CONNTRACK_STATUS       = 0x40
+	XT_CONNTRACK_EXPIRES      = 0x80
+	XT_CONNTRACK_ORIGSRC_PORT = 0x100
+	XT_CONNTRACK_ORIGDST_PORT = 0x200
+	XT_CONNTRACK_REPLSRC_PORT = 0x400
+	XT_CONNTRACK_REPLDST_PORT = 0x800
+	XT_CONNTRACK_DIRECTION    = 0x1000
+	XT_CONNTRACK_STATE_ALIAS  = 0x2000
+)
+
+const (
+	XT_CONNTRACK_STATE_INVALID   = 0x1
+	XT_CONNTRACK_STATE_SNAT      = 0x40
+	XT_CONNTRACK_STATE_DNAT      = 0x80
+	XT_CONNTRACK_STATE_UNTRACKED = 0x100
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/xt_multiport.h
+const (
+	XT_MULTIPORT_SOURCE      = 0x0
+	XT_MULTIPORT_DESTINATION = 0x1
+	XT_MULTIPORT_EITHER      = 0x2
+)
+
+const (
+	XT_MULTI_PORTS = 0xf
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/xt_comment.h
+const (
+	XT_MAX_COMMENT_LEN = 0x100
+)
+
+// https://github.com/torvalds/linux/blob/f83a4f2a4d8c485922fba3018a64fc8f4cfd315f/include/uapi/linux/netfilter/nf_conntrack_tuple_common.h
+const (
+	IP_CT_DIR_ORIGINAL = 0x0
+	IP_CT_DIR_REPLY    = 0x1
+)
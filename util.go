@@ -7,6 +7,7 @@ import (
 	"net/netip"
 
 	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
 	"golang.org/x/sys/unix"
 )
 
@@ -22,7 +23,57 @@ func extractAttrs[T nftnl.Attrs](results []nftnl.Msg) ([]T, error) {
 	return attrs, nil
 }
 
-func prefixExpr(prefix *netip.Prefix, src bool) []nftnl.ExprAttrs {
+// cmpOp returns the comparison of a match, inverted if negate is set.
+func cmpOp(negate bool) uint32 {
+	if negate {
+		return unix.NFT_CMP_NEQ
+	}
+	return unix.NFT_CMP_EQ
+}
+
+// lookupFlags returns the flags of a set lookup, inverted if negate is set.
+func lookupFlags(negate bool) uint32 {
+	if negate {
+		return unix.NFT_LOOKUP_F_INV
+	}
+	return 0
+}
+
+// ipMatchExpr matches the source or destination address of the IPv4 or
+// IPv6 header with m. addrLen is the length of the addresses of a set.
+func ipMatchExpr(m *IPMatch, src bool, addrLen uint32) []nftnl.ExprAttrs {
+	switch {
+	case m.Prefix != nil:
+		return prefixExpr(m.Prefix, src, m.Negate)
+	case m.Addr != nil:
+		return addrExpr(m.Addr, src, m.Negate)
+	case m.Set != "":
+		var offset uint32 = 12 // IPv4 src/dst offset
+		if addrLen == net.IPv6len {
+			offset = 8 // IPv6 src/dst offset
+		}
+		if !src {
+			offset += addrLen
+		}
+		return appendExpr(nil,
+			&nftnl.PayloadAttrs{
+				DReg:   1,
+				Base:   unix.NFT_PAYLOAD_NETWORK_HEADER,
+				Offset: offset,
+				Len:    addrLen,
+			},
+			&nftnl.LookupAttrs{
+				Set:   m.Set,
+				SetID: m.SetID,
+				SReg:  1,
+				Flags: lookupFlags(m.Negate),
+			},
+		)
+	}
+	return nil
+}
+
+func prefixExpr(prefix *netip.Prefix, src bool, negate bool) []nftnl.ExprAttrs {
 	var exprs []nftnl.ExprAttrs
 	if prefix == nil {
 		return exprs
@@ -62,7 +113,7 @@ func prefixExpr(prefix *netip.Prefix, src bool) []nftnl.ExprAttrs {
 		},
 		&nftnl.CmpAttrs{
 			SReg: 1,
-			Op:   unix.NFT_CMP_EQ,
+			Op:   cmpOp(negate),
 			Data: &nftnl.DataAttrs{
 				Value: addr.AsSlice(),
 			},
@@ -99,7 +150,7 @@ func prefixFromExpr(attr *nftnl.BitwiseAttrs) *netip.Prefix {
 	return &prefix
 }
 
-func addrExpr(addr *netip.Addr, isSrc bool) []nftnl.ExprAttrs {
+func addrExpr(addr *netip.Addr, isSrc bool, negate bool) []nftnl.ExprAttrs {
 	var exprs []nftnl.ExprAttrs
 
 	var offset uint32 = 12 // IPv4 src/dst offset
@@ -120,7 +171,7 @@ func addrExpr(addr *netip.Addr, isSrc bool) []nftnl.ExprAttrs {
 		},
 		&nftnl.CmpAttrs{
 			SReg: 1,
-			Op:   unix.NFT_CMP_EQ,
+			Op:   cmpOp(negate),
 			Data: &nftnl.DataAttrs{
 				Value: addr.AsSlice(),
 			},
@@ -206,114 +257,72 @@ func ctStatesFromExpr(attr *nftnl.BitwiseAttrs) []uint32 {
 	return states
 }
 
-func portExpr(port uint16) []nftnl.ExprAttrs {
-	var exprs []nftnl.ExprAttrs
-
-	value := make([]byte, 2)
-	binary.BigEndian.PutUint16(value, port)
-
-	exprs = appendExpr(exprs,
-		&nftnl.PayloadAttrs{
-			DReg:   1,
-			Base:   unix.NFT_PAYLOAD_TRANSPORT_HEADER,
-			Offset: 0, // TCP/UDP source/dest port offset
-			Len:    2,
-		},
-		&nftnl.CmpAttrs{
-			SReg: 1,
-			Op:   unix.NFT_CMP_EQ,
-			Data: &nftnl.DataAttrs{
-				Value: value,
-			},
-		},
-	)
-
-	return exprs
-}
-
-func portFromExpr(attr *nftnl.CmpAttrs) uint16 {
-	if attr.Data == nil || len(attr.Data.Value) < 2 {
-		return 0
+// ctIPMatchExpr matches the original source or destination address of the
+// conntrack entry with m. addrLen is the length of the addresses.
+func ctIPMatchExpr(m *IPMatch, src bool, addrLen uint32) []nftnl.ExprAttrs {
+	var key uint32
+	switch {
+	case addrLen == net.IPv4len && src:
+		key = unix.NFT_CT_SRC_IP
+	case addrLen == net.IPv4len:
+		key = unix.NFT_CT_DST_IP
+	case src:
+		key = unix.NFT_CT_SRC_IP6
+	default:
+		key = unix.NFT_CT_DST_IP6
 	}
-	return binary.BigEndian.Uint16(attr.Data.Value)
-}
-
-func ctPortExpr(port uint16) []nftnl.ExprAttrs {
-	var exprs []nftnl.ExprAttrs
-
-	value := make([]byte, 2)
-	binary.NativeEndian.PutUint16(value, port)
-
-	exprs = appendExpr(exprs,
-		&nftnl.MetaAttrs{
-			DReg: 1,
-			Key:  unix.NFT_CT_PROTO_SRC,
-		},
-		&nftnl.CmpAttrs{
-			SReg: 1,
-			Op:   unix.NFT_CMP_EQ,
-			Data: &nftnl.DataAttrs{
-				Value: value,
-			},
+	exprs := appendExpr(nil,
+		&nftnl.CtAttrs{
+			DReg:      1,
+			Key:       key,
+			Direction: unixext.IP_CT_DIR_ORIGINAL,
 		},
 	)
 
-	return exprs
-}
-
-func ctPortFromExpr(attr *nftnl.CmpAttrs) uint16 {
-	if attr.Data == nil || len(attr.Data.Value) < 2 {
-		return 0
-	}
-	return binary.NativeEndian.Uint16(attr.Data.Value)
-}
-
-func ctPrefixExpr(prefix *netip.Prefix, isSrc bool) []nftnl.ExprAttrs {
-	var exprs []nftnl.ExprAttrs
-	addr := prefix.Addr()
-	var base uint32
-
-	if addr.Is4() && isSrc {
-		base = unix.NFT_CT_SRC_IP
-	} else if addr.Is4() && !isSrc {
-		base = unix.NFT_CT_DST_IP
-	} else if addr.Is6() && isSrc {
-		base = unix.NFT_CT_SRC_IP6
-	} else {
-		base = unix.NFT_CT_DST_IP6
-	}
-
-	var offset uint32 = 12 // IPv4 src/dst offset
-	if addr.Is6() {
-		offset = 8 // IPv6 src/dst offset
-	}
-	if !isSrc {
-		offset += uint32(len(addr.AsSlice()))
-	}
-	length := uint32(len(addr.AsSlice()))
-	// Big endian ?
-	mask := net.CIDRMask(prefix.Bits(), addr.BitLen())
-
-	exprs = appendExpr(exprs,
-		&nftnl.PayloadAttrs{
-			DReg:   1,
-			Base:   base,
-			Offset: offset,
-			Len:    length,
-		},
-		&nftnl.BitwiseAttrs{
-			SReg: 1,
-			Len:  length,
-			Mask: &nftnl.DataAttrs{
-				Value: mask,
+	switch {
+	case m.Prefix != nil:
+		addr := m.Prefix.Addr()
+		return appendExpr(exprs,
+			&nftnl.BitwiseAttrs{
+				SReg: 1,
+				DReg: 1,
+				Len:  addrLen,
+				Mask: &nftnl.DataAttrs{
+					Value: net.CIDRMask(m.Prefix.Bits(), addr.BitLen()),
+				},
+				Xor: &nftnl.DataAttrs{
+					Value: make([]byte, addrLen),
+				},
 			},
-			Xor: &nftnl.DataAttrs{
-				Value: make([]byte, length),
+			&nftnl.CmpAttrs{
+				SReg: 1,
+				Op:   cmpOp(m.Negate),
+				Data: &nftnl.DataAttrs{
+					Value: addr.AsSlice(),
+				},
 			},
-		},
-	)
-
-	return exprs
+		)
+	case m.Addr != nil:
+		return appendExpr(exprs,
+			&nftnl.CmpAttrs{
+				SReg: 1,
+				Op:   cmpOp(m.Negate),
+				Data: &nftnl.DataAttrs{
+					Value: m.Addr.AsSlice(),
+				},
+			},
+		)
+	case m.Set != "":
+		return appendExpr(exprs,
+			&nftnl.LookupAttrs{
+				Set:   m.Set,
+				SetID: m.SetID,
+				SReg:  1,
+				Flags: lookupFlags(m.Negate),
+			},
+		)
+	}
+	return nil
 }
 
 func ctPrefixFromExpr(attr *nftnl.BitwiseAttrs) *netip.Prefix {
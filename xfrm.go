@@ -0,0 +1,170 @@
+package nft
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+type XfrmDir uint8
+
+const (
+	// XfrmDirIn inspects the states the packet was decapsulated with. The
+	// kernel only allows it in the prerouting, input and forward hooks.
+	XfrmDirIn XfrmDir = 0x1
+	// XfrmDirOut inspects the state the packet is about to be encapsulated
+	// with. The kernel only allows it in the forward, output and postrouting
+	// hooks.
+	XfrmDirOut XfrmDir = 0x2
+)
+
+type XfrmKey uint8
+
+const (
+	XfrmKeyReqID XfrmKey = 0x1
+	XfrmKeySPI   XfrmKey = 0x2
+	// XfrmKeyDAddr compares the tunnel destination address of the state.
+	XfrmKeyDAddr XfrmKey = 0x3
+	// XfrmKeySAddr compares the tunnel source address of the state.
+	XfrmKeySAddr XfrmKey = 0x4
+)
+
+// xfrmMaxDepth mirrors XFRM_MAX_DEPTH, the maximum number of states in a
+// security path.
+const xfrmMaxDepth = 6
+
+// XfrmMatch compares a property of the IPsec security association a packet
+// was received or is sent with. Packets that did not go through IPsec never
+// match.
+type XfrmMatch struct {
+	Dir XfrmDir
+	// SPNum selects the state in the security path, 0 being the first.
+	SPNum uint32
+	Key   XfrmKey
+	ReqID uint32
+	SPI   uint32
+	Addr  *netip.Addr
+}
+
+func (m *XfrmMatch) validate(r *Rule) error {
+	switch r.Family {
+	case unix.NFPROTO_IPV4, unix.NFPROTO_IPV6, unix.NFPROTO_INET:
+	default:
+		return fmt.Errorf("xfrm is only supported in the ipv4, ipv6 and inet families")
+	}
+	switch m.Dir {
+	case XfrmDirIn, XfrmDirOut:
+	default:
+		return fmt.Errorf("unknown xfrm direction %d", m.Dir)
+	}
+	if m.SPNum >= xfrmMaxDepth {
+		return fmt.Errorf("xfrm spnum must be less than %d", xfrmMaxDepth)
+	}
+	switch m.Key {
+	case XfrmKeyReqID, XfrmKeySPI:
+	case XfrmKeyDAddr, XfrmKeySAddr:
+		if m.Addr == nil || !m.Addr.IsValid() {
+			return fmt.Errorf("xfrm address key requires an address")
+		}
+	default:
+		return fmt.Errorf("unknown xfrm key %d", m.Key)
+	}
+	return nil
+}
+
+func xfrmExpr(m *XfrmMatch) []nftnl.ExprAttrs {
+	attrs := &nftnl.XfrmAttrs{
+		DReg:  1,
+		Dir:   unixext.XFRM_POLICY_IN,
+		SPNum: m.SPNum,
+	}
+	if m.Dir == XfrmDirOut {
+		attrs.Dir = unixext.XFRM_POLICY_OUT
+	}
+
+	var value []byte
+	switch m.Key {
+	case XfrmKeyReqID:
+		attrs.Key = unixext.NFT_XFRM_KEY_REQID
+		value = make([]byte, 4)
+		binary.NativeEndian.PutUint32(value, m.ReqID)
+	case XfrmKeySPI:
+		attrs.Key = unixext.NFT_XFRM_KEY_SPI
+		value = make([]byte, 4)
+		binary.BigEndian.PutUint32(value, m.SPI)
+	case XfrmKeyDAddr:
+		attrs.Key = unixext.NFT_XFRM_KEY_DADDR_IP6
+		if m.Addr.Is4() {
+			attrs.Key = unixext.NFT_XFRM_KEY_DADDR_IP4
+		}
+		value = m.Addr.AsSlice()
+	case XfrmKeySAddr:
+		attrs.Key = unixext.NFT_XFRM_KEY_SADDR_IP6
+		if m.Addr.Is4() {
+			attrs.Key = unixext.NFT_XFRM_KEY_SADDR_IP4
+		}
+		value = m.Addr.AsSlice()
+	}
+
+	return appendExpr(nil,
+		attrs,
+		&nftnl.CmpAttrs{
+			SReg: 1,
+			Op:   unix.NFT_CMP_EQ,
+			Data: &nftnl.DataAttrs{
+				Value: value,
+			},
+		},
+	)
+}
+
+func (r *Rule) unmarshalXfrmExprs(attrs *nftnl.RuleAttrs) {
+	for i := 0; i+1 < len(attrs.Expressions); i++ {
+		xfrm, ok := attrs.Expressions[i].Data.(*nftnl.XfrmAttrs)
+		if !ok {
+			continue
+		}
+		cmp, ok := attrs.Expressions[i+1].Data.(*nftnl.CmpAttrs)
+		if !ok || cmp.Op != unix.NFT_CMP_EQ || cmp.Data == nil || len(cmp.Data.Value) == 0 {
+			continue
+		}
+
+		m := &XfrmMatch{Dir: XfrmDirIn, SPNum: xfrm.SPNum}
+		if xfrm.Dir == unixext.XFRM_POLICY_OUT {
+			m.Dir = XfrmDirOut
+		}
+		value := cmp.Data.Value
+		switch xfrm.Key {
+		case unixext.NFT_XFRM_KEY_REQID, unixext.NFT_XFRM_KEY_SPI:
+			if len(value) < 4 {
+				continue
+			}
+			if xfrm.Key == unixext.NFT_XFRM_KEY_REQID {
+				m.Key = XfrmKeyReqID
+				m.ReqID = binary.NativeEndian.Uint32(value)
+			} else {
+				m.Key = XfrmKeySPI
+				m.SPI = binary.BigEndian.Uint32(value)
+			}
+		case unixext.NFT_XFRM_KEY_DADDR_IP4, unixext.NFT_XFRM_KEY_DADDR_IP6,
+			unixext.NFT_XFRM_KEY_SADDR_IP4, unixext.NFT_XFRM_KEY_SADDR_IP6:
+			addr, ok := netip.AddrFromSlice(value)
+			if !ok {
+				continue
+			}
+			m.Key = XfrmKeySAddr
+			if xfrm.Key == unixext.NFT_XFRM_KEY_DADDR_IP4 || xfrm.Key == unixext.NFT_XFRM_KEY_DADDR_IP6 {
+				m.Key = XfrmKeyDAddr
+			}
+			m.Addr = &addr
+		default:
+			continue
+		}
+		r.Xfrm = append(r.Xfrm, m)
+		i++
+	}
+}
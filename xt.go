@@ -0,0 +1,366 @@
+package nft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/nickgarlis/go-nft/nftnl"
+	"github.com/nickgarlis/go-nft/unixext"
+	"golang.org/x/sys/unix"
+)
+
+// XtMatch is an iptables match extension, which iptables-nft adds to rules
+// it cannot translate to native expressions. Info is sent as is when the
+// rule is created. On dump, the field of the extension is decoded from it
+// when the extension and its revision are known.
+type XtMatch struct {
+	Name string
+	Rev  uint32
+	Info []byte
+
+	Comment   string
+	Conntrack *XtConntrack
+	Multiport *XtMultiport
+	Mark      *XtMark
+}
+
+// XtTarget is an iptables target extension. Like XtMatch, the field of the
+// extension is decoded from Info on dump.
+type XtTarget struct {
+	Name string
+	Rev  uint32
+	Info []byte
+
+	Masquerade *XtNatRange
+	SNAT       *XtNatRange
+	DNAT       *XtNatRange
+}
+
+// XtConntrack is the conntrack match. Flags are the XT_CONNTRACK_* criteria
+// checked by the match, and Invert the negated ones. Addresses are decoded
+// as IPv6 in the ipv6 family and as IPv4 otherwise.
+type XtConntrack struct {
+	Flags  uint16
+	Invert uint16
+	States []CtState
+	// SNAT and DNAT match the virtual states of translated connections.
+	SNAT        bool
+	DNAT        bool
+	Status      uint16
+	L4Proto     uint8
+	OrigSrc     *netip.Prefix
+	OrigDst     *netip.Prefix
+	ReplSrc     *netip.Prefix
+	ReplDst     *netip.Prefix
+	OrigSrcPort XtPortRange
+	OrigDstPort XtPortRange
+	ReplSrcPort XtPortRange
+	ReplDstPort XtPortRange
+	// ExpiresMin and ExpiresMax bound the remaining lifetime in seconds.
+	ExpiresMin uint32
+	ExpiresMax uint32
+}
+
+type XtPortRange struct {
+	Min uint16
+	Max uint16
+}
+
+type XtMultiportDir uint8
+
+const (
+	XtMultiportSrc    XtMultiportDir = 0x1
+	XtMultiportDst    XtMultiportDir = 0x2
+	XtMultiportEither XtMultiportDir = 0x3
+)
+
+type XtMultiport struct {
+	Dir    XtMultiportDir
+	Ports  []XtPortRange
+	Invert bool
+}
+
+type XtMark struct {
+	Mark   uint32
+	Mask   uint32
+	Invert bool
+}
+
+// XtNatRange is the translation of the MASQUERADE, SNAT and DNAT targets.
+// Addresses are decoded as IPv6 in the ipv6 family and as IPv4 otherwise.
+type XtNatRange struct {
+	MinAddr     *netip.Addr
+	MaxAddr     *netip.Addr
+	MinPort     uint16
+	MaxPort     uint16
+	Random      bool
+	RandomFully bool
+	Persistent  bool
+}
+
+func xtMatchFromAttrs(l3proto uint8, attrs *nftnl.MatchAttrs) *XtMatch {
+	m := &XtMatch{
+		Name: attrs.Name,
+		Rev:  attrs.Rev,
+		Info: attrs.Info,
+	}
+	switch attrs.Name {
+	case "comment":
+		m.Comment = xtString(attrs.Info)
+	case "conntrack":
+		m.Conntrack = xtConntrackFromInfo(l3proto, attrs.Rev, attrs.Info)
+	case "multiport":
+		m.Multiport = xtMultiportFromInfo(attrs.Rev, attrs.Info)
+	case "mark":
+		m.Mark = xtMarkFromInfo(attrs.Rev, attrs.Info)
+	}
+	return m
+}
+
+func xtTargetFromAttrs(l3proto uint8, attrs *nftnl.TargetAttrs) *XtTarget {
+	t := &XtTarget{
+		Name: attrs.Name,
+		Rev:  attrs.Rev,
+		Info: attrs.Info,
+	}
+	switch attrs.Name {
+	case "MASQUERADE":
+		// The IPv6 target always used struct nf_nat_range.
+		rev := attrs.Rev
+		if l3proto == unix.NFPROTO_IPV6 {
+			rev = 1
+		}
+		t.Masquerade = xtNatRangeFromInfo(l3proto, rev, attrs.Info)
+	case "SNAT":
+		t.SNAT = xtNatRangeFromInfo(l3proto, attrs.Rev, attrs.Info)
+	case "DNAT":
+		t.DNAT = xtNatRangeFromInfo(l3proto, attrs.Rev, attrs.Info)
+	}
+	return t
+}
+
+func xtString(info []byte) string {
+	if i := bytes.IndexByte(info, 0); i >= 0 {
+		info = info[:i]
+	}
+	return string(info)
+}
+
+// xtAddr decodes the union nf_inet_addr at the start of b.
+func xtAddr(l3proto uint8, b []byte) netip.Addr {
+	if l3proto == unix.NFPROTO_IPV6 {
+		return netip.AddrFrom16([16]byte(b[:16]))
+	}
+	return netip.AddrFrom4([4]byte(b[:4]))
+}
+
+// xtConntrackFromInfo decodes struct xt_conntrack_mtinfo1, 2 or 3, which
+// only differ in the size of the state masks and the port ranges.
+func xtConntrackFromInfo(l3proto uint8, rev uint32, info []byte) *XtConntrack {
+	sizes := map[uint32]int{1: 152, 2: 156, 3: 164}
+	if size, ok := sizes[rev]; !ok || len(info) < size {
+		return nil
+	}
+
+	c := &XtConntrack{
+		Flags:  binary.NativeEndian.Uint16(info[146:]),
+		Invert: binary.NativeEndian.Uint16(info[148:]),
+	}
+
+	var stateMask uint16
+	if rev == 1 {
+		stateMask = uint16(info[150])
+		c.Status = uint16(info[151])
+	} else {
+		stateMask = binary.NativeEndian.Uint16(info[150:])
+		c.Status = binary.NativeEndian.Uint16(info[152:])
+	}
+	if c.Flags&unixext.XT_CONNTRACK_STATE != 0 {
+		for _, state := range []CtState{CtStateInvalid, CtStateEstablished, CtStateRelated, CtStateNew} {
+			// The xt and nft state bits only differ for untracked.
+			if stateMask&uint16(state) != 0 {
+				c.States = append(c.States, state)
+			}
+		}
+		if stateMask&unixext.XT_CONNTRACK_STATE_UNTRACKED != 0 {
+			c.States = append(c.States, CtStateUntracked)
+		}
+		c.SNAT = stateMask&unixext.XT_CONNTRACK_STATE_SNAT != 0
+		c.DNAT = stateMask&unixext.XT_CONNTRACK_STATE_DNAT != 0
+	}
+	if c.Flags&unixext.XT_CONNTRACK_STATUS == 0 {
+		c.Status = 0
+	}
+	if c.Flags&unixext.XT_CONNTRACK_PROTO != 0 {
+		c.L4Proto = uint8(binary.NativeEndian.Uint16(info[136:]))
+	}
+	if c.Flags&unixext.XT_CONNTRACK_EXPIRES != 0 {
+		c.ExpiresMin = binary.NativeEndian.Uint32(info[128:])
+		c.ExpiresMax = binary.NativeEndian.Uint32(info[132:])
+	}
+
+	// Each address is followed by its mask.
+	for i, prefix := range []**netip.Prefix{&c.OrigSrc, &c.OrigDst, &c.ReplSrc, &c.ReplDst} {
+		if c.Flags&(unixext.XT_CONNTRACK_ORIGSRC<<i) == 0 {
+			continue
+		}
+		offset := i * 32
+		addr := xtAddr(l3proto, info[offset:])
+		bits, _ := net.IPMask(xtAddr(l3proto, info[offset+16:]).AsSlice()).Size()
+		p := netip.PrefixFrom(addr, bits)
+		*prefix = &p
+	}
+
+	// Revisions 1 and 2 have a single port in network byte order. Revision 3
+	// adds the high ends of the ranges, and both ends are in host byte order.
+	for i, ports := range []*XtPortRange{&c.OrigSrcPort, &c.OrigDstPort, &c.ReplSrcPort, &c.ReplDstPort} {
+		if c.Flags&(unixext.XT_CONNTRACK_ORIGSRC_PORT<<i) == 0 {
+			continue
+		}
+		if rev >= 3 {
+			ports.Min = binary.NativeEndian.Uint16(info[138+2*i:])
+			ports.Max = binary.NativeEndian.Uint16(info[154+2*i:])
+		} else {
+			ports.Min = binary.BigEndian.Uint16(info[138+2*i:])
+			ports.Max = ports.Min
+		}
+	}
+
+	return c
+}
+
+// xtMultiportFromInfo decodes struct xt_multiport, or xt_multiport_v1 which
+// adds port ranges and inversion.
+func xtMultiportFromInfo(rev uint32, info []byte) *XtMultiport {
+	if (rev == 0 && len(info) < 32) || (rev == 1 && len(info) < 48) || rev > 1 {
+		return nil
+	}
+
+	m := &XtMultiport{}
+	switch info[0] {
+	case unixext.XT_MULTIPORT_SOURCE:
+		m.Dir = XtMultiportSrc
+	case unixext.XT_MULTIPORT_DESTINATION:
+		m.Dir = XtMultiportDst
+	case unixext.XT_MULTIPORT_EITHER:
+		m.Dir = XtMultiportEither
+	}
+	count := min(int(info[1]), unixext.XT_MULTI_PORTS)
+	port := func(i int) uint16 {
+		return binary.NativeEndian.Uint16(info[2+2*i:])
+	}
+	for i := 0; i < count; i++ {
+		ports := XtPortRange{Min: port(i), Max: port(i)}
+		// A port flag makes the port the start of a range ending at the
+		// next one.
+		if rev == 1 && info[32+i] != 0 && i+1 < count {
+			i++
+			ports.Max = port(i)
+		}
+		m.Ports = append(m.Ports, ports)
+	}
+	if rev == 1 {
+		m.Invert = info[47] != 0
+	}
+	return m
+}
+
+// xtMarkFromInfo decodes struct xt_mark_mtinfo1. Revision 0 uses longs and
+// is not decoded.
+func xtMarkFromInfo(rev uint32, info []byte) *XtMark {
+	if rev != 1 || len(info) < 12 {
+		return nil
+	}
+	return &XtMark{
+		Mark:   binary.NativeEndian.Uint32(info[0:]),
+		Mask:   binary.NativeEndian.Uint32(info[4:]),
+		Invert: info[8] != 0,
+	}
+}
+
+// xtNatRangeFromInfo decodes the range of a NAT target, which is struct
+// nf_nat_ipv4_multi_range_compat in revision 0, nf_nat_range in revision 1
+// and nf_nat_range2 in revision 2. The kernel pads the info it dumps.
+func xtNatRangeFromInfo(l3proto uint8, rev uint32, info []byte) *XtNatRange {
+	var (
+		flags              uint32
+		minAddr, maxAddr   netip.Addr
+		minProto, maxProto []byte
+	)
+	switch {
+	case rev == 0 && len(info) >= 20:
+		flags = binary.NativeEndian.Uint32(info[4:])
+		minAddr = xtAddr(unix.NFPROTO_IPV4, info[8:])
+		maxAddr = xtAddr(unix.NFPROTO_IPV4, info[12:])
+		minProto, maxProto = info[16:], info[18:]
+	case (rev == 1 && len(info) >= 40) || (rev == 2 && len(info) >= 44):
+		flags = binary.NativeEndian.Uint32(info[0:])
+		minAddr = xtAddr(l3proto, info[4:])
+		maxAddr = xtAddr(l3proto, info[20:])
+		minProto, maxProto = info[36:], info[38:]
+	default:
+		return nil
+	}
+
+	r := &XtNatRange{
+		Random:      flags&unixext.NF_NAT_RANGE_PROTO_RANDOM != 0,
+		RandomFully: flags&unixext.NF_NAT_RANGE_PROTO_RANDOM_FULLY != 0,
+		Persistent:  flags&unixext.NF_NAT_RANGE_PERSISTENT != 0,
+	}
+	if flags&unixext.NF_NAT_RANGE_MAP_IPS != 0 {
+		r.MinAddr = &minAddr
+		r.MaxAddr = &maxAddr
+	}
+	if flags&unixext.NF_NAT_RANGE_PROTO_SPECIFIED != 0 {
+		r.MinPort = binary.BigEndian.Uint16(minProto)
+		r.MaxPort = binary.BigEndian.Uint16(maxProto)
+	}
+	return r
+}
+
+// xtMaxNameLen mirrors XT_EXTENSION_MAXNAMELEN, minus the terminating NUL.
+const xtMaxNameLen = 28
+
+func validateXtName(name string) error {
+	if name == "" {
+		return fmt.Errorf("xt extension name must be specified")
+	}
+	if len(name) > xtMaxNameLen {
+		return fmt.Errorf("xt extension name must be at most %d characters", xtMaxNameLen)
+	}
+	return nil
+}
+
+func xtMatchExpr(m *XtMatch) []nftnl.ExprAttrs {
+	return appendExpr(nil,
+		&nftnl.MatchAttrs{
+			Name: m.Name,
+			Rev:  m.Rev,
+			Info: m.Info,
+		},
+	)
+}
+
+func xtTargetExpr(t *XtTarget) []nftnl.ExprAttrs {
+	return appendExpr(nil,
+		&nftnl.TargetAttrs{
+			Name: t.Name,
+			Rev:  t.Rev,
+			Info: t.Info,
+		},
+	)
+}
+
+func (r *Rule) unmarshalXtExprs(attrs *nftnl.RuleAttrs) {
+	for _, expr := range attrs.Expressions {
+		switch e := expr.Data.(type) {
+		case *nftnl.MatchAttrs:
+			r.XtMatches = append(r.XtMatches, xtMatchFromAttrs(r.l3proto(), e))
+		case *nftnl.TargetAttrs:
+			r.XtTarget = xtTargetFromAttrs(r.l3proto(), e)
+		}
+	}
+}